@@ -0,0 +1,18 @@
+package accounts
+
+import (
+	"api/providers"
+)
+
+// Accounts exports and erases everything the app keeps about a user
+type Accounts struct {
+	Users      providers.UserRepository
+	Households providers.HouseholdRepository
+	Groceries  providers.GroceryRepository
+	Invites    providers.InviteRepository
+	Devices    providers.DeviceRepository
+	Lists      providers.ListRepository
+	Staples    providers.StapleRepository
+	Receipts   *providers.ReceiptProvider
+	Exports    *providers.ExportProvider
+}
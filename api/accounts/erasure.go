@@ -0,0 +1,116 @@
+package accounts
+
+import (
+	"api/providers"
+	"api/proxy"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrOwnsSharedHousehold is returned by Erase while the user owns a household others are still in
+var ErrOwnsSharedHousehold = errors.New("transfer ownership of households shared with others first")
+
+// Erase removes the user, their devices, the invites they created and the receipts they uploaded.
+// They leave their households, the ones they own alone are marked for deletion and their ids
+// returned so the caller can start deleting them. Nothing is changed when the user owns a shared household.
+// A failed erasure can be repeated.
+func (a *Accounts) Erase(ctx context.Context, userId string) ([]string, error) {
+	user, err := providers.GetUser(ctx, a.Users, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	var leaving, deleting []string
+	for _, householdId := range user.HouseholdIds {
+		household, err := a.Households.GetHousehold(ctx, householdId)
+
+		// The household is being deleted already, only the membership is left to go
+		if errors.Is(err, proxy.ErrNotFound) {
+			leaving = append(leaving, householdId)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if household.OwnerId != userId {
+			leaving = append(leaving, householdId)
+			continue
+		}
+
+		// Members who joined before the members index existed are only found through their user
+		memberIds, err := providers.GetHouseholdUserIds(ctx, a.Households, a.Users, householdId)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, memberId := range memberIds {
+			if memberId != userId {
+				return nil, fmt.Errorf("%w, household [%s] has other members", ErrOwnsSharedHousehold, householdId)
+			}
+		}
+
+		deleting = append(deleting, householdId)
+	}
+
+	for _, householdId := range deleting {
+		if err := a.Households.MarkHouseholdForDeletion(ctx, householdId); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, householdId := range leaving {
+		if err := a.deleteInvitesBy(ctx, householdId, userId); err != nil {
+			return nil, err
+		}
+
+		if err := a.Households.LeaveHousehold(ctx, userId, householdId); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := a.Receipts.DeleteUserReceipts(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	if err := a.Exports.DeleteUserExports(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	devices, err := a.Devices.GetUserDevices(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, device := range devices {
+		if err := a.Devices.DeleteDevice(ctx, device.Id); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := a.Users.DeleteUser(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	return deleting, nil
+}
+
+func (a *Accounts) deleteInvitesBy(ctx context.Context, householdId string, userId string) error {
+	invites, err := a.Invites.GetHouseholdInvites(ctx, householdId)
+	if err != nil {
+		return err
+	}
+
+	for _, invite := range invites {
+		if invite.CreatedBy != userId {
+			continue
+		}
+
+		if err := a.Invites.DeleteInvite(ctx, invite.Code); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
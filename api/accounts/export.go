@@ -0,0 +1,317 @@
+package accounts
+
+import (
+	"api/models"
+	"api/providers"
+	"api/proxy"
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+)
+
+// ExportedHousehold is a household the user belongs to, as it appears in an export
+type ExportedHousehold struct {
+	models.Household
+	Role    models.HouseholdRole     `json:"role"`
+	Members []models.HouseholdMember `json:"members"`
+	Lists   []models.HouseholdList   `json:"lists"`
+	Staples []models.Staple          `json:"staples"`
+}
+
+// export is everything collected about a user before it is written out
+type export struct {
+	user         models.User
+	devices      []models.Device
+	households   []ExportedHousehold
+	groceryItems []models.GroceryItem
+	receipts     []models.Receipt
+	// files are added to the zip as they are, after the lists
+	files []exportFile
+}
+
+type exportFile struct {
+	name string
+	body []byte
+}
+
+// Export bundles the user record, the devices they are signed in on, their households with their lists, staples and grocery items and the receipts
+// they uploaded with the text read off them into a zip, with JSON and CSV copies of each list. The zip is too big to
+// hand back in a response once receipts are in it, it is stored with the exports instead and an url to download it from is returned.
+func (a *Accounts) Export(ctx context.Context, userId string) (string, error) {
+	archive, err := a.archive(ctx, userId)
+	if err != nil {
+		return "", err
+	}
+
+	return a.Exports.SaveExport(ctx, userId, archive)
+}
+
+func (a *Accounts) archive(ctx context.Context, userId string) ([]byte, error) {
+	user, err := providers.GetUser(ctx, a.Users, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	e := export{
+		user:         user,
+		households:   make([]ExportedHousehold, 0, len(user.HouseholdIds)),
+		groceryItems: make([]models.GroceryItem, 0),
+	}
+
+	for _, householdId := range user.HouseholdIds {
+		household, err := a.Households.GetHousehold(ctx, householdId)
+
+		// The household is being deleted along with its items
+		if errors.Is(err, proxy.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		members, err := a.Households.GetMembers(ctx, householdId)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range members {
+			if members[i].UserId == household.OwnerId {
+				members[i].Role = models.OwnerRole
+			}
+		}
+
+		lists, err := a.Lists.GetLists(ctx, householdId)
+		if err != nil {
+			return nil, err
+		}
+
+		staples, err := a.Staples.GetStaples(ctx, householdId)
+		if err != nil {
+			return nil, err
+		}
+
+		e.households = append(e.households, ExportedHousehold{
+			Household: household,
+			Role:      householdRole(household, members, userId),
+			Members:   members,
+			Lists:     lists,
+			Staples:   staples,
+		})
+
+		groceryItems, err := a.Groceries.GetGroceryItems(ctx, householdId)
+		if err != nil {
+			return nil, err
+		}
+		e.groceryItems = append(e.groceryItems, groceryItems...)
+	}
+
+	e.devices, err = a.Devices.GetUserDevices(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	e.receipts, err = a.Receipts.GetUserReceipts(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, receipt := range e.receipts {
+		file, err := a.Receipts.GetReceipt(ctx, receipt)
+		if err != nil {
+			return nil, err
+		}
+		e.files = append(e.files, exportFile{path.Join("receipts", receipt.Key), file})
+
+		if receipt.HasText {
+			text, err := a.Receipts.GetReceiptText(ctx, receipt.Key)
+			if err != nil {
+				return nil, err
+			}
+			e.files = append(e.files, exportFile{path.Join("receipts", receipt.Key+".txt"), []byte(text)})
+		}
+	}
+
+	return e.zip()
+}
+
+// householdRole is the role of the user in the household, like routes.Api.memberRole but
+// from members that were already fetched
+func householdRole(household models.Household, members []models.HouseholdMember, userId string) models.HouseholdRole {
+	if household.OwnerId == userId || household.OwnerId == "" {
+		return models.OwnerRole
+	}
+
+	for _, member := range members {
+		if member.UserId == userId {
+			return member.Role
+		}
+	}
+
+	return models.MemberRole
+}
+
+func (e export) zip() ([]byte, error) {
+	households := [][]string{{"id", "name", "role", "ownerId", "createdAt"}}
+	for _, household := range e.households {
+		households = append(households, []string{
+			household.Id,
+			household.Name,
+			string(household.Role),
+			household.OwnerId,
+			strconv.FormatInt(household.CreatedAt, 10),
+		})
+	}
+
+	lists := [][]string{{"householdId", "id", "name", "createdAt", "archived"}}
+	for _, household := range e.households {
+		for _, list := range household.Lists {
+			lists = append(lists, []string{
+				list.HouseholdId,
+				list.Id,
+				list.Name,
+				strconv.FormatInt(list.CreatedAt, 10),
+				strconv.FormatBool(list.Archived),
+			})
+		}
+	}
+
+	staples := [][]string{{"householdId", "id", "listId", "name", "recurrence", "days", "nextDueAt", "lastAddedAt", "createdAt"}}
+	for _, household := range e.households {
+		for _, staple := range household.Staples {
+			staples = append(staples, []string{
+				staple.HouseholdId,
+				staple.Id,
+				staple.ListId,
+				staple.Name,
+				string(staple.Recurrence),
+				strconv.Itoa(staple.Days),
+				strconv.FormatInt(staple.NextDueAt, 10),
+				strconv.FormatInt(staple.LastAddedAt, 10),
+				strconv.FormatInt(staple.CreatedAt, 10),
+			})
+		}
+	}
+
+	groceryItems := [][]string{{"householdId", "id", "listId", "name", "quantity", "unit", "text", "notes", "storeOverride", "checked", "addedBy", "assignedTo", "version", "createdAt", "updatedAt", "checkedAt"}}
+	for _, item := range e.groceryItems {
+		listId := item.ListId
+		if listId == "" {
+			listId = models.DefaultListId
+		}
+
+		groceryItems = append(groceryItems, []string{
+			item.HouseholdId,
+			item.Id,
+			listId,
+			item.Name,
+			strconv.FormatFloat(item.Quantity, 'f', -1, 64),
+			item.Unit,
+			item.Text,
+			item.Notes,
+			string(item.StoreOverride),
+			strconv.FormatBool(item.Checked),
+			item.AddedBy,
+			item.AssignedTo,
+			strconv.Itoa(item.Version),
+			strconv.FormatInt(item.CreatedAt, 10),
+			strconv.FormatInt(item.UpdatedAt, 10),
+			strconv.FormatInt(item.CheckedAt, 10),
+		})
+	}
+
+	devices := [][]string{{"id", "name", "linkedAt", "revokedAt"}}
+	for _, device := range e.devices {
+		devices = append(devices, []string{
+			device.Id,
+			device.Name,
+			strconv.FormatInt(device.LinkedAt, 10),
+			strconv.FormatInt(device.RevokedAt, 10),
+		})
+	}
+
+	receipts := [][]string{{"key", "processed", "hasText"}}
+	for _, receipt := range e.receipts {
+		receipts = append(receipts, []string{
+			receipt.Key,
+			strconv.FormatBool(receipt.Processed),
+			strconv.FormatBool(receipt.HasText),
+		})
+	}
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+
+	jsonFiles := []struct {
+		name  string
+		value interface{}
+	}{
+		{"user.json", e.user},
+		{"devices.json", e.devices},
+		{"households.json", e.households},
+		{"grocery-items.json", e.groceryItems},
+		{"receipts.json", e.receipts},
+	}
+	for _, file := range jsonFiles {
+		body, err := json.MarshalIndent(file.value, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("unable to export %s, %v", file.name, err)
+		}
+
+		if err := writeZipFile(w, file.name, body); err != nil {
+			return nil, err
+		}
+	}
+
+	csvFiles := []struct {
+		name    string
+		records [][]string
+	}{
+		{"devices.csv", devices},
+		{"households.csv", households},
+		{"lists.csv", lists},
+		{"staples.csv", staples},
+		{"grocery-items.csv", groceryItems},
+		{"receipts.csv", receipts},
+	}
+	for _, file := range csvFiles {
+		var body bytes.Buffer
+		if err := csv.NewWriter(&body).WriteAll(file.records); err != nil {
+			return nil, fmt.Errorf("unable to export %s, %v", file.name, err)
+		}
+
+		if err := writeZipFile(w, file.name, body.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, file := range e.files {
+		if err := writeZipFile(w, file.name, file.body); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("unable to finish export, %v", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeZipFile(w *zip.Writer, name string, body []byte) error {
+	f, err := w.Create(name)
+	if err != nil {
+		return fmt.Errorf("unable to export %s, %v", name, err)
+	}
+
+	if _, err := f.Write(body); err != nil {
+		return fmt.Errorf("unable to export %s, %v", name, err)
+	}
+
+	return nil
+}
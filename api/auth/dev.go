@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// devKeyId is the key id of the key made by EnsureDevKey
+const devKeyId = "dev"
+
+// EnsureDevKey makes an Ed25519 key for signing tokens locally unless keyPath already holds one,
+// writing the private key to keyPath and the JWKS that verifies its tokens to jwksPath. It refuses
+// to replace a JWKS it didn't write.
+func EnsureDevKey(keyPath string, jwksPath string) error {
+	if _, err := os.Stat(keyPath); err == nil {
+		return nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if _, err := os.Stat(jwksPath); err == nil {
+		return fmt.Errorf("jwks file %q exists without dev key %q, remove it to make a new dev key", jwksPath, keyPath)
+	}
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("unable to generate dev key, %v", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return fmt.Errorf("unable to encode dev key, %v", err)
+	}
+
+	jwks, err := json.MarshalIndent(map[string][]jsonWebKey{
+		"keys": {{Kid: devKeyId, Kty: "OKP", Use: "sig", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(publicKey)}},
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(jwksPath, jwks, 0644); err != nil {
+		return fmt.Errorf("unable to write dev jwks, %v", err)
+	}
+
+	// Written last, so a key is only ever found alongside its JWKS
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		return fmt.Errorf("unable to write dev key, %v", err)
+	}
+
+	return nil
+}
+
+// SignDevToken signs a token for userId with the key EnsureDevKey wrote to keyPath. Issuer and
+// audience are only set when not empty, to match what the Verifier checks.
+func SignDevToken(keyPath string, userId string, issuer string, audience string, ttl time.Duration) (string, error) {
+	body, err := os.ReadFile(keyPath)
+	if err != nil {
+		return "", fmt.Errorf("unable to read dev key, %v", err)
+	}
+
+	block, _ := pem.Decode(body)
+	if block == nil {
+		return "", fmt.Errorf("dev key %q is not PEM encoded", keyPath)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse dev key, %v", err)
+	}
+
+	now := time.Now()
+	claims := jwt.RegisteredClaims{
+		Subject:   userId,
+		Issuer:    issuer,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+	if audience != "" {
+		claims.Audience = jwt.ClaimStrings{audience}
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = devKeyId
+
+	return token.SignedString(key)
+}
@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"time"
+)
+
+// fetchTimeout bounds fetching the issuer's JWKS, it is fetched on every cold start
+const fetchTimeout = 5 * time.Second
+
+// maxJwksSize is far larger than any real key set
+const maxJwksSize = 1 << 20
+
+// KeySet holds the public keys of a JWKS document, by key id
+type KeySet struct {
+	keys map[string]crypto.PublicKey
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// LoadKeySet reads a JWKS file, only RSA, EC and Ed25519 signing keys are used
+func LoadKeySet(path string) (*KeySet, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read jwks file %q, %v", path, err)
+	}
+
+	return parseKeySet(body, path)
+}
+
+// FetchKeySet downloads the JWKS document the token issuer publishes, like LoadKeySet it only
+// uses signing keys
+func FetchKeySet(ctx context.Context, url string) (*KeySet, error) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jwks url %q, %v", url, err)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch jwks %q, %v", url, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch jwks %q, status %d", url, res.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxJwksSize))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read jwks %q, %v", url, err)
+	}
+
+	return parseKeySet(body, url)
+}
+
+// parseKeySet reads a JWKS document, source names where it came from in errors
+func parseKeySet(body []byte, source string) (*KeySet, error) {
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil, fmt.Errorf("unable to parse jwks %q, %v", source, err)
+	}
+
+	keySet := &KeySet{keys: make(map[string]crypto.PublicKey)}
+	for _, key := range document.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+
+		publicKey, err := key.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q in jwks %q, %v", key.Kid, source, err)
+		}
+
+		keySet.keys[key.Kid] = publicKey
+	}
+
+	if len(keySet.keys) == 0 {
+		return nil, fmt.Errorf("jwks %q has no signing keys", source)
+	}
+
+	return keySet, nil
+}
+
+// Key returns the key with the given id. Tokens without a key id are only accepted
+// when the set has a single key.
+func (s *KeySet) Key(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus, %v", err)
+		}
+
+		e, err := decodeBigInt(k.E)
+		if err != nil || !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid exponent")
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x coordinate, %v", err)
+		}
+
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y coordinate, %v", err)
+		}
+
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on curve %q", k.Crv)
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid public key")
+		}
+
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bytes) == 0 {
+		return nil, fmt.Errorf("value is empty")
+	}
+
+	return new(big.Int).SetBytes(bytes), nil
+}
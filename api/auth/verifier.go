@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// leeway allows for clock skew between the token issuer and the api
+const leeway = 30 * time.Second
+
+// Verifier checks bearer tokens against a KeySet
+type Verifier struct {
+	keys   *KeySet
+	parser *jwt.Parser
+}
+
+// NewVerifier creates a Verifier, issuer and audience are only checked when not empty
+func NewVerifier(keys *KeySet, issuer string, audience string) *Verifier {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(leeway),
+	}
+	if issuer != "" {
+		options = append(options, jwt.WithIssuer(issuer))
+	}
+	if audience != "" {
+		options = append(options, jwt.WithAudience(audience))
+	}
+
+	return &Verifier{
+		keys:   keys,
+		parser: jwt.NewParser(options...),
+	}
+}
+
+// Verify checks the signature and claims of a token and returns its subject, the user id
+func (v *Verifier) Verify(token string) (string, error) {
+	var claims jwt.RegisteredClaims
+
+	_, err := v.parser.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		key, ok := v.keys.Key(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+
+		return key, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if claims.Subject == "" {
+		return "", fmt.Errorf("%w: token has no subject", ErrInvalidToken)
+	}
+
+	return claims.Subject, nil
+}
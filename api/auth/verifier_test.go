@@ -0,0 +1,277 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com/"
+	testAudience = "groceries"
+)
+
+// testKeys are the private keys behind the JWKS of newTestKeySet, kids "rsa" and "ec"
+type testKeys struct {
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey
+}
+
+func newTestKeySet(t *testing.T) (*KeySet, []byte, testKeys) {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey() error = %v", err)
+	}
+
+	encode := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+	jwks, err := json.Marshal(map[string][]jsonWebKey{"keys": {
+		{Kid: "rsa", Kty: "RSA", Use: "sig", N: encode(rsaKey.N), E: encode(big.NewInt(int64(rsaKey.E)))},
+		{Kid: "ec", Kty: "EC", Crv: "P-256", X: encode(ecKey.X), Y: encode(ecKey.Y)},
+		// Encryption keys are left out
+		{Kid: "enc", Kty: "RSA", Use: "enc", N: encode(rsaKey.N), E: encode(big.NewInt(int64(rsaKey.E)))},
+	}})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	keySet, err := parseKeySet(jwks, "test")
+	if err != nil {
+		t.Fatalf("parseKeySet() error = %v", err)
+	}
+
+	return keySet, jwks, testKeys{rsa: rsaKey, ec: ecKey}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key crypto.PrivateKey, claims jwt.Claims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+
+	return signed
+}
+
+func TestVerifierVerify(t *testing.T) {
+	keySet, _, keys := newTestKeySet(t)
+	verifier := NewVerifier(keySet, testIssuer, testAudience)
+
+	now := time.Now()
+	valid := func() jwt.RegisteredClaims {
+		return jwt.RegisteredClaims{
+			Subject:   "u1",
+			Issuer:    testIssuer,
+			Audience:  jwt.ClaimStrings{testAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		}
+	}
+	with := func(change func(*jwt.RegisteredClaims)) jwt.RegisteredClaims {
+		claims := valid()
+		change(&claims)
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "rsa", token: sign(t, jwt.SigningMethodRS256, "rsa", keys.rsa, valid())},
+		{name: "ec", token: sign(t, jwt.SigningMethodES256, "ec", keys.ec, valid())},
+		{
+			name:  "expired within the leeway",
+			token: sign(t, jwt.SigningMethodRS256, "rsa", keys.rsa, with(func(c *jwt.RegisteredClaims) { c.ExpiresAt = jwt.NewNumericDate(now.Add(-leeway / 2)) })),
+		},
+		{
+			name:    "expired",
+			token:   sign(t, jwt.SigningMethodRS256, "rsa", keys.rsa, with(func(c *jwt.RegisteredClaims) { c.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Hour)) })),
+			wantErr: true,
+		},
+		{
+			name:    "without expiry",
+			token:   sign(t, jwt.SigningMethodRS256, "rsa", keys.rsa, with(func(c *jwt.RegisteredClaims) { c.ExpiresAt = nil })),
+			wantErr: true,
+		},
+		{
+			name:    "other issuer",
+			token:   sign(t, jwt.SigningMethodRS256, "rsa", keys.rsa, with(func(c *jwt.RegisteredClaims) { c.Issuer = "https://evil.example.com/" })),
+			wantErr: true,
+		},
+		{
+			name:    "other audience",
+			token:   sign(t, jwt.SigningMethodRS256, "rsa", keys.rsa, with(func(c *jwt.RegisteredClaims) { c.Audience = jwt.ClaimStrings{"other"} })),
+			wantErr: true,
+		},
+		{
+			name:    "without subject",
+			token:   sign(t, jwt.SigningMethodRS256, "rsa", keys.rsa, with(func(c *jwt.RegisteredClaims) { c.Subject = "" })),
+			wantErr: true,
+		},
+		{
+			name:    "signed by the other key",
+			token:   sign(t, jwt.SigningMethodES256, "rsa", keys.ec, valid()),
+			wantErr: true,
+		},
+		{
+			name:    "unknown key",
+			token:   sign(t, jwt.SigningMethodRS256, "other", keys.rsa, valid()),
+			wantErr: true,
+		},
+		{
+			name:    "encryption key",
+			token:   sign(t, jwt.SigningMethodRS256, "enc", keys.rsa, valid()),
+			wantErr: true,
+		},
+		{
+			name:    "without key id in a set of several",
+			token:   sign(t, jwt.SigningMethodRS256, "", keys.rsa, valid()),
+			wantErr: true,
+		},
+		{
+			name:    "hmac",
+			token:   sign(t, jwt.SigningMethodHS256, "rsa", []byte("secret"), valid()),
+			wantErr: true,
+		},
+		{
+			name:    "unsigned",
+			token:   sign(t, jwt.SigningMethodNone, "rsa", jwt.UnsafeAllowNoneSignatureType, valid()),
+			wantErr: true,
+		},
+		{
+			name:    "garbage",
+			token:   "not.a.token",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			userId, err := verifier.Verify(test.token)
+			if test.wantErr {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Errorf("Verify() = %q, %v, want ErrInvalidToken", userId, err)
+				}
+				return
+			}
+
+			if err != nil || userId != "u1" {
+				t.Errorf("Verify() = %q, %v, want u1", userId, err)
+			}
+		})
+	}
+}
+
+func TestDevToken(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "dev-key.pem")
+	jwksPath := filepath.Join(dir, "jwks.json")
+
+	if err := EnsureDevKey(keyPath, jwksPath); err != nil {
+		t.Fatalf("EnsureDevKey() error = %v", err)
+	}
+	// A second call keeps the key
+	if err := EnsureDevKey(keyPath, jwksPath); err != nil {
+		t.Fatalf("EnsureDevKey() again error = %v", err)
+	}
+
+	keySet, err := LoadKeySet(jwksPath)
+	if err != nil {
+		t.Fatalf("LoadKeySet() error = %v", err)
+	}
+
+	token, err := SignDevToken(keyPath, "u1", testIssuer, testAudience, time.Hour)
+	if err != nil {
+		t.Fatalf("SignDevToken() error = %v", err)
+	}
+
+	if userId, err := NewVerifier(keySet, testIssuer, testAudience).Verify(token); err != nil || userId != "u1" {
+		t.Errorf("Verify() = %q, %v, want u1", userId, err)
+	}
+	if _, err := NewVerifier(keySet, "https://other.example.com/", "").Verify(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() with another issuer error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestEnsureDevKeyKeepsForeignJwks(t *testing.T) {
+	dir := t.TempDir()
+	_, jwks, _ := newTestKeySet(t)
+
+	jwksPath := filepath.Join(dir, "jwks.json")
+	if err := os.WriteFile(jwksPath, jwks, 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if err := EnsureDevKey(filepath.Join(dir, "dev-key.pem"), jwksPath); err == nil {
+		t.Error("EnsureDevKey() error = nil, want the jwks left alone")
+	}
+}
+
+func TestFetchKeySet(t *testing.T) {
+	_, jwks, _ := newTestKeySet(t)
+
+	tests := []struct {
+		name    string
+		status  int
+		body    []byte
+		wantErr bool
+	}{
+		{name: "ok", status: http.StatusOK, body: jwks},
+		{name: "server error", status: http.StatusInternalServerError, body: jwks, wantErr: true},
+		{name: "not json", status: http.StatusOK, body: []byte("<html>"), wantErr: true},
+		{name: "no signing keys", status: http.StatusOK, body: []byte(`{"keys":[]}`), wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.status)
+				w.Write(test.body)
+			}))
+			defer server.Close()
+
+			keySet, err := FetchKeySet(context.Background(), server.URL)
+			if test.wantErr {
+				if err == nil {
+					t.Error("FetchKeySet() error = nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FetchKeySet() error = %v", err)
+			}
+
+			for _, kid := range []string{"rsa", "ec"} {
+				if _, ok := keySet.Key(kid); !ok {
+					t.Errorf("Key(%q) is missing", kid)
+				}
+			}
+		})
+	}
+}
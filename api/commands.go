@@ -0,0 +1,233 @@
+package main
+
+import (
+	"api/auth"
+	"api/config"
+	"api/models"
+	receiptprocessor "api/receipt-processor"
+	"api/routes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+const usage = `usage: api <command> [flags]
+
+commands:
+  serve              serve the api over http
+  lambda             serve the api as an AWS Lambda handler
+  process-receipts   convert unprocessed receipts into text
+  delete-households  finish deleting households that are pending deletion
+  add-staples        add the staples that are due to their lists
+  dev-token          print a bearer token for running the api locally
+
+Run without a command the api serves as a Lambda handler when LAMBDA_TASK_ROOT
+is set and over http otherwise.`
+
+const (
+	serverReadHeaderTimeout = 10 * time.Second
+	serverReadTimeout       = 30 * time.Second
+	serverWriteTimeout      = 60 * time.Second
+	serverIdleTimeout       = 120 * time.Second
+	// serverShutdownTimeout is how long in-flight requests get to finish after a shutdown signal
+	serverShutdownTimeout = 20 * time.Second
+)
+
+// run dispatches to the subcommand named by the first argument
+func run(args []string, cfg *config.Config) error {
+	// Stop on SIGINT/SIGTERM, Lambda and ECS send SIGTERM before killing the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	command := ""
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	if command == "" {
+		if _, isLambda := os.LookupEnv("LAMBDA_TASK_ROOT"); isLambda {
+			command = "lambda"
+		} else {
+			command = "serve"
+		}
+	}
+
+	switch command {
+	case "serve":
+		return runServe(ctx, args, cfg)
+	case "lambda":
+		return runLambda(ctx, args, cfg)
+	case "process-receipts":
+		return runProcessReceipts(ctx, args, cfg)
+	case "delete-households", "add-staples":
+		return runScheduledJob(ctx, command, args, cfg)
+	case "dev-token":
+		return runDevToken(args, cfg)
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", command, usage)
+	}
+}
+
+func runServe(ctx context.Context, args []string, cfg *config.Config) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := flags.String("addr", ":8080", "address to listen on")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	api, err := newApi(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create api, %v", err)
+	}
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           newRouter(api),
+		ReadHeaderTimeout: serverReadHeaderTimeout,
+		ReadTimeout:       serverReadTimeout,
+		WriteTimeout:      serverWriteTimeout,
+		IdleTimeout:       serverIdleTimeout,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("listening on %s", *addr)
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("unable to serve, %v", err)
+	case <-ctx.Done():
+	}
+
+	log.Println("shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("unable to shut down cleanly, %v", err)
+	}
+
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("unable to serve, %v", err)
+	}
+
+	return nil
+}
+
+func runLambda(ctx context.Context, args []string, cfg *config.Config) error {
+	flags := flag.NewFlagSet("lambda", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	api, err := newApi(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create api, %v", err)
+	}
+
+	router = newRouter(api)
+	lambda.StartWithOptions(newLambdaHandler(api), lambda.WithContext(ctx))
+
+	return nil
+}
+
+// scheduledJobs are the jobs that run on a schedule, by name. On Lambda they are invoked
+// with a lambdaJobEvent, elsewhere as a subcommand of the same name.
+var scheduledJobs = map[string]func(context.Context, *routes.Api) error{
+	"delete-households": func(ctx context.Context, api *routes.Api) error {
+		return api.Deletions.Run(ctx)
+	},
+	"add-staples": func(ctx context.Context, api *routes.Api) error {
+		return api.StapleJob.Run(ctx)
+	},
+}
+
+func runScheduledJob(ctx context.Context, name string, args []string, cfg *config.Config) error {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	api, err := newApi(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create api, %v", err)
+	}
+
+	return scheduledJobs[name](ctx, api)
+}
+
+// runDevToken signs a token with a key of its own, which it makes on first use along with the JWKS
+// at API_JWKS_FILE. Only an api that reads that JWKS accepts the tokens.
+func runDevToken(args []string, cfg *config.Config) error {
+	flags := flag.NewFlagSet("dev-token", flag.ContinueOnError)
+	user := flags.String("user", "", "id of the user the token is for")
+	keyPath := flags.String("key", "dev-key.pem", "file the private key is kept in")
+	ttl := flags.Duration("ttl", 24*time.Hour, "how long the token is valid for")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *user == "" {
+		return errors.New("user must not be empty")
+	}
+	if cfg.Auth.JwksFile == "" {
+		return errors.New("dev tokens need API_JWKS_FILE, not API_JWKS_URL")
+	}
+
+	if err := auth.EnsureDevKey(*keyPath, cfg.Auth.JwksFile); err != nil {
+		return err
+	}
+
+	token, err := auth.SignDevToken(*keyPath, *user, cfg.Auth.Issuer, cfg.Auth.Audience, *ttl)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(token)
+	return nil
+}
+
+func runProcessReceipts(ctx context.Context, args []string, cfg *config.Config) error {
+	flags := flag.NewFlagSet("process-receipts", flag.ContinueOnError)
+	contextName := flags.String("context", "business", "kind of receipts to process, business or retail")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var receiptContext models.ReceiptContext
+	switch strings.ToLower(*contextName) {
+	case "business":
+		receiptContext = models.BusinessReceipt
+	case "retail":
+		receiptContext = models.RetailReceipt
+	default:
+		return fmt.Errorf("context must be one of business or retail, got %q", *contextName)
+	}
+
+	api, err := newApi(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create api, %v", err)
+	}
+
+	if _, err := receiptprocessor.ConvertAllReceiptsIntoText(ctx, api.Receipts, receiptContext); err != nil {
+		return fmt.Errorf("unable to process receipts, %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,284 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+type Stage string
+
+const (
+	Dev     Stage = "dev"
+	Staging Stage = "staging"
+	Prod    Stage = "prod"
+)
+
+type Storage string
+
+const (
+	// DynamoStorage keeps records in DynamoDB and blobs in S3
+	DynamoStorage Storage = "dynamodb"
+	// MemoryStorage keeps records in process and blobs under BlobDir, for running without AWS credentials
+	MemoryStorage Storage = "memory"
+)
+
+type Tables struct {
+	Groceries  string `json:"groceries"`
+	Users      string `json:"users"`
+	Households string `json:"households"`
+	Invites    string `json:"invites"`
+	Members    string `json:"members"`
+	Devices    string `json:"devices"`
+	Pairings   string `json:"pairings"`
+	Layouts    string `json:"layouts"`
+	Ops        string `json:"ops"`
+	Lists      string `json:"lists"`
+	Staples    string `json:"staples"`
+}
+
+type Buckets struct {
+	Catalog             string `json:"catalog"`
+	UnprocessedReceipts string `json:"unprocessedReceipts"`
+	ProcessedReceipts   string `json:"processedReceipts"`
+	Exports             string `json:"exports"`
+}
+
+type Auth struct {
+	// JwksUrl is where the token issuer publishes the public keys bearer tokens are signed with,
+	// they are fetched on startup. Without it they are read from JwksFile.
+	JwksUrl  string `json:"jwksUrl"`
+	JwksFile string `json:"jwksFile"`
+	// Issuer and Audience are checked against the token claims when set
+	Issuer   string `json:"issuer"`
+	Audience string `json:"audience"`
+}
+
+// Config is everything that differs between deployments of the api
+type Config struct {
+	Stage   Stage   `json:"stage"`
+	Region  string  `json:"region"`
+	Storage Storage `json:"storage"`
+	// ResourcePrefix is put in front of the default table and bucket names,
+	// names that are set explicitly are used as is
+	ResourcePrefix *string `json:"resourcePrefix"`
+	Tables         Tables  `json:"tables"`
+	Buckets        Buckets `json:"buckets"`
+	CatalogKey     string  `json:"catalogKey"`
+	Auth           Auth    `json:"auth"`
+	// MaxReceiptSize is the largest receipt upload accepted, in bytes
+	MaxReceiptSize int64 `json:"maxReceiptSize"`
+	// BlobDir and BaseUrl are only used with MemoryStorage
+	BlobDir string `json:"blobDir"`
+	BaseUrl string `json:"baseUrl"`
+}
+
+// stagePrefixes are the default resource prefixes, prod keeps the original unprefixed names
+var stagePrefixes = map[Stage]string{
+	Dev:     "dev-",
+	Staging: "staging-",
+	Prod:    "",
+}
+
+const maxS3ObjectSize int64 = 5 * 1024 * 1024 * 1024 // 5GB, the S3 single PUT limit
+
+var bucketNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+var tableNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]{3,255}$`)
+
+// Load reads the config file named by API_CONFIG_FILE, if any, then applies environment
+// variable overrides and fills in defaults for the stage before validating the result
+func Load() (*Config, error) {
+	cfg := &Config{}
+
+	if path, ok := os.LookupEnv("API_CONFIG_FILE"); ok {
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read config file %q, %v", path, err)
+		}
+
+		if err := json.Unmarshal(body, cfg); err != nil {
+			return nil, fmt.Errorf("unable to parse config file %q, %v", path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	cfg.applyDefaults()
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func (cfg *Config) applyEnv() error {
+	setFromEnv((*string)(&cfg.Stage), "API_STAGE")
+	setFromEnv(&cfg.Region, "API_REGION")
+	setFromEnv((*string)(&cfg.Storage), "API_STORAGE")
+	setFromEnv(&cfg.Tables.Groceries, "API_GROCERIES_TABLE")
+	setFromEnv(&cfg.Tables.Users, "API_USERS_TABLE")
+	setFromEnv(&cfg.Tables.Households, "API_HOUSEHOLDS_TABLE")
+	setFromEnv(&cfg.Tables.Invites, "API_INVITES_TABLE")
+	setFromEnv(&cfg.Tables.Members, "API_MEMBERS_TABLE")
+	setFromEnv(&cfg.Tables.Devices, "API_DEVICES_TABLE")
+	setFromEnv(&cfg.Tables.Pairings, "API_PAIRINGS_TABLE")
+	setFromEnv(&cfg.Tables.Layouts, "API_LAYOUTS_TABLE")
+	setFromEnv(&cfg.Tables.Ops, "API_OPS_TABLE")
+	setFromEnv(&cfg.Tables.Lists, "API_LISTS_TABLE")
+	setFromEnv(&cfg.Tables.Staples, "API_STAPLES_TABLE")
+	setFromEnv(&cfg.Buckets.Catalog, "API_CATALOG_BUCKET")
+	setFromEnv(&cfg.Buckets.UnprocessedReceipts, "API_UNPROCESSED_RECEIPTS_BUCKET")
+	setFromEnv(&cfg.Buckets.ProcessedReceipts, "API_PROCESSED_RECEIPTS_BUCKET")
+	setFromEnv(&cfg.Buckets.Exports, "API_EXPORTS_BUCKET")
+	setFromEnv(&cfg.CatalogKey, "API_CATALOG_KEY")
+	setFromEnv(&cfg.Auth.JwksUrl, "API_JWKS_URL")
+	setFromEnv(&cfg.Auth.JwksFile, "API_JWKS_FILE")
+	setFromEnv(&cfg.Auth.Issuer, "API_JWT_ISSUER")
+	setFromEnv(&cfg.Auth.Audience, "API_JWT_AUDIENCE")
+	setFromEnv(&cfg.BlobDir, "API_BLOB_DIR")
+	setFromEnv(&cfg.BaseUrl, "API_BASE_URL")
+
+	if prefix, ok := os.LookupEnv("API_RESOURCE_PREFIX"); ok {
+		cfg.ResourcePrefix = &prefix
+	}
+
+	if value, ok := os.LookupEnv("API_MAX_RECEIPT_SIZE"); ok {
+		maxReceiptSize, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("API_MAX_RECEIPT_SIZE must be a number of bytes, got %q", value)
+		}
+		cfg.MaxReceiptSize = maxReceiptSize
+	}
+
+	return nil
+}
+
+func (cfg *Config) applyDefaults() {
+	setDefault((*string)(&cfg.Stage), string(Prod))
+	setDefault(&cfg.Region, "ap-southeast-2")
+	setDefault((*string)(&cfg.Storage), string(DynamoStorage))
+
+	prefix := stagePrefixes[cfg.Stage]
+	if cfg.ResourcePrefix != nil {
+		prefix = *cfg.ResourcePrefix
+	}
+
+	setDefault(&cfg.Tables.Groceries, prefix+"Groceries")
+	setDefault(&cfg.Tables.Users, prefix+"Users")
+	setDefault(&cfg.Tables.Households, prefix+"Households")
+	setDefault(&cfg.Tables.Invites, prefix+"HouseholdInvites")
+	setDefault(&cfg.Tables.Members, prefix+"HouseholdMembers")
+	setDefault(&cfg.Tables.Devices, prefix+"Devices")
+	setDefault(&cfg.Tables.Pairings, prefix+"PairingCodes")
+	setDefault(&cfg.Tables.Layouts, prefix+"GroceryLayouts")
+	setDefault(&cfg.Tables.Ops, prefix+"GroceryOps")
+	setDefault(&cfg.Tables.Lists, prefix+"GroceryLists")
+	setDefault(&cfg.Tables.Staples, prefix+"GroceryStaples")
+	setDefault(&cfg.Buckets.Catalog, prefix+"store-comparison-bucket-001")
+	setDefault(&cfg.Buckets.UnprocessedReceipts, prefix+"unprocessed-receipts-001")
+	setDefault(&cfg.Buckets.ProcessedReceipts, prefix+"processed-receipts-001")
+	setDefault(&cfg.Buckets.Exports, prefix+"account-exports-001")
+	setDefault(&cfg.CatalogKey, "catalog.json")
+	if cfg.Auth.JwksUrl == "" {
+		setDefault(&cfg.Auth.JwksFile, "jwks.json")
+	}
+
+	if cfg.MaxReceiptSize == 0 {
+		cfg.MaxReceiptSize = 10 * 1024 * 1024 // 10MB
+	}
+
+	if cfg.Storage == MemoryStorage {
+		setDefault(&cfg.BlobDir, "blobs")
+		setDefault(&cfg.BaseUrl, "http://localhost:8080")
+	}
+}
+
+// Validate reports every problem with the config at once
+func (cfg *Config) Validate() error {
+	var errs []error
+
+	if _, ok := stagePrefixes[cfg.Stage]; !ok {
+		errs = append(errs, fmt.Errorf("stage must be one of dev, staging or prod, got %q", cfg.Stage))
+	}
+
+	if cfg.Storage != DynamoStorage && cfg.Storage != MemoryStorage {
+		errs = append(errs, fmt.Errorf("storage must be one of dynamodb or memory, got %q", cfg.Storage))
+	}
+
+	if cfg.Region == "" {
+		errs = append(errs, errors.New("region must not be empty"))
+	}
+
+	for _, table := range [][2]string{
+		{"groceries", cfg.Tables.Groceries},
+		{"users", cfg.Tables.Users},
+		{"households", cfg.Tables.Households},
+		{"invites", cfg.Tables.Invites},
+		{"members", cfg.Tables.Members},
+		{"devices", cfg.Tables.Devices},
+		{"pairings", cfg.Tables.Pairings},
+		{"layouts", cfg.Tables.Layouts},
+		{"ops", cfg.Tables.Ops},
+		{"lists", cfg.Tables.Lists},
+		{"staples", cfg.Tables.Staples},
+	} {
+		if !tableNameRegex.MatchString(table[1]) {
+			errs = append(errs, fmt.Errorf("%s table name %q is not a valid DynamoDB table name", table[0], table[1]))
+		}
+	}
+
+	for _, bucket := range [][2]string{
+		{"catalog", cfg.Buckets.Catalog},
+		{"unprocessed receipts", cfg.Buckets.UnprocessedReceipts},
+		{"processed receipts", cfg.Buckets.ProcessedReceipts},
+		{"exports", cfg.Buckets.Exports},
+	} {
+		if !bucketNameRegex.MatchString(bucket[1]) {
+			errs = append(errs, fmt.Errorf("%s bucket name %q is not a valid S3 bucket name", bucket[0], bucket[1]))
+		}
+	}
+
+	if cfg.Buckets.UnprocessedReceipts == cfg.Buckets.ProcessedReceipts {
+		errs = append(errs, errors.New("unprocessed and processed receipt buckets must be different"))
+	}
+
+	if cfg.CatalogKey == "" {
+		errs = append(errs, errors.New("catalog key must not be empty"))
+	}
+
+	if (cfg.Auth.JwksUrl == "") == (cfg.Auth.JwksFile == "") {
+		errs = append(errs, errors.New("exactly one of jwks url and jwks file must be set"))
+	}
+
+	if cfg.MaxReceiptSize <= 0 || cfg.MaxReceiptSize > maxS3ObjectSize {
+		errs = append(errs, fmt.Errorf("max receipt size must be between 1 and %d bytes, got %d", maxS3ObjectSize, cfg.MaxReceiptSize))
+	}
+
+	if cfg.Storage == MemoryStorage && (cfg.BlobDir == "" || cfg.BaseUrl == "") {
+		errs = append(errs, errors.New("blob dir and base url must be set when using memory storage"))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+func setFromEnv(field *string, key string) {
+	if value, ok := os.LookupEnv(key); ok {
+		*field = value
+	}
+}
+
+func setDefault(field *string, defaultValue string) {
+	if *field == "" {
+		*field = defaultValue
+	}
+}
@@ -0,0 +1,19 @@
+package events
+
+import (
+	"api/models"
+	"context"
+)
+
+// Bus carries household events from whoever changed the household to the members watching it
+type Bus interface {
+	// Publish assigns the event an id and delivers it to the household's subscribers
+	Publish(ctx context.Context, event models.HouseholdEvent) error
+	// Subscribe delivers the household's events published after the event with id afterId, then new
+	// ones as they are published, until ctx is done or the subscriber falls too far behind. Both end
+	// with the channel being closed. Without afterId only new events are delivered, and when the
+	// events after afterId are no longer known a models.EventsReset event comes first.
+	// The returned position is the id of the last event published before the subscription started,
+	// resuming from it misses nothing.
+	Subscribe(ctx context.Context, householdId string, afterId string) (events <-chan models.HouseholdEvent, position string, err error)
+}
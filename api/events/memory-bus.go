@@ -0,0 +1,180 @@
+package events
+
+import (
+	"api/models"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recentEventsPerHousehold is how many events a MemoryBus keeps per household for subscribers
+// resuming from an earlier event
+const recentEventsPerHousehold = 100
+
+// subscriberBuffer is how many events can queue up for a subscriber before it is dropped
+const subscriberBuffer = 64
+
+// MemoryBus is a Bus that only reaches subscribers in the same process. That is every member
+// when serving from a single process, but on Lambda only those whose requests land on the same
+// instance, which needs a Bus shared between instances instead.
+//
+// Events are only kept for households while someone is subscribed to them, resuming after an
+// event that was published while nobody was subscribed gets a reset.
+type MemoryBus struct {
+	mu sync.Mutex
+	// instance tells apart the ids of buses in different processes, ids from another process
+	// can't be resumed from
+	instance   string
+	lastId     uint64
+	households map[string]*householdEvents
+}
+
+type householdEvents struct {
+	recent []models.HouseholdEvent
+	// evictedUpTo is the number of the last event that no longer is in recent
+	evictedUpTo uint64
+	subscribers map[chan models.HouseholdEvent]struct{}
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{
+		instance:   strconv.FormatInt(time.Now().UnixNano(), 36),
+		households: make(map[string]*householdEvents),
+	}
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, event models.HouseholdEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastId++
+	event.Id = b.eventId(b.lastId)
+
+	household, ok := b.households[event.HouseholdId]
+	if !ok {
+		return nil
+	}
+
+	household.recent = append(household.recent, event)
+	if len(household.recent) > recentEventsPerHousehold {
+		household.evictedUpTo = b.number(household.recent[0].Id)
+		household.recent = append(household.recent[:0], household.recent[1:]...)
+	}
+
+	for subscriber := range household.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			// Too slow to keep up, it can resume from the last event it got
+			b.unsubscribe(event.HouseholdId, household, subscriber)
+		}
+	}
+
+	return nil
+}
+
+func (b *MemoryBus) Subscribe(ctx context.Context, householdId string, afterId string) (<-chan models.HouseholdEvent, string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	household := b.household(householdId)
+	backlog := b.since(household, householdId, afterId)
+
+	subscriber := make(chan models.HouseholdEvent, subscriberBuffer+len(backlog))
+	for _, event := range backlog {
+		subscriber <- event
+	}
+	household.subscribers[subscriber] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		b.unsubscribe(householdId, household, subscriber)
+	}()
+
+	return subscriber, b.eventId(b.lastId), nil
+}
+
+// since returns the household's events after afterId, or a reset event when they aren't all known
+func (b *MemoryBus) since(household *householdEvents, householdId string, afterId string) []models.HouseholdEvent {
+	if afterId == "" {
+		return nil
+	}
+
+	after, ok := b.parseEventId(afterId)
+	if !ok || after > b.lastId || after < household.evictedUpTo {
+		return []models.HouseholdEvent{{
+			Id:          b.eventId(b.lastId),
+			HouseholdId: householdId,
+			Type:        models.EventsReset,
+			At:          time.Now().UnixMilli(),
+		}}
+	}
+
+	events := make([]models.HouseholdEvent, 0)
+	for _, event := range household.recent {
+		if b.number(event.Id) > after {
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
+
+// household returns the events of a household someone is subscribing to. Events published
+// before it was first subscribed to weren't kept, they count as evicted.
+func (b *MemoryBus) household(householdId string) *householdEvents {
+	household, ok := b.households[householdId]
+	if !ok {
+		household = &householdEvents{
+			evictedUpTo: b.lastId,
+			subscribers: make(map[chan models.HouseholdEvent]struct{}),
+		}
+		b.households[householdId] = household
+	}
+
+	return household
+}
+
+// unsubscribe closes the subscriber unless that already happened, and forgets the household's
+// events once nobody is subscribed to it anymore
+func (b *MemoryBus) unsubscribe(householdId string, household *householdEvents, subscriber chan models.HouseholdEvent) {
+	if _, ok := household.subscribers[subscriber]; !ok {
+		return
+	}
+
+	delete(household.subscribers, subscriber)
+	close(subscriber)
+
+	if len(household.subscribers) == 0 && b.households[householdId] == household {
+		delete(b.households, householdId)
+	}
+}
+
+// eventId formats the number of an event as instance-number
+func (b *MemoryBus) eventId(number uint64) string {
+	return fmt.Sprintf("%s-%d", b.instance, number)
+}
+
+// parseEventId returns the number of an event id made by this bus
+func (b *MemoryBus) parseEventId(id string) (uint64, bool) {
+	instance, number, found := strings.Cut(id, "-")
+	if !found || instance != b.instance {
+		return 0, false
+	}
+
+	n, err := strconv.ParseUint(number, 10, 64)
+	return n, err == nil
+}
+
+// number returns the number of an event id known to be made by this bus
+func (b *MemoryBus) number(id string) uint64 {
+	n, _ := b.parseEventId(id)
+	return n
+}
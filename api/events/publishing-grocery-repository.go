@@ -0,0 +1,137 @@
+package events
+
+import (
+	"api/models"
+	"api/providers"
+	"api/proxy"
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// PublishingGroceryRepository is a providers.GroceryRepository that publishes an event for every
+// change made through it. Failing to publish doesn't fail the change, subscribers that missed
+// events catch up by fetching the list.
+type PublishingGroceryRepository struct {
+	providers.GroceryRepository
+	bus Bus
+}
+
+func NewPublishingGroceryRepository(groceries providers.GroceryRepository, bus Bus) *PublishingGroceryRepository {
+	return &PublishingGroceryRepository{GroceryRepository: groceries, bus: bus}
+}
+
+func (r *PublishingGroceryRepository) CreateGroceryItem(ctx context.Context, groceryItem models.GroceryItem) (models.GroceryItem, error) {
+	stored, err := r.GroceryRepository.CreateGroceryItem(ctx, groceryItem)
+	if err != nil {
+		return stored, err
+	}
+
+	r.publish(ctx, models.ItemCreated, stored)
+
+	return stored, nil
+}
+
+// UpdateGroceryItem publishes models.ItemChecked or models.ItemUnchecked when the update flipped
+// Checked and models.ItemUpdated otherwise, which takes reading the item before updating it
+func (r *PublishingGroceryRepository) UpdateGroceryItem(ctx context.Context, groceryItem models.GroceryItem) (models.GroceryItem, error) {
+	previous, previousErr := r.GroceryRepository.GetGroceryItem(ctx, groceryItem.HouseholdId, groceryItem.Id)
+
+	updated, err := r.GroceryRepository.UpdateGroceryItem(ctx, groceryItem)
+	if err != nil {
+		return updated, err
+	}
+
+	// The previous item is only the one that was replaced if nobody updated it in between
+	eventType := models.ItemUpdated
+	if previousErr == nil && previous.Version == groceryItem.Version && previous.Checked != updated.Checked {
+		eventType = models.ItemUnchecked
+		if updated.Checked {
+			eventType = models.ItemChecked
+		}
+	}
+
+	r.publish(ctx, eventType, updated)
+
+	return updated, nil
+}
+
+// DeleteGroceryItem only publishes models.ItemDeleted for items that were there to delete, which
+// takes reading the item before deleting it
+func (r *PublishingGroceryRepository) DeleteGroceryItem(ctx context.Context, householdId string, groceryItemId string) error {
+	_, existsErr := r.GroceryRepository.GetGroceryItem(ctx, householdId, groceryItemId)
+
+	if err := r.GroceryRepository.DeleteGroceryItem(ctx, householdId, groceryItemId); err != nil {
+		return err
+	}
+
+	if errors.Is(existsErr, proxy.ErrNotFound) {
+		return nil
+	}
+
+	r.publish(ctx, models.ItemDeleted, models.GroceryItem{HouseholdId: householdId, Id: groceryItemId, Deleted: true})
+
+	return nil
+}
+
+func (r *PublishingGroceryRepository) BatchCreateGroceryItems(ctx context.Context, groceryItems []models.GroceryItem) ([]models.GroceryItem, error) {
+	stored, err := r.GroceryRepository.BatchCreateGroceryItems(ctx, groceryItems)
+
+	for _, groceryItem := range stored {
+		r.publish(ctx, models.ItemCreated, groceryItem)
+	}
+
+	return stored, err
+}
+
+func (r *PublishingGroceryRepository) BatchDeleteGroceryItems(ctx context.Context, groceryItems []models.GroceryItem) ([]models.GroceryItem, error) {
+	notDeleted, err := r.GroceryRepository.BatchDeleteGroceryItems(ctx, groceryItems)
+
+	for _, groceryItem := range without(groceryItems, notDeleted) {
+		r.publish(ctx, models.ItemDeleted, models.GroceryItem{HouseholdId: groceryItem.HouseholdId, Id: groceryItem.Id, Deleted: true})
+	}
+
+	return notDeleted, err
+}
+
+func (r *PublishingGroceryRepository) publish(ctx context.Context, eventType models.HouseholdEventType, groceryItem models.GroceryItem) {
+	event := models.HouseholdEvent{
+		HouseholdId: groceryItem.HouseholdId,
+		Type:        eventType,
+		Item:        &groceryItem,
+		At:          time.Now().UnixMilli(),
+	}
+
+	if err := r.bus.Publish(ctx, event); err != nil {
+		log.Printf("could not publish %s of grocery item [%s]: %v\n", eventType, groceryItem.Id, err)
+	}
+}
+
+// without returns the grocery items that aren't in failed, matching them by key. Like the
+// batch writes, the last occurrence of a repeated key wins.
+func without(groceryItems []models.GroceryItem, failed []models.GroceryItem) []models.GroceryItem {
+	failedKeys := make(map[[2]string]bool, len(failed))
+	for _, groceryItem := range failed {
+		failedKeys[[2]string{groceryItem.HouseholdId, groceryItem.Id}] = true
+	}
+
+	indexes := make(map[[2]string]int, len(groceryItems))
+	succeeded := make([]models.GroceryItem, 0, len(groceryItems))
+	for _, groceryItem := range groceryItems {
+		key := [2]string{groceryItem.HouseholdId, groceryItem.Id}
+		if failedKeys[key] {
+			continue
+		}
+
+		if index, seen := indexes[key]; seen {
+			succeeded[index] = groceryItem
+			continue
+		}
+
+		indexes[key] = len(succeeded)
+		succeeded = append(succeeded, groceryItem)
+	}
+
+	return succeeded
+}
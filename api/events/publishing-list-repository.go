@@ -0,0 +1,63 @@
+package events
+
+import (
+	"api/models"
+	"api/providers"
+	"context"
+	"log"
+	"time"
+)
+
+// PublishingListRepository is a providers.ListRepository that publishes an event for every change
+// made through it, like PublishingGroceryRepository
+type PublishingListRepository struct {
+	providers.ListRepository
+	bus Bus
+}
+
+func NewPublishingListRepository(lists providers.ListRepository, bus Bus) *PublishingListRepository {
+	return &PublishingListRepository{ListRepository: lists, bus: bus}
+}
+
+func (r *PublishingListRepository) CreateList(ctx context.Context, list models.HouseholdList) error {
+	if err := r.ListRepository.CreateList(ctx, list); err != nil {
+		return err
+	}
+
+	r.publish(ctx, models.ListCreated, list)
+
+	return nil
+}
+
+func (r *PublishingListRepository) UpdateList(ctx context.Context, list models.HouseholdList) error {
+	if err := r.ListRepository.UpdateList(ctx, list); err != nil {
+		return err
+	}
+
+	r.publish(ctx, models.ListUpdated, list)
+
+	return nil
+}
+
+func (r *PublishingListRepository) DeleteList(ctx context.Context, householdId string, listId string) error {
+	if err := r.ListRepository.DeleteList(ctx, householdId, listId); err != nil {
+		return err
+	}
+
+	r.publish(ctx, models.ListDeleted, models.HouseholdList{HouseholdId: householdId, Id: listId})
+
+	return nil
+}
+
+func (r *PublishingListRepository) publish(ctx context.Context, eventType models.HouseholdEventType, list models.HouseholdList) {
+	event := models.HouseholdEvent{
+		HouseholdId: list.HouseholdId,
+		Type:        eventType,
+		List:        &list,
+		At:          time.Now().UnixMilli(),
+	}
+
+	if err := r.bus.Publish(ctx, event); err != nil {
+		log.Printf("could not publish %s of list [%s]: %v\n", eventType, list.Id, err)
+	}
+}
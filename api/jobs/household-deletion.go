@@ -0,0 +1,165 @@
+package jobs
+
+import (
+	"api/providers"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// deletionPageSize is how many grocery items are read and deleted per batch
+const deletionPageSize = 100
+
+// HouseholdDeletionJob removes households queued by HouseholdRepository.MarkHouseholdForDeletion
+// along with everything that belongs to them. Every step can be repeated, so a run that is
+// cut short, e.g. by the Lambda timeout, is finished by the next one.
+type HouseholdDeletionJob struct {
+	Households providers.HouseholdRepository
+	Users      providers.UserRepository
+	Groceries  providers.GroceryRepository
+	Invites    providers.InviteRepository
+	Layouts    providers.LayoutRepository
+	Lists      providers.ListRepository
+	Staples    providers.StapleRepository
+}
+
+// Run deletes every household that is pending deletion, carrying on past households that fail
+func (j *HouseholdDeletionJob) Run(ctx context.Context) error {
+	householdIds, err := j.Households.GetHouseholdsPendingDeletion(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get households pending deletion, %w", err)
+	}
+
+	var errs []error
+	for _, householdId := range householdIds {
+		if err := j.DeleteHousehold(ctx, householdId); err != nil {
+			errs = append(errs, err)
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// DeleteHousehold runs the deletion of a single household that was marked for deletion
+func (j *HouseholdDeletionJob) DeleteHousehold(ctx context.Context, householdId string) error {
+	// Members and staples go first so nothing is added to the household while it is being emptied
+	steps := []struct {
+		name string
+		run  func(context.Context, string) error
+	}{
+		{"detach members", j.detachMembers},
+		{"delete staples", j.deleteStaples},
+		{"delete grocery items", j.deleteGroceryItems},
+		{"delete invites", j.deleteInvites},
+		{"delete lists", j.deleteLists},
+		{"delete household", j.Households.DeleteHousehold},
+	}
+
+	for _, step := range steps {
+		if err := step.run(ctx, householdId); err != nil {
+			return fmt.Errorf("unable to %s of household [%s], %w", step.name, householdId, err)
+		}
+	}
+
+	log.Printf("deleted household [%s]\n", householdId)
+	return nil
+}
+
+// detachMembers takes the household off its members. Members that joined before the members index
+// existed only have it on their user record, those are found by going through the users.
+func (j *HouseholdDeletionJob) detachMembers(ctx context.Context, householdId string) error {
+	userIds, err := providers.GetHouseholdUserIds(ctx, j.Households, j.Users, householdId)
+	if err != nil {
+		return err
+	}
+
+	for _, userId := range userIds {
+		if err := j.Households.LeaveHousehold(ctx, userId, householdId); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (j *HouseholdDeletionJob) deleteGroceryItems(ctx context.Context, householdId string) error {
+	// Deleted items leave tombstones behind, so the pages are followed rather than the first page
+	// read again. Tombstones expire on their own.
+	cursor := ""
+	for {
+		groceryItems, nextCursor, err := j.Groceries.GetGroceryItemsPage(ctx, householdId, deletionPageSize, cursor)
+		if err != nil {
+			return err
+		}
+
+		if len(groceryItems) > 0 {
+			notDeleted, err := j.Groceries.BatchDeleteGroceryItems(ctx, groceryItems)
+			if err != nil {
+				return err
+			}
+
+			if len(notDeleted) > 0 {
+				return fmt.Errorf("%d of %d grocery items could not be deleted", len(notDeleted), len(groceryItems))
+			}
+		}
+
+		if nextCursor == "" {
+			return nil
+		}
+		cursor = nextCursor
+	}
+}
+
+func (j *HouseholdDeletionJob) deleteInvites(ctx context.Context, householdId string) error {
+	invites, err := j.Invites.GetHouseholdInvites(ctx, householdId)
+	if err != nil {
+		return err
+	}
+
+	for _, invite := range invites {
+		if err := j.Invites.DeleteInvite(ctx, invite.Code); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (j *HouseholdDeletionJob) deleteStaples(ctx context.Context, householdId string) error {
+	staples, err := j.Staples.GetStaples(ctx, householdId)
+	if err != nil {
+		return err
+	}
+
+	for _, staple := range staples {
+		if err := j.Staples.DeleteStaple(ctx, householdId, staple.Id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (j *HouseholdDeletionJob) deleteLists(ctx context.Context, householdId string) error {
+	lists, err := j.Lists.GetLists(ctx, householdId)
+	if err != nil {
+		return err
+	}
+
+	for _, list := range lists {
+		if err := j.Layouts.DeleteLayout(ctx, householdId, list.Id); err != nil {
+			return err
+		}
+
+		if err := j.Lists.DeleteList(ctx, householdId, list.Id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package jobs
+
+import (
+	"api/layouts"
+	"api/models"
+	"api/parsing"
+	"api/providers"
+	"api/proxy"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// StapleJob adds the staples that are due to their lists. A staple whose list already has an
+// unchecked item of the same name counts as added. Either way it is next due an interval later,
+// so a run that fails after adding the item doesn't add it twice on the next run. A staple that
+// is changed while it is being added keeps the change, it isn't moved on by the job.
+type StapleJob struct {
+	Staples   providers.StapleRepository
+	Lists     providers.ListRepository
+	Groceries providers.GroceryRepository
+	Layouts   *layouts.Editor
+}
+
+// Run adds every staple that is due, carrying on past staples that fail
+func (j *StapleJob) Run(ctx context.Context) error {
+	now := time.Now()
+
+	staples, err := j.Staples.GetDueStaples(ctx, now)
+	if err != nil {
+		return fmt.Errorf("unable to get due staples, %w", err)
+	}
+
+	// Items are read once per household rather than once per staple
+	groceryItems := make(map[string][]models.GroceryItem)
+
+	var errs []error
+	for _, staple := range staples {
+		items, read := groceryItems[staple.HouseholdId]
+		if !read {
+			items, err = j.Groceries.GetGroceryItems(ctx, staple.HouseholdId)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("unable to get the grocery items of household [%s], %w", staple.HouseholdId, err))
+				continue
+			}
+		}
+
+		added, err := j.AddStaple(ctx, staple, items, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to add staple [%s] of household [%s], %w", staple.Id, staple.HouseholdId, err))
+		}
+		if added != nil {
+			items = append(items, *added)
+		}
+		groceryItems[staple.HouseholdId] = items
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// AddStaple adds the staple to its list unless one of the household's items already stands in
+// for it, and schedules it again. It returns the item it added, if any.
+func (j *StapleJob) AddStaple(ctx context.Context, staple models.Staple, groceryItems []models.GroceryItem, now time.Time) (*models.GroceryItem, error) {
+	var added *models.GroceryItem
+
+	list, err := j.Lists.GetList(ctx, staple.HouseholdId, staple.ListId)
+	switch {
+	case errors.Is(err, proxy.ErrNotFound), err == nil && list.Archived:
+		// Staples of lists that are gone or archived wait for the list to be restored or the staple to be moved
+		log.Printf("skipped staple [%s], list [%s] can't be added to\n", staple.Id, staple.ListId)
+	case err != nil:
+		return nil, err
+	case !onList(staple, models.ItemsInList(groceryItems, list.Id)):
+		groceryItem := models.GroceryItem{HouseholdId: staple.HouseholdId, ListId: list.Id, Name: staple.Name}
+		if quantity, ok := parsing.ParseQuantity(groceryItem.Name); ok {
+			groceryItem.SetQuantity(quantity.Amount, quantity.Unit, quantity.Name)
+		}
+		groceryItem.GenerateID()
+
+		groceryItem, err := j.Groceries.CreateGroceryItem(ctx, groceryItem)
+		if err != nil {
+			return nil, err
+		}
+		added = &groceryItem
+		staple.LastAddedAt = now.Unix()
+
+		// The item is there either way, it ends up at the bottom of the list if it can't be placed
+		if err := j.Layouts.PlaceItem(ctx, staple.HouseholdId, list.Id, groceryItem.Id, ""); err != nil {
+			log.Printf("could not place grocery item [%s]: %v\n", groceryItem.Id, err)
+		}
+	}
+
+	staple.Advance(now)
+
+	_, err = j.Staples.UpdateStaple(ctx, staple)
+	if errors.Is(err, proxy.ErrConditionFailed) || errors.Is(err, proxy.ErrNotFound) {
+		log.Printf("staple [%s] was changed while it was being added, keeping the change\n", staple.Id)
+		return added, nil
+	}
+
+	return added, err
+}
+
+// onList tells whether an unchecked item of the list is the staple, by the name it was added as
+// or its name without the quantity
+func onList(staple models.Staple, groceryItems []models.GroceryItem) bool {
+	names := []string{staple.Name}
+	if quantity, ok := parsing.ParseQuantity(staple.Name); ok {
+		names = append(names, quantity.Name)
+	}
+
+	for _, groceryItem := range groceryItems {
+		if groceryItem.Checked {
+			continue
+		}
+
+		for _, name := range names {
+			if strings.EqualFold(groceryItem.Name, name) || strings.EqualFold(groceryItem.Text, name) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,129 @@
+package jobs
+
+import (
+	"api/layouts"
+	"api/models"
+	"api/providers"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStapleJobAddStaple(t *testing.T) {
+	day := int64(24 * 60 * 60)
+	now := time.Unix(100*day, 0)
+
+	tests := []struct {
+		name string
+		// onList is an item the household has before the job runs
+		onList   *models.GroceryItem
+		archived bool
+		// stale staples were updated by someone else after the job read them
+		stale     bool
+		wantAdded *models.GroceryItem
+	}{
+		{
+			name:      "added with its quantity",
+			wantAdded: &models.GroceryItem{Name: "milk", Quantity: 2, Unit: "liter"},
+		},
+		{
+			name:   "already on the list",
+			onList: &models.GroceryItem{Id: "item-1", Name: "milk"},
+		},
+		{
+			name:      "checked off the list",
+			onList:    &models.GroceryItem{Id: "item-1", Name: "milk", Checked: true},
+			wantAdded: &models.GroceryItem{Name: "milk", Quantity: 2, Unit: "liter"},
+		},
+		{
+			name:     "archived list",
+			archived: true,
+		},
+		{
+			name:      "changed while being added",
+			stale:     true,
+			wantAdded: &models.GroceryItem{Name: "milk", Quantity: 2, Unit: "liter"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			groceries := providers.NewMemoryGroceryRepository()
+			lists := providers.NewMemoryListRepository()
+			staples := providers.NewMemoryStapleRepository()
+			job := &StapleJob{
+				Staples:   staples,
+				Lists:     lists,
+				Groceries: groceries,
+				Layouts:   &layouts.Editor{Groceries: groceries, Layouts: providers.NewMemoryLayoutRepository()},
+			}
+
+			list := models.HouseholdList{HouseholdId: "h1", Id: "list-1", Name: "Weekly shop", Archived: test.archived}
+			if err := lists.CreateList(ctx, list); err != nil {
+				t.Fatalf("CreateList() error = %v", err)
+			}
+
+			staple := models.Staple{HouseholdId: "h1", Id: "staple-1", ListId: list.Id, Name: "2 l milk", Recurrence: models.Weekly, NextDueAt: now.Unix()}
+			if err := staples.CreateStaple(ctx, staple); err != nil {
+				t.Fatalf("CreateStaple() error = %v", err)
+			}
+
+			var items []models.GroceryItem
+			if test.onList != nil {
+				item := *test.onList
+				item.HouseholdId = "h1"
+				item.ListId = list.Id
+				if _, err := groceries.CreateGroceryItem(ctx, item); err != nil {
+					t.Fatalf("CreateGroceryItem() error = %v", err)
+				}
+				items = append(items, item)
+			}
+
+			current := staple
+			if test.stale {
+				changed := staple
+				changed.Name = "oat milk"
+				if current, _ = staples.UpdateStaple(ctx, changed); current.Name != changed.Name {
+					t.Fatalf("UpdateStaple() didn't store the change")
+				}
+			}
+
+			added, err := job.AddStaple(ctx, staple, items, now)
+			if err != nil {
+				t.Fatalf("AddStaple() error = %v", err)
+			}
+
+			if (added == nil) != (test.wantAdded == nil) {
+				t.Fatalf("AddStaple() added = %+v, want %+v", added, test.wantAdded)
+			}
+			if added != nil {
+				if added.Name != test.wantAdded.Name || added.Quantity != test.wantAdded.Quantity || added.Unit != test.wantAdded.Unit || !added.InList(list.Id) {
+					t.Errorf("AddStaple() added = %+v, want %+v on %s", added, test.wantAdded, list.Id)
+				}
+
+				layout, err := job.Layouts.Layouts.GetLayout(ctx, "h1", list.Id)
+				if err != nil || len(layout.Blocks) == 0 || layout.Blocks[len(layout.Blocks)-1].Value != added.Id {
+					t.Errorf("layout = %+v, %v, want the added item at the end", layout, err)
+				}
+			}
+
+			stored, err := staples.GetStaple(ctx, "h1", staple.Id)
+			if err != nil {
+				t.Fatalf("GetStaple() error = %v", err)
+			}
+			if test.stale {
+				if stored != current {
+					t.Errorf("stored staple = %+v, want the change %+v kept", stored, current)
+				}
+				return
+			}
+			if want := now.Unix() + 7*day; stored.NextDueAt != want {
+				t.Errorf("NextDueAt = %d, want %d", stored.NextDueAt, want)
+			}
+			if stored.Version != staple.Version+1 {
+				t.Errorf("Version = %d, want %d", stored.Version, staple.Version+1)
+			}
+		})
+	}
+}
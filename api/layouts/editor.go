@@ -0,0 +1,211 @@
+package layouts
+
+import (
+	"api/models"
+	"api/providers"
+	"api/proxy"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var ErrBlockNotFound = fmt.Errorf("layout block %w", proxy.ErrNotFound)
+
+// maxAttempts bounds how often a change is started over when the layout is saved by someone else
+// in the meantime
+const maxAttempts = 3
+
+// Editor changes how the lists of households are laid out. Changes apply to the layout as the list is
+// shown, see models.GroceryLayout.Arrange, and start over when someone else saved the layout first.
+type Editor struct {
+	Groceries providers.GroceryRepository
+	Layouts   providers.LayoutRepository
+}
+
+// MoveItem moves the item after the block with key afterId, an empty afterId moves it to the top
+func (e *Editor) MoveItem(ctx context.Context, householdId string, listId string, itemId string, afterId string) error {
+	groceryItem, err := e.Groceries.GetGroceryItem(ctx, householdId, itemId)
+	if err != nil {
+		return err
+	}
+	if !groceryItem.InList(listId) {
+		return fmt.Errorf("grocery item [%s] is not on list [%s]: %w", itemId, listId, proxy.ErrNotFound)
+	}
+
+	return e.edit(ctx, householdId, listId, func(layout models.GroceryLayout, blocks []models.LayoutBlock) ([]models.LayoutBlock, error) {
+		return insertAfter(remove(blocks, itemId), models.LayoutBlock{Type: models.GroceryItemId, Value: itemId}, afterId)
+	})
+}
+
+// PlaceItem finds a place for a new item: at the end of the section under the heading named
+// section, or at the end of the list when there is no such heading. Items that have a place
+// already stay where they are.
+func (e *Editor) PlaceItem(ctx context.Context, householdId string, listId string, itemId string, section string) error {
+	return e.edit(ctx, householdId, listId, func(layout models.GroceryLayout, blocks []models.LayoutBlock) ([]models.LayoutBlock, error) {
+		if slices.ContainsFunc(layout.Blocks, func(block models.LayoutBlock) bool { return block.Key() == itemId }) {
+			return blocks, nil
+		}
+
+		itemBlock := models.LayoutBlock{Type: models.GroceryItemId, Value: itemId}
+		blocks = remove(blocks, itemId)
+
+		heading := slices.IndexFunc(blocks, func(block models.LayoutBlock) bool {
+			return block.Type == models.Text && section != "" && strings.EqualFold(block.Value, section)
+		})
+		if heading == -1 {
+			return append(blocks, itemBlock), nil
+		}
+
+		end := heading + 1
+		for end < len(blocks) && blocks[end].Type != models.Text {
+			end++
+		}
+
+		return slices.Insert(blocks, end, itemBlock), nil
+	})
+}
+
+// RemoveItem takes an item that left the list out of its layout, other items keep their place
+func (e *Editor) RemoveItem(ctx context.Context, householdId string, listId string, itemId string) error {
+	return e.edit(ctx, householdId, listId, func(layout models.GroceryLayout, blocks []models.LayoutBlock) ([]models.LayoutBlock, error) {
+		return remove(blocks, itemId), nil
+	})
+}
+
+// InsertHeading adds a heading after the block with key afterId, or at the top when afterId is empty
+func (e *Editor) InsertHeading(ctx context.Context, householdId string, listId string, text string, afterId string) (models.LayoutBlock, error) {
+	heading := models.LayoutBlock{Id: uuid.NewString(), Type: models.Text, Value: text}
+
+	err := e.edit(ctx, householdId, listId, func(layout models.GroceryLayout, blocks []models.LayoutBlock) ([]models.LayoutBlock, error) {
+		return insertAfter(blocks, heading, afterId)
+	})
+
+	return heading, err
+}
+
+func (e *Editor) RenameHeading(ctx context.Context, householdId string, listId string, headingId string, text string) error {
+	return e.edit(ctx, householdId, listId, func(layout models.GroceryLayout, blocks []models.LayoutBlock) ([]models.LayoutBlock, error) {
+		index := indexOf(blocks, headingId)
+		if index == -1 || blocks[index].Type != models.Text {
+			return nil, ErrBlockNotFound
+		}
+
+		blocks[index].Value = text
+		return blocks, nil
+	})
+}
+
+// DeleteHeading removes the heading, the items under it stay where they are
+func (e *Editor) DeleteHeading(ctx context.Context, householdId string, listId string, headingId string) error {
+	return e.edit(ctx, householdId, listId, func(layout models.GroceryLayout, blocks []models.LayoutBlock) ([]models.LayoutBlock, error) {
+		index := indexOf(blocks, headingId)
+		if index == -1 || blocks[index].Type != models.Text {
+			return nil, ErrBlockNotFound
+		}
+
+		return slices.Delete(blocks, index, index+1), nil
+	})
+}
+
+// Replace lays out the list as blocks, items missing from them go at the end. Headings without an
+// id take the id of a heading with the same text, so that laying out the list the same way again
+// doesn't change it. Returns the layout as the list is shown now.
+func (e *Editor) Replace(ctx context.Context, householdId string, listId string, blocks []models.LayoutBlock) ([]models.LayoutBlock, error) {
+	var replaced []models.LayoutBlock
+
+	err := e.edit(ctx, householdId, listId, func(layout models.GroceryLayout, current []models.LayoutBlock) ([]models.LayoutBlock, error) {
+		headingIds := make(map[string]string)
+		for _, block := range current {
+			if block.Type == models.Text {
+				headingIds[block.Value] = block.Id
+			}
+		}
+
+		replaced = make([]models.LayoutBlock, len(blocks))
+		for index, block := range blocks {
+			if block.Type == models.Text && block.Id == "" {
+				block.Id = headingIds[block.Value]
+				if block.Id == "" {
+					block.Id = uuid.NewString()
+				}
+				// Repeated headings get an id of their own
+				delete(headingIds, block.Value)
+			}
+			replaced[index] = block
+		}
+
+		return replaced, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	groceryItems, err := e.Groceries.GetGroceryItems(ctx, householdId)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.GroceryLayout{Blocks: replaced}.Arrange(models.ItemsInList(groceryItems, listId)), nil
+}
+
+// edit applies change to the layout as the list is shown and saves the result, unless it is no
+// different. change is called again when the layout was saved by someone else in the meantime.
+func (e *Editor) edit(ctx context.Context, householdId string, listId string, change func(models.GroceryLayout, []models.LayoutBlock) ([]models.LayoutBlock, error)) error {
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		layout, err := e.Layouts.GetLayout(ctx, householdId, listId)
+		if err != nil {
+			return err
+		}
+
+		groceryItems, err := e.Groceries.GetGroceryItems(ctx, householdId)
+		if err != nil {
+			return err
+		}
+
+		blocks := layout.Arrange(models.ItemsInList(groceryItems, listId))
+		changed, err := change(layout, slices.Clone(blocks))
+		if err != nil {
+			return err
+		}
+
+		if slices.Equal(changed, layout.Blocks) {
+			return nil
+		}
+
+		layout.Blocks = changed
+		_, err = e.Layouts.SaveLayout(ctx, layout)
+		if errors.Is(err, proxy.ErrConditionFailed) {
+			continue
+		}
+
+		return err
+	}
+
+	return fmt.Errorf("layout of list [%s] kept changing: %w", listId, proxy.ErrConditionFailed)
+}
+
+func indexOf(blocks []models.LayoutBlock, key string) int {
+	return slices.IndexFunc(blocks, func(block models.LayoutBlock) bool { return block.Key() == key })
+}
+
+func remove(blocks []models.LayoutBlock, key string) []models.LayoutBlock {
+	return slices.DeleteFunc(blocks, func(block models.LayoutBlock) bool { return block.Key() == key })
+}
+
+// insertAfter inserts block after the block with key afterId, or at the top when afterId is empty
+func insertAfter(blocks []models.LayoutBlock, block models.LayoutBlock, afterId string) ([]models.LayoutBlock, error) {
+	if afterId == "" {
+		return slices.Insert(blocks, 0, block), nil
+	}
+
+	index := indexOf(blocks, afterId)
+	if index == -1 {
+		return nil, ErrBlockNotFound
+	}
+
+	return slices.Insert(blocks, index+1, block), nil
+}
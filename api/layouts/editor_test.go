@@ -0,0 +1,239 @@
+package layouts
+
+import (
+	"api/models"
+	"api/providers"
+	"api/proxy"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// newTestEditor makes an editor for list-1 of h1 holding items a, b and c laid out as
+// "Dairy", a, "Veg", b, c, and d on another list
+func newTestEditor(t *testing.T) *Editor {
+	t.Helper()
+	ctx := context.Background()
+
+	editor := &Editor{
+		Groceries: providers.NewMemoryGroceryRepository(),
+		Layouts:   providers.NewMemoryLayoutRepository(),
+	}
+
+	for _, groceryItem := range []models.GroceryItem{
+		{HouseholdId: "h1", ListId: "list-1", Id: "a", Name: "milk"},
+		{HouseholdId: "h1", ListId: "list-1", Id: "b", Name: "carrots"},
+		{HouseholdId: "h1", ListId: "list-1", Id: "c", Name: "leeks"},
+		{HouseholdId: "h1", ListId: "list-2", Id: "d", Name: "nails"},
+	} {
+		if _, err := editor.Groceries.CreateGroceryItem(ctx, groceryItem); err != nil {
+			t.Fatalf("CreateGroceryItem() error = %v", err)
+		}
+	}
+
+	_, err := editor.Replace(ctx, "h1", "list-1", []models.LayoutBlock{
+		{Id: "dairy", Type: models.Text, Value: "Dairy"},
+		{Type: models.GroceryItemId, Value: "a"},
+		{Id: "veg", Type: models.Text, Value: "Veg"},
+		{Type: models.GroceryItemId, Value: "b"},
+		{Type: models.GroceryItemId, Value: "c"},
+	})
+	if err != nil {
+		t.Fatalf("Replace() error = %v", err)
+	}
+
+	return editor
+}
+
+// keys are the keys of the blocks of list-1 as it is shown, with the text of headings
+func keys(t *testing.T, editor *Editor) []string {
+	t.Helper()
+	ctx := context.Background()
+
+	layout, err := editor.Layouts.GetLayout(ctx, "h1", "list-1")
+	if err != nil {
+		t.Fatalf("GetLayout() error = %v", err)
+	}
+	groceryItems, err := editor.Groceries.GetGroceryItems(ctx, "h1")
+	if err != nil {
+		t.Fatalf("GetGroceryItems() error = %v", err)
+	}
+
+	var keys []string
+	for _, block := range layout.Arrange(models.ItemsInList(groceryItems, "list-1")) {
+		if block.Type == models.Text {
+			keys = append(keys, "#"+block.Value)
+		} else {
+			keys = append(keys, block.Value)
+		}
+	}
+
+	return keys
+}
+
+func TestEditor(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(ctx context.Context, editor *Editor) error
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "move item to the top",
+			edit: func(ctx context.Context, editor *Editor) error {
+				return editor.MoveItem(ctx, "h1", "list-1", "c", "")
+			},
+			want: []string{"c", "#Dairy", "a", "#Veg", "b"},
+		},
+		{
+			name: "move item under a heading",
+			edit: func(ctx context.Context, editor *Editor) error {
+				return editor.MoveItem(ctx, "h1", "list-1", "c", "dairy")
+			},
+			want: []string{"#Dairy", "c", "a", "#Veg", "b"},
+		},
+		{
+			name: "move item after a missing block",
+			edit: func(ctx context.Context, editor *Editor) error {
+				return editor.MoveItem(ctx, "h1", "list-1", "c", "nope")
+			},
+			want:    []string{"#Dairy", "a", "#Veg", "b", "c"},
+			wantErr: ErrBlockNotFound,
+		},
+		{
+			name: "move item of another list",
+			edit: func(ctx context.Context, editor *Editor) error {
+				return editor.MoveItem(ctx, "h1", "list-1", "d", "")
+			},
+			want:    []string{"#Dairy", "a", "#Veg", "b", "c"},
+			wantErr: proxy.ErrNotFound,
+		},
+		{
+			name: "place item at the end of its section",
+			edit: func(ctx context.Context, editor *Editor) error {
+				if err := moveToList(ctx, editor, "d", "list-1"); err != nil {
+					return err
+				}
+				return editor.PlaceItem(ctx, "h1", "list-1", "d", "dairy")
+			},
+			want: []string{"#Dairy", "a", "d", "#Veg", "b", "c"},
+		},
+		{
+			name: "place item without a section",
+			edit: func(ctx context.Context, editor *Editor) error {
+				if err := moveToList(ctx, editor, "d", "list-1"); err != nil {
+					return err
+				}
+				return editor.PlaceItem(ctx, "h1", "list-1", "d", "Bakery")
+			},
+			want: []string{"#Dairy", "a", "#Veg", "b", "c", "d"},
+		},
+		{
+			name: "place item that has a place",
+			edit: func(ctx context.Context, editor *Editor) error {
+				return editor.PlaceItem(ctx, "h1", "list-1", "b", "dairy")
+			},
+			want: []string{"#Dairy", "a", "#Veg", "b", "c"},
+		},
+		{
+			name: "remove item",
+			edit: func(ctx context.Context, editor *Editor) error {
+				return editor.RemoveItem(ctx, "h1", "list-1", "a")
+			},
+			// The item is still on the list, so it is shown at the end until it leaves
+			want: []string{"#Dairy", "#Veg", "b", "c", "a"},
+		},
+		{
+			name: "insert heading",
+			edit: func(ctx context.Context, editor *Editor) error {
+				_, err := editor.InsertHeading(ctx, "h1", "list-1", "Leeks", "b")
+				return err
+			},
+			want: []string{"#Dairy", "a", "#Veg", "b", "#Leeks", "c"},
+		},
+		{
+			name: "rename heading",
+			edit: func(ctx context.Context, editor *Editor) error {
+				return editor.RenameHeading(ctx, "h1", "list-1", "veg", "Vegetables")
+			},
+			want: []string{"#Dairy", "a", "#Vegetables", "b", "c"},
+		},
+		{
+			name: "rename an item",
+			edit: func(ctx context.Context, editor *Editor) error {
+				return editor.RenameHeading(ctx, "h1", "list-1", "a", "Milk")
+			},
+			want:    []string{"#Dairy", "a", "#Veg", "b", "c"},
+			wantErr: ErrBlockNotFound,
+		},
+		{
+			name: "delete heading",
+			edit: func(ctx context.Context, editor *Editor) error {
+				return editor.DeleteHeading(ctx, "h1", "list-1", "veg")
+			},
+			want: []string{"#Dairy", "a", "b", "c"},
+		},
+		{
+			name: "replace leaving items out",
+			edit: func(ctx context.Context, editor *Editor) error {
+				_, err := editor.Replace(ctx, "h1", "list-1", []models.LayoutBlock{
+					{Type: models.GroceryItemId, Value: "b"},
+					{Type: models.Text, Value: "Dairy"},
+				})
+				return err
+			},
+			want: []string{"b", "#Dairy", "a", "c"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			editor := newTestEditor(t)
+
+			err := test.edit(ctx, editor)
+			if test.wantErr == nil && err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if test.wantErr != nil && !errors.Is(err, test.wantErr) {
+				t.Fatalf("error = %v, want %v", err, test.wantErr)
+			}
+
+			if got := keys(t, editor); !slices.Equal(got, test.want) {
+				t.Errorf("layout = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestEditorReplaceKeepsHeadingIds(t *testing.T) {
+	ctx := context.Background()
+	editor := newTestEditor(t)
+
+	blocks, err := editor.Replace(ctx, "h1", "list-1", []models.LayoutBlock{
+		{Type: models.Text, Value: "Veg"},
+		{Type: models.Text, Value: "Veg"},
+	})
+	if err != nil {
+		t.Fatalf("Replace() error = %v", err)
+	}
+
+	if blocks[0].Id != "veg" {
+		t.Errorf("first heading id = %q, want the id of the existing heading", blocks[0].Id)
+	}
+	if blocks[1].Id == "" || blocks[1].Id == "veg" {
+		t.Errorf("repeated heading id = %q, want an id of its own", blocks[1].Id)
+	}
+}
+
+func moveToList(ctx context.Context, editor *Editor, itemId string, listId string) error {
+	groceryItem, err := editor.Groceries.GetGroceryItem(ctx, "h1", itemId)
+	if err != nil {
+		return err
+	}
+
+	groceryItem.ListId = listId
+	_, err = editor.Groceries.UpdateGroceryItem(ctx, groceryItem)
+	return err
+}
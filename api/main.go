@@ -1,15 +1,19 @@
 package main
 
 import (
+	"api/auth"
+	"api/config"
 	"api/metrics"
-	"api/models"
-	receiptprocessor "api/receipt-processor"
+	ddbproxy "api/proxy/ddb"
+	s3proxy "api/proxy/s3"
 	"api/routes"
 	"api/utils"
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
-	"strings"
+	"os"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -18,42 +22,125 @@ import (
 
 var router *gin.Engine
 
-func finit() {
-	router = gin.Default()
+// newRouter builds the gin router with every route bound to the given Api
+func newRouter(api *routes.Api) *gin.Engine {
+	router := gin.Default()
 
 	router.Use(CORSMiddleware())
 	router.Use(LoggingMiddleware())
 
+	// Everything but presigned blob uploads requires a bearer token
+	authorized := router.Group("/", api.Authenticate)
+
 	// Groceries
-	router.GET("/groceries/:householdId", routes.GetGroceries)
-	router.PUT("/groceries", routes.CreateGroceryItem)
-	router.POST("/groceries", routes.UpdateGroceryItem)
-	router.DELETE("/groceries/:householdId/:id", routes.DeleteGroceryItem)
-	router.POST("/groceries/batchDelete", routes.BatchDeleteGroceryItems)
-	router.POST("/groceries/magic", routes.GroceryMagic)
+	authorized.GET("/groceries/:householdId", api.GetGroceries)
+	authorized.GET("/groceries/:householdId/changes", api.GetGroceryChanges)
+	authorized.PUT("/groceries", api.CreateGroceryItem)
+	authorized.POST("/groceries", api.UpdateGroceryItem)
+	authorized.DELETE("/groceries/:householdId/:id", api.DeleteGroceryItem)
+	authorized.POST("/groceries/batchDelete", api.BatchDeleteGroceryItems)
+	authorized.POST("/groceries/magic", api.GroceryMagic)
+	authorized.POST("/groceries/:householdId/ops", api.ApplyGroceryOps)
+	authorized.PUT("/groceries/:householdId/layout/items/:id", api.MoveLayoutItem)
+	authorized.POST("/groceries/:householdId/layout/headings", api.InsertLayoutHeading)
+	authorized.PATCH("/groceries/:householdId/layout/headings/:headingId", api.RenameLayoutHeading)
+	authorized.DELETE("/groceries/:householdId/layout/headings/:headingId", api.DeleteLayoutHeading)
 
 	// Households
-	router.PUT("/households", routes.CreateHousehold)
-	router.POST("/households/join/:householdId/:userId", routes.JoinHousehold)
-	router.POST("/households/leave/:householdId/:userId", routes.LeaveHousehold)
+	authorized.PUT("/households", api.CreateHousehold)
+	authorized.POST("/households/join", api.JoinHousehold)
+	authorized.POST("/households/leave/:householdId/:userId", api.LeaveHousehold)
+	authorized.GET("/households/:householdId", api.GetHousehold)
+	authorized.PATCH("/households/:householdId", api.RenameHousehold)
+	authorized.DELETE("/households/:householdId", api.DeleteHousehold)
+	authorized.POST("/households/:householdId/transfer", api.TransferHousehold)
+	authorized.GET("/households/:householdId/members", api.GetHouseholdMembers)
+	authorized.GET("/households/:householdId/events", api.GetHouseholdEvents)
+	authorized.DELETE("/households/:householdId/members/:userId", api.RemoveHouseholdMember)
+	authorized.PUT("/households/:householdId/members/:userId/role", api.SetHouseholdMemberRole)
+	authorized.POST("/households/:householdId/invites", api.CreateHouseholdInvite)
+	authorized.GET("/households/:householdId/invites", api.GetHouseholdInvites)
+	authorized.DELETE("/households/:householdId/invites/:code", api.RevokeHouseholdInvite)
+	authorized.GET("/households/:householdId/lists", api.GetHouseholdLists)
+	authorized.POST("/households/:householdId/lists", api.CreateHouseholdList)
+	authorized.PATCH("/households/:householdId/lists/:listId", api.UpdateHouseholdList)
+	authorized.DELETE("/households/:householdId/lists/:listId", api.DeleteHouseholdList)
+	authorized.POST("/households/:householdId/lists/:listId/items/move", api.MoveGroceryItems)
+	authorized.POST("/households/:householdId/lists/:listId/items/copy", api.CopyGroceryItems)
+	authorized.GET("/households/:householdId/staples", api.GetStaples)
+	authorized.POST("/households/:householdId/staples", api.CreateStaple)
+	authorized.PATCH("/households/:householdId/staples/:stapleId", api.UpdateStaple)
+	authorized.DELETE("/households/:householdId/staples/:stapleId", api.DeleteStaple)
 
 	// Users
-	router.PUT("/users", routes.CreateUser)
-	router.GET("/users/:id", routes.GetUser)
+	authorized.PUT("/users", api.CreateUser)
+	authorized.GET("/users/:id", api.GetUser)
+	authorized.DELETE("/users/:id", api.DeleteUser)
+	authorized.GET("/users/:id/export", api.ExportUser)
+	authorized.GET("/users/:id/households", api.GetUserHouseholds)
+	authorized.GET("/users/:id/devices", api.GetUserDevices)
+	authorized.DELETE("/users/:id/devices/:deviceId", api.RevokeDevice)
+	authorized.POST("/users/:id/pairing-codes", api.CreatePairingCode)
+	authorized.POST("/devices/pair", api.PairDevice)
+	authorized.PUT("/users/:id/households/default", api.SetDefaultHousehold)
 
 	// Catalog
-	router.GET("/catalog", routes.GetCatalog)
+	authorized.GET("/catalog", api.GetCatalog)
 
 	router.MaxMultipartMemory = 8 << 20 // 8 MiB
-	router.POST("/receipt/upload", routes.UploadReceipt)
+	authorized.POST("/receipt/upload", api.UploadReceipt)
+
+	// Presigned urls are only served by the api when blobs are stored locally
+	if _, isLocal := api.Blobs.(*s3proxy.DirectoryBlobStore); isLocal {
+		router.PUT("/blobs/:bucket/*key", api.UploadBlob)
+		router.GET("/blobs/:bucket/*key", api.DownloadBlob)
+	}
+
+	return router
+}
+
+// newApi picks the storage backing the routes, set API_STORAGE=memory to run
+// without AWS credentials. Blobs are then kept under API_BLOB_DIR.
+func newApi(ctx context.Context, cfg *config.Config) (*routes.Api, error) {
+	var keys *auth.KeySet
+	var err error
+	if cfg.Auth.JwksUrl != "" {
+		keys, err = auth.FetchKeySet(ctx, cfg.Auth.JwksUrl)
+	} else {
+		keys, err = auth.LoadKeySet(cfg.Auth.JwksFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	tokens := auth.NewVerifier(keys, cfg.Auth.Issuer, cfg.Auth.Audience)
+
+	if cfg.Storage == config.MemoryStorage {
+		blobs, err := s3proxy.NewDirectoryBlobStore(cfg.BlobDir, cfg.BaseUrl)
+		if err != nil {
+			return nil, err
+		}
+
+		return routes.NewMemoryApi(cfg, blobs, tokens), nil
+	}
+
+	if err := ddbproxy.Configure(ctx, cfg.Region); err != nil {
+		return nil, err
+	}
+
+	blobs, err := s3proxy.NewS3BlobStore(ctx, cfg.Region)
+	if err != nil {
+		return nil, err
+	}
+
+	return routes.NewDynamoApi(cfg, blobs, tokens), nil
 }
 
 func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, Last-Event-ID")
+		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -110,34 +197,65 @@ func LoggingMiddleware() gin.HandlerFunc {
 	}
 }
 
-func Handler(req events.APIGatewayV2HTTPRequest) (events.APIGatewayProxyResponse, error) {
-	// Adapt the API Gateway request to a GIN request
-	httpRequest, err := http.NewRequest(strings.ToUpper(req.RequestContext.HTTP.Method), req.RawPath, strings.NewReader(req.Body))
-	if err != nil {
-		return events.APIGatewayProxyResponse{StatusCode: http.StatusInternalServerError}, err
+// lambdaDeadlineMargin is kept back from the Lambda deadline so a timed out request
+// still has time to write its error response
+const lambdaDeadlineMargin = 500 * time.Millisecond
+
+// lambdaJobEvent is the input of scheduled invocations, see scheduledJobs
+type lambdaJobEvent struct {
+	Job string `json:"job"`
+}
+
+// newLambdaHandler serves API Gateway requests and runs the scheduled job named by a lambdaJobEvent
+func newLambdaHandler(api *routes.Api) func(context.Context, json.RawMessage) (interface{}, error) {
+	return func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		var jobEvent lambdaJobEvent
+		if err := json.Unmarshal(payload, &jobEvent); err == nil && jobEvent.Job != "" {
+			job, ok := scheduledJobs[jobEvent.Job]
+			if !ok {
+				return nil, fmt.Errorf("unknown job %q", jobEvent.Job)
+			}
+
+			return nil, job(ctx, api)
+		}
+
+		var req events.APIGatewayV2HTTPRequest
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return nil, fmt.Errorf("unable to parse invocation, %v", err)
+		}
+
+		return Handler(ctx, req)
+	}
+}
+
+func Handler(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	// Cancel in-flight work shortly before Lambda would kill the invocation
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline.Add(-lambdaDeadlineMargin))
+		defer cancel()
 	}
 
-	for key, value := range req.Headers {
-		httpRequest.Header.Set(key, value)
+	// Adapt the API Gateway request to a GIN request
+	httpRequest, err := utils.NewHTTPRequest(ctx, req)
+	if err != nil {
+		log.Printf("unable to adapt request, %v", err)
+		return events.APIGatewayV2HTTPResponse{StatusCode: http.StatusBadRequest}, nil
 	}
 
-	w := &utils.ResponseWriter{}
+	w := utils.NewResponseWriter()
 	router.ServeHTTP(w, httpRequest)
 
-	return events.APIGatewayProxyResponse{
-		StatusCode: w.StatusCode,
-		Headers:    w.Headers,
-		Body:       w.Body,
-	}, nil
+	return w.APIGatewayResponse(), nil
 }
 
 func main() {
-	// _, isLambda := os.LookupEnv("LAMBDA_TASK_ROOT")
-
-	// if isLambda {
-	// 	lambda.Start(Handler)
-	// } else {
-	// 	router.Run(":8080")
-	// }
-	receiptprocessor.ConvertAllReceiptsIntoText(models.BusinessReceipt)
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("unable to load config, %v", err)
+	}
+
+	if err := run(os.Args[1:], cfg); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,33 @@
+package models
+
+// Device is a client signed in as a user, identified by the subject of its bearer tokens.
+// Every device starts out as a user of its own until it is linked to another user with a PairingCode.
+type Device struct {
+	Id     string `json:"id" dynamodbav:"id"`
+	UserId string `json:"userId" dynamodbav:"userId"`
+	// Name is what the device is listed as, the User-Agent it was first seen with unless named when linked
+	Name string `json:"name" dynamodbav:"name"`
+	// LinkedAt is in unix seconds, when the device started acting as the user
+	LinkedAt int64 `json:"linkedAt" dynamodbav:"linkedAt"`
+	// RevokedAt is in unix seconds, once set the device's tokens are refused
+	RevokedAt int64 `json:"-" dynamodbav:"revokedAt,omitempty"`
+	// Current is set on the device making the request
+	Current bool `json:"current" dynamodbav:"-"`
+}
+
+// PairingCode lets another device become the user who created it, it can be redeemed once
+type PairingCode struct {
+	Code   string `json:"code" dynamodbav:"code"`
+	UserId string `json:"userId" dynamodbav:"userId"`
+	// CreatedBy is the id of the device that asked for the code
+	CreatedBy string `json:"createdBy" dynamodbav:"createdBy"`
+	// CreatedAt and ExpiresAt are unix seconds, ExpiresAt doubles as the table's TTL attribute
+	CreatedAt int64 `json:"createdAt" dynamodbav:"createdAt"`
+	ExpiresAt int64 `json:"expiresAt" dynamodbav:"expiresAt"`
+}
+
+type PairDeviceRequest struct {
+	Code string `json:"code" binding:"required"`
+	// Name overrides the name the device is listed as
+	Name string `json:"name"`
+}
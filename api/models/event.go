@@ -0,0 +1,37 @@
+package models
+
+type HouseholdEventType string
+
+const (
+	ItemCreated   HouseholdEventType = "item.created"
+	ItemUpdated   HouseholdEventType = "item.updated"
+	ItemChecked   HouseholdEventType = "item.checked"
+	ItemUnchecked HouseholdEventType = "item.unchecked"
+	// ItemDeleted events only carry the householdId and id of the item
+	ItemDeleted HouseholdEventType = "item.deleted"
+	ListCreated HouseholdEventType = "list.created"
+	ListUpdated HouseholdEventType = "list.updated"
+	// ListDeleted events only carry the householdId and id of the list, the deletion of its items
+	// comes as events of their own
+	ListDeleted HouseholdEventType = "list.deleted"
+	// EventsReset tells a subscriber that events were missed, it should fetch the whole list again
+	EventsReset HouseholdEventType = "reset"
+)
+
+// HouseholdEvent is a change to a household, as delivered by GET /households/:householdId/events
+type HouseholdEvent struct {
+	// Id is assigned by the bus, a subscriber passes the last one it saw to resume where it left off
+	Id          string             `json:"id"`
+	HouseholdId string             `json:"householdId"`
+	Type        HouseholdEventType `json:"type"`
+	Item        *GroceryItem       `json:"item,omitempty"`
+	List        *HouseholdList     `json:"list,omitempty"`
+	// At is in unix milliseconds
+	At int64 `json:"at"`
+}
+
+type HouseholdEventsResponse struct {
+	Events []HouseholdEvent `json:"events"`
+	// LastEventId is passed as the since query parameter of the next request
+	LastEventId string `json:"lastEventId"`
+}
@@ -0,0 +1,61 @@
+package models
+
+type GroceryOpType string
+
+const (
+	AddOp     GroceryOpType = "add"
+	RenameOp  GroceryOpType = "rename"
+	CheckOp   GroceryOpType = "check"
+	UncheckOp GroceryOpType = "uncheck"
+	DeleteOp  GroceryOpType = "delete"
+	ReorderOp GroceryOpType = "reorder"
+)
+
+// GroceryOp is a change a client made to a grocery item, possibly while offline
+type GroceryOp struct {
+	// Id is generated by the client, an operation with the same id is only applied once
+	Id   string        `json:"id" binding:"required"`
+	Type GroceryOpType `json:"type" binding:"required,oneof=add rename check uncheck delete reorder"`
+	// ItemId is the item the operation applies to, for add it is the id the client gave the new item
+	ItemId string `json:"itemId" binding:"required"`
+	// Name is the name of an added or renamed item
+	Name string `json:"name"`
+	// AfterId is the key of the layout block to place the item after. When empty added items go at
+	// the end of the list and reordered ones at the top.
+	AfterId string `json:"afterId"`
+	// BaseVersion is the version of the item the client changed, 0 for add
+	BaseVersion int `json:"baseVersion"`
+	// ClientTimestamp is when the client made the change, in unix milliseconds
+	ClientTimestamp int64 `json:"clientTimestamp"`
+}
+
+type GroceryOpStatus string
+
+const (
+	// OpApplied means the operation took effect, or had already been
+	OpApplied GroceryOpStatus = "applied"
+	// OpSuperseded means someone changed the item after the operation was made and their change won
+	OpSuperseded GroceryOpStatus = "superseded"
+	// OpRejected means the operation can't be applied, e.g. because the item has been deleted
+	OpRejected GroceryOpStatus = "rejected"
+)
+
+// GroceryOpResult is how an operation went, it is remembered for a while so that repeating the
+// operation gets the same result
+type GroceryOpResult struct {
+	HouseholdId string          `json:"-" dynamodbav:"householdId"`
+	OpId        string          `json:"opId" dynamodbav:"opId"`
+	Status      GroceryOpStatus `json:"status" dynamodbav:"status"`
+	Reason      string          `json:"reason,omitempty" dynamodbav:"reason,omitempty"`
+	ExpiresAt   int64           `json:"-" dynamodbav:"expiresAt"`
+}
+
+type GroceryOpsRequest struct {
+	Ops []GroceryOp `json:"ops" binding:"required,max=100,dive"`
+}
+
+type GroceryOpsResponse struct {
+	// Results holds the result of every operation, in the order of the request
+	Results     []GroceryOpResult `json:"results"`
+	GroceryList GroceryList       `json:"groceryList"`
+}
@@ -1,17 +1,64 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type BatchDeleteGroceryItemsRequest struct {
 	ItemsToDelete []GroceryItem `json:"itemsToDelete" dynamodbav:"itemsToDelete"`
 }
 
+type BatchDeleteGroceryItemsResponse struct {
+	Deleted    []GroceryItem `json:"deleted"`
+	NotDeleted []GroceryItem `json:"notDeleted"`
+}
+
 type GroceryItem struct {
 	HouseholdId   string          `json:"householdId" dynamodbav:"householdId"`
 	Id            string          `json:"id" dynamodbav:"id"`
 	Name          string          `json:"name" dynamodbav:"name"`
 	StoreOverride StorePreference `json:"storeOverride" dynamodbav:"storeOverride"`
 	Checked       bool            `json:"checked" dynamodbav:"checked"`
+	// ListId is the list of the household the item is on, see DefaultListId
+	ListId string `json:"listId" dynamodbav:"listId,omitempty"`
+	// Quantity and Unit are how much of the item is needed, e.g. 2 and "kilogram". Unit is empty
+	// for a number of things and Quantity is 0 when no amount was given.
+	Quantity float64 `json:"quantity" dynamodbav:"quantity"`
+	Unit     string  `json:"unit" dynamodbav:"unit"`
+	// Text is the line the item was created from when its quantity was read off it, the name is
+	// what is left of it
+	Text string `json:"text,omitempty" dynamodbav:"text,omitempty"`
+	// Notes are free text for whoever buys the item, e.g. "the lactose free one"
+	Notes string `json:"notes" dynamodbav:"notes"`
+	// AddedBy is the id of the user that added the item, empty when a staple added it. AssignedTo
+	// is the id of the member that is to buy it, empty when anyone can.
+	AddedBy    string `json:"addedBy" dynamodbav:"addedBy,omitempty"`
+	AssignedTo string `json:"assignedTo" dynamodbav:"assignedTo"`
+	// CreatedAt and CheckedAt are in unix milliseconds, CheckedAt is 0 while the item is unchecked.
+	// Items created before they were recorded have neither.
+	CreatedAt int64 `json:"createdAt" dynamodbav:"createdAt,omitempty"`
+	CheckedAt int64 `json:"checkedAt" dynamodbav:"checkedAt"`
+	// Version is bumped on every update, an update must carry the version it was based on
+	Version int `json:"version" dynamodbav:"version"`
+	// UpdatedAt is when the item was last created, updated or deleted, in unix milliseconds
+	UpdatedAt int64 `json:"updatedAt" dynamodbav:"updatedAt,omitempty"`
+	// Deleted marks a tombstone, which stands in for a deleted item until ExpiresAt so that
+	// clients syncing changes learn about the deletion
+	Deleted   bool  `json:"deleted,omitempty" dynamodbav:"deleted,omitempty"`
+	ExpiresAt int64 `json:"-" dynamodbav:"expiresAt,omitempty"`
+}
+
+// GroceryChanges are the items of a household that changed since a change token,
+// including tombstones of the deleted ones
+type GroceryChanges struct {
+	Items []GroceryItem `json:"items"`
+	// Token is passed back as the since query parameter to get the changes after these
+	Token string `json:"token"`
+	// Reset is set when Items is the whole list rather than the changes, the client replaces
+	// its copy of the list with it
+	Reset bool `json:"reset,omitempty"`
 }
 
 type LayoutBlockType string
@@ -21,15 +68,147 @@ const (
 	GroceryItemId LayoutBlockType = "GroceryItemId"
 )
 
+// LayoutBlock is either a grocery item, whose id is the value, or a heading with the value as its text
 type LayoutBlock struct {
+	// Id identifies Text blocks, item blocks are identified by their value
+	Id    string          `json:"id,omitempty" dynamodbav:"id,omitempty"`
 	Value string          `json:"value" dynamodbav:"value"`
 	Type  LayoutBlockType `json:"type" dynamodbav:"type"`
 }
 
+// Key identifies the block within a layout
+func (b LayoutBlock) Key() string {
+	if b.Type == GroceryItemId {
+		return b.Value
+	}
+
+	return b.Id
+}
+
+type MoveLayoutItemRequest struct {
+	// AfterId is the key of the block to move the item after, empty to move it to the top
+	AfterId string `json:"afterId"`
+}
+
+type LayoutHeadingRequest struct {
+	Text string `json:"text" binding:"required,max=100"`
+	// AfterId is the key of the block to insert a new heading after, empty for the top
+	AfterId string `json:"afterId"`
+}
+
+// GroceryLayout is the arrangement of one of a household's lists that its members chose
+type GroceryLayout struct {
+	HouseholdId string        `json:"householdId" dynamodbav:"householdId"`
+	ListId      string        `json:"listId" dynamodbav:"listId"`
+	Blocks      []LayoutBlock `json:"blocks" dynamodbav:"blocks"`
+	// Version is bumped on every save, a save must carry the version it was based on
+	Version int `json:"version" dynamodbav:"version"`
+}
+
+// Arrange lays out items following the layout. Blocks of items that are gone are dropped,
+// items that haven't been placed yet go at the end in the order they are given.
+func (l GroceryLayout) Arrange(items []GroceryItem) []LayoutBlock {
+	unplaced := make(map[string]bool, len(items))
+	for _, item := range items {
+		unplaced[item.Id] = true
+	}
+
+	blocks := make([]LayoutBlock, 0, len(l.Blocks)+len(items))
+	for _, block := range l.Blocks {
+		if block.Type == GroceryItemId {
+			if !unplaced[block.Value] {
+				continue
+			}
+			unplaced[block.Value] = false
+		}
+
+		blocks = append(blocks, block)
+	}
+
+	for _, item := range items {
+		if unplaced[item.Id] {
+			blocks = append(blocks, LayoutBlock{Type: GroceryItemId, Value: item.Id})
+		}
+	}
+
+	return blocks
+}
+
 type GroceryList struct {
-	Name   string
+	ListId string        `json:"listId,omitempty" dynamodbav:"-"`
+	Name   string        `json:"name,omitempty" dynamodbav:"-"`
 	Items  []GroceryItem `json:"items" dynamodbav:"items"`
 	Layout []LayoutBlock `json:"layout" dynamodbav:"layout"`
+	// Cursor is set when the list was fetched a page at a time and more items remain,
+	// pass it back as the cursor query parameter to get the next page
+	Cursor string `json:"cursor,omitempty" dynamodbav:"-"`
+}
+
+// AssignedTo narrows the list down to the items assigned to the user, dropping the blocks of the
+// other items from the layout
+func (l GroceryList) AssignedTo(userId string) GroceryList {
+	l.Items = ItemsAssignedTo(l.Items, userId)
+
+	kept := make(map[string]bool, len(l.Items))
+	for _, item := range l.Items {
+		kept[item.Id] = true
+	}
+
+	layout := make([]LayoutBlock, 0, len(l.Layout))
+	for _, block := range l.Layout {
+		if block.Type != GroceryItemId || kept[block.Value] {
+			layout = append(layout, block)
+		}
+	}
+	l.Layout = layout
+
+	return l
+}
+
+// ItemsAssignedTo returns the items assigned to the user
+func ItemsAssignedTo(items []GroceryItem, userId string) []GroceryItem {
+	assigned := make([]GroceryItem, 0, len(items))
+	for _, item := range items {
+		if item.AssignedTo == userId {
+			assigned = append(assigned, item)
+		}
+	}
+
+	return assigned
+}
+
+// SetQuantity takes the amount and unit that were read off the item's name, leaving the rest of
+// the name. Items that were given a quantity of their own are left alone.
+func (item *GroceryItem) SetQuantity(quantity float64, unit string, name string) {
+	if item.Quantity != 0 || item.Unit != "" {
+		return
+	}
+
+	item.Text = item.Name
+	item.Quantity = quantity
+	item.Unit = unit
+	item.Name = name
+}
+
+// InList reports whether the item is on the list
+func (item GroceryItem) InList(listId string) bool {
+	if item.ListId == "" {
+		return listId == DefaultListId
+	}
+
+	return item.ListId == listId
+}
+
+// ItemsInList returns the items that are on the list, in the order they are given
+func ItemsInList(items []GroceryItem, listId string) []GroceryItem {
+	inList := make([]GroceryItem, 0, len(items))
+	for _, item := range items {
+		if item.InList(listId) {
+			inList = append(inList, item)
+		}
+	}
+
+	return inList
 }
 
 // Function to generate UUID for ID field
@@ -44,3 +223,19 @@ func (item *GroceryItem) GetOrGenerateID() string {
 
 	return item.Id
 }
+
+// Replaces prepares an update of the stored item. The fields only the server sets are carried over
+// from it, and CheckedAt is stamped when the update checks the item.
+func (item *GroceryItem) Replaces(stored GroceryItem, now time.Time) {
+	item.AddedBy = stored.AddedBy
+	item.CreatedAt = stored.CreatedAt
+
+	switch {
+	case !item.Checked:
+		item.CheckedAt = 0
+	case stored.Checked:
+		item.CheckedAt = stored.CheckedAt
+	default:
+		item.CheckedAt = now.UnixMilli()
+	}
+}
@@ -1,5 +1,52 @@
 package models
 
 type Household struct {
-	Id string `json:"id" dynamodbav:"id"`
+	Id   string `json:"id" dynamodbav:"id"`
+	Name string `json:"name" dynamodbav:"name"`
+	// CreatedAt is in unix seconds
+	CreatedAt int64 `json:"createdAt" dynamodbav:"createdAt"`
+	// OwnerId is the user that owns the household, empty for households created before owners existed
+	OwnerId string `json:"ownerId" dynamodbav:"ownerId"`
+	// DeletionStatus is only set once the household is being deleted, see jobs.HouseholdDeletionJob
+	DeletionStatus DeletionStatus `json:"-" dynamodbav:"deletionStatus,omitempty"`
+	// DeletedAt is in unix seconds, when deletion was requested
+	DeletedAt int64 `json:"-" dynamodbav:"deletedAt,omitempty"`
+}
+
+type DeletionStatus string
+
+const DeletionPending DeletionStatus = "pending"
+
+type HouseholdRole string
+
+const (
+	// OwnerRole is never stored on a member, the owner is whoever Household.OwnerId names
+	OwnerRole  HouseholdRole = "owner"
+	AdminRole  HouseholdRole = "admin"
+	MemberRole HouseholdRole = "member"
+)
+
+// HouseholdMember is an entry in the members index of a household
+type HouseholdMember struct {
+	HouseholdId string        `json:"householdId" dynamodbav:"householdId"`
+	UserId      string        `json:"userId" dynamodbav:"userId"`
+	Role        HouseholdRole `json:"role" dynamodbav:"role"`
+	// JoinedAt is in unix seconds
+	JoinedAt int64 `json:"joinedAt" dynamodbav:"joinedAt"`
+}
+
+type CreateHouseholdRequest struct {
+	Name string `json:"name"`
+}
+
+type RenameHouseholdRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type SetHouseholdMemberRoleRequest struct {
+	Role HouseholdRole `json:"role" binding:"required"`
+}
+
+type TransferHouseholdRequest struct {
+	UserId string `json:"userId" binding:"required"`
 }
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// HouseholdInvite lets whoever holds Code join the household until it expires or is used up
+type HouseholdInvite struct {
+	Code        string `json:"code" dynamodbav:"code"`
+	HouseholdId string `json:"householdId" dynamodbav:"householdId"`
+	CreatedBy   string `json:"createdBy" dynamodbav:"createdBy"`
+	// CreatedAt and ExpiresAt are unix seconds, ExpiresAt doubles as the table's TTL attribute
+	CreatedAt int64 `json:"createdAt" dynamodbav:"createdAt"`
+	ExpiresAt int64 `json:"expiresAt" dynamodbav:"expiresAt"`
+	MaxUses   int   `json:"maxUses" dynamodbav:"maxUses"`
+	Uses      int   `json:"uses" dynamodbav:"uses"`
+}
+
+// Outstanding reports whether the invite can still be redeemed
+func (i HouseholdInvite) Outstanding(now time.Time) bool {
+	return now.Unix() < i.ExpiresAt && i.Uses < i.MaxUses
+}
+
+type CreateHouseholdInviteRequest struct {
+	// ExpiresInMinutes defaults to a week
+	ExpiresInMinutes int `json:"expiresInMinutes"`
+	// MaxUses defaults to 1
+	MaxUses int `json:"maxUses"`
+}
+
+type JoinHouseholdRequest struct {
+	Code string `json:"code" binding:"required"`
+}
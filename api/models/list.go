@@ -0,0 +1,35 @@
+package models
+
+// DefaultListId is the list every household has. Items added before households could have several
+// lists have no list id and are on it.
+const DefaultListId = "default"
+
+const DefaultListName = "Groceries"
+
+// HouseholdList is one of the named lists of a household, e.g. "Weekly shop" or "Hardware store"
+type HouseholdList struct {
+	HouseholdId string `json:"householdId" dynamodbav:"householdId"`
+	Id          string `json:"id" dynamodbav:"id"`
+	Name        string `json:"name" dynamodbav:"name"`
+	// CreatedAt is in unix seconds, 0 for the default list
+	CreatedAt int64 `json:"createdAt" dynamodbav:"createdAt"`
+	// Archived lists keep their items but are left out of the household's lists unless asked for,
+	// and nothing can be added to them
+	Archived bool `json:"archived" dynamodbav:"archived"`
+}
+
+type CreateListRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// UpdateListRequest renames, archives or restores a list, fields that are left out stay as they are
+type UpdateListRequest struct {
+	Name     *string `json:"name"`
+	Archived *bool   `json:"archived"`
+}
+
+// TransferGroceryItemsRequest moves or copies items of a list to another list of the household
+type TransferGroceryItemsRequest struct {
+	ItemIds  []string `json:"itemIds" binding:"required,min=1,max=100"`
+	ToListId string   `json:"toListId" binding:"required"`
+}
@@ -12,3 +12,12 @@ const (
 	BusinessReceipt ReceiptContext = "Business"
 	RetailReceipt   ReceiptContext = "Retail"
 )
+
+// Receipt is an uploaded receipt as stored in one of the receipt buckets
+type Receipt struct {
+	Key string `json:"key"`
+	// Processed is set once the receipt was moved to the processed bucket
+	Processed bool `json:"processed"`
+	// HasText is set once the text on the receipt was read by the receipt processor
+	HasText bool `json:"hasText"`
+}
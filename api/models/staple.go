@@ -0,0 +1,114 @@
+package models
+
+import (
+	"fmt"
+	"hash/fnv"
+	"time"
+)
+
+// StapleRecurrence is how often a staple is added to its list
+type StapleRecurrence string
+
+const (
+	Weekly      StapleRecurrence = "weekly"
+	Fortnightly StapleRecurrence = "fortnightly"
+	// EveryNDays staples are added every Days days
+	EveryNDays StapleRecurrence = "days"
+)
+
+// StapleScheduleShards is how many partitions of the index the staples job finds due staples with
+// the staples are spread over, so that no single partition takes every write
+const StapleScheduleShards = 8
+
+// StapleScheduleId is the ScheduleId of the staples of a household, the partition key of that index.
+// A household's staples all land in the same shard.
+func StapleScheduleId(householdId string) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(householdId))
+
+	return stapleScheduleShard(hash.Sum32() % StapleScheduleShards)
+}
+
+// StapleScheduleIds returns the ScheduleId of every shard
+func StapleScheduleIds() []string {
+	scheduleIds := make([]string, StapleScheduleShards)
+	for shard := range scheduleIds {
+		scheduleIds[shard] = stapleScheduleShard(uint32(shard))
+	}
+
+	return scheduleIds
+}
+
+func stapleScheduleShard(shard uint32) string {
+	return fmt.Sprintf("staples-%d", shard)
+}
+
+// Staple is an item a household buys regularly, it is added to its list whenever it is due unless
+// the list already has it, see jobs.StapleJob
+type Staple struct {
+	HouseholdId string `json:"householdId" dynamodbav:"householdId"`
+	Id          string `json:"id" dynamodbav:"id"`
+	ListId      string `json:"listId" dynamodbav:"listId"`
+	// Name is what the item is added as, a quantity is split off it like it is for other items
+	Name       string           `json:"name" dynamodbav:"name"`
+	Recurrence StapleRecurrence `json:"recurrence" dynamodbav:"recurrence"`
+	// Days is the number of days between additions of EveryNDays staples
+	Days int `json:"days,omitempty" dynamodbav:"days,omitempty"`
+	// NextDueAt, LastAddedAt and CreatedAt are in unix seconds, LastAddedAt is 0 until the staple
+	// was first added
+	NextDueAt   int64  `json:"nextDueAt" dynamodbav:"nextDueAt"`
+	LastAddedAt int64  `json:"lastAddedAt" dynamodbav:"lastAddedAt"`
+	CreatedAt   int64  `json:"createdAt" dynamodbav:"createdAt"`
+	ScheduleId  string `json:"-" dynamodbav:"scheduleId"`
+	// Version is bumped on every update, staples start out at 0
+	Version int `json:"version" dynamodbav:"version"`
+}
+
+// Interval is the time between additions of the staple
+func (s Staple) Interval() time.Duration {
+	switch s.Recurrence {
+	case Weekly:
+		return 7 * 24 * time.Hour
+	case Fortnightly:
+		return 14 * 24 * time.Hour
+	default:
+		return time.Duration(s.Days) * 24 * time.Hour
+	}
+}
+
+// Due tells whether the staple is to be added at now
+func (s Staple) Due(now time.Time) bool {
+	return s.NextDueAt <= now.Unix()
+}
+
+// Advance moves NextDueAt past now by whole intervals, so a staple that was due several times while
+// the job didn't run is only added once
+func (s *Staple) Advance(now time.Time) {
+	interval := int64(s.Interval().Seconds())
+	if interval <= 0 {
+		return
+	}
+
+	if behind := now.Unix() - s.NextDueAt; behind >= 0 {
+		s.NextDueAt += (behind/interval + 1) * interval
+	}
+}
+
+// CreateStapleRequest defines a staple. It goes on the default list without a listId and is first
+// added on the next run of the staples job without a nextDueAt.
+type CreateStapleRequest struct {
+	Name       string           `json:"name" binding:"required"`
+	ListId     string           `json:"listId"`
+	Recurrence StapleRecurrence `json:"recurrence" binding:"required,oneof=weekly fortnightly days"`
+	Days       int              `json:"days"`
+	NextDueAt  int64            `json:"nextDueAt"`
+}
+
+// UpdateStapleRequest changes a staple, fields that are left out stay as they are
+type UpdateStapleRequest struct {
+	Name       *string           `json:"name"`
+	ListId     *string           `json:"listId"`
+	Recurrence *StapleRecurrence `json:"recurrence" binding:"omitempty,oneof=weekly fortnightly days"`
+	Days       *int              `json:"days"`
+	NextDueAt  *int64            `json:"nextDueAt"`
+}
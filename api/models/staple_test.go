@@ -0,0 +1,82 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStapleAdvance(t *testing.T) {
+	day := int64(24 * 60 * 60)
+	now := time.Unix(100*day, 0)
+
+	tests := []struct {
+		name          string
+		staple        Staple
+		wantNextDueAt int64
+	}{
+		{
+			name:          "due now",
+			staple:        Staple{Recurrence: Weekly, NextDueAt: now.Unix()},
+			wantNextDueAt: now.Unix() + 7*day,
+		},
+		{
+			name:          "missed several weeks",
+			staple:        Staple{Recurrence: Weekly, NextDueAt: now.Unix() - 20*day},
+			wantNextDueAt: now.Unix() + day,
+		},
+		{
+			name:          "fortnightly",
+			staple:        Staple{Recurrence: Fortnightly, NextDueAt: now.Unix() - day},
+			wantNextDueAt: now.Unix() + 13*day,
+		},
+		{
+			name:          "every 3 days",
+			staple:        Staple{Recurrence: EveryNDays, Days: 3, NextDueAt: now.Unix() - 3*day},
+			wantNextDueAt: now.Unix() + 3*day,
+		},
+		{
+			name:          "not due yet",
+			staple:        Staple{Recurrence: Weekly, NextDueAt: now.Unix() + day},
+			wantNextDueAt: now.Unix() + day,
+		},
+		{
+			name:          "no interval",
+			staple:        Staple{Recurrence: EveryNDays, NextDueAt: now.Unix() - day},
+			wantNextDueAt: now.Unix() - day,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			staple := test.staple
+			staple.Advance(now)
+
+			if staple.NextDueAt != test.wantNextDueAt {
+				t.Errorf("NextDueAt = %d, want %d", staple.NextDueAt, test.wantNextDueAt)
+			}
+			if test.staple.Interval() > 0 && staple.Due(now) {
+				t.Errorf("staple is still due after Advance")
+			}
+		})
+	}
+}
+
+func TestStapleScheduleId(t *testing.T) {
+	shards := make(map[string]bool)
+	for _, scheduleId := range StapleScheduleIds() {
+		shards[scheduleId] = true
+	}
+	if len(shards) != StapleScheduleShards {
+		t.Fatalf("StapleScheduleIds() has %d distinct ids, want %d", len(shards), StapleScheduleShards)
+	}
+
+	for _, householdId := range []string{"", "h1", "h2", "a4d2c0d6-3f0e-4c55-9d0c-5b8b7f7f2e11"} {
+		scheduleId := StapleScheduleId(householdId)
+		if !shards[scheduleId] {
+			t.Errorf("StapleScheduleId(%q) = %q, not one of StapleScheduleIds()", householdId, scheduleId)
+		}
+		if again := StapleScheduleId(householdId); again != scheduleId {
+			t.Errorf("StapleScheduleId(%q) = %q then %q", householdId, scheduleId, again)
+		}
+	}
+}
@@ -3,4 +3,36 @@ package models
 type User struct {
 	Id           string   `json:"id" dynamodbav:"id"`
 	HouseholdIds []string `json:"householdIds" dynamodbav:"householdIds"`
+	// DefaultHouseholdId is the household the user lands in, one of HouseholdIds
+	DefaultHouseholdId string `json:"defaultHouseholdId" dynamodbav:"defaultHouseholdId"`
+	// Version is bumped on every update, users written before it existed are version 0
+	Version int `json:"-" dynamodbav:"version"`
+}
+
+// DefaultHousehold returns the user's default household, falling back to the first one they joined
+// for users that never picked one. It is empty when they are not in any household.
+func (u User) DefaultHousehold() string {
+	if u.DefaultHouseholdId != "" {
+		return u.DefaultHouseholdId
+	}
+
+	if len(u.HouseholdIds) > 0 {
+		return u.HouseholdIds[0]
+	}
+
+	return ""
+}
+
+type UserHouseholdsResponse struct {
+	DefaultHouseholdId string      `json:"defaultHouseholdId"`
+	Households         []Household `json:"households"`
+}
+
+type SetDefaultHouseholdRequest struct {
+	HouseholdId string `json:"householdId" binding:"required"`
+}
+
+// AccountExport points at the zip an export of the user was written to, the url expires after a few minutes
+type AccountExport struct {
+	Url string `json:"url"`
 }
@@ -0,0 +1,245 @@
+package ops
+
+import (
+	"api/layouts"
+	"api/models"
+	"api/parsing"
+	"api/providers"
+	"api/proxy"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// resultRetention is how long results are remembered, an operation repeated after that is
+// applied again, which most operations shrug off anyway
+const resultRetention = 7 * 24 * time.Hour
+
+// maxAttempts bounds how often an operation is retried when the item changes under it
+const maxAttempts = 3
+
+// Processor applies batches of operations that clients made, possibly while offline.
+//
+// Operations are applied in the order they are given and each one at most once, repeating an
+// operation returns the result it had the first time. Conflicts are resolved as follows:
+//   - add creates the item on the list with the id the client gave it, splitting a quantity off
+//     the name like routes.Api.CreateGroceryItem does. Adding an item that is on the list already
+//     is a no-op, adding one that is on another list is rejected. Without afterId, or with one
+//     that isn't on the list, the item goes at the end. Adding to a list that has been archived
+//     is rejected.
+//   - delete always wins, operations on an item that has been deleted are rejected, adds too for
+//     as long as its tombstone is kept
+//   - operations on items that are on another list are rejected
+//   - rename, check and uncheck made against an older version of the item only apply when they
+//     were made after the item last changed, otherwise the newer change wins and they are
+//     superseded. Client timestamps in the future count as now.
+//   - reorder moves the item after another block in the order the operations arrive, the last
+//     one wins. Reordering after a block that isn't on the list is rejected.
+type Processor struct {
+	Groceries providers.GroceryRepository
+	Layouts   *layouts.Editor
+	Results   providers.GroceryOpRepository
+}
+
+// Apply applies the operations a user made to one of the household's lists and returns their
+// results. It stops at the first operation that fails, the ones before it are applied and are
+// skipped on retry.
+func (p *Processor) Apply(ctx context.Context, list models.HouseholdList, userId string, ops []models.GroceryOp) ([]models.GroceryOpResult, error) {
+	householdId := list.HouseholdId
+	results := make([]models.GroceryOpResult, 0, len(ops))
+
+	for _, op := range ops {
+		result, err := p.Results.GetOpResult(ctx, householdId, op.Id)
+		if err == nil {
+			results = append(results, result)
+			continue
+		}
+		if !errors.Is(err, proxy.ErrNotFound) {
+			return nil, err
+		}
+
+		result, err = p.apply(ctx, list, userId, op)
+		if err != nil {
+			return nil, fmt.Errorf("unable to apply operation [%s], %w", op.Id, err)
+		}
+
+		result.HouseholdId = householdId
+		result.OpId = op.Id
+		result.ExpiresAt = time.Now().Add(resultRetention).Unix()
+
+		if err := p.Results.SaveOpResult(ctx, result); err != nil {
+			return nil, err
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func (p *Processor) apply(ctx context.Context, list models.HouseholdList, userId string, op models.GroceryOp) (models.GroceryOpResult, error) {
+	householdId := list.HouseholdId
+
+	switch op.Type {
+	case models.AddOp:
+		return p.add(ctx, list, userId, op)
+	case models.RenameOp:
+		name := strings.TrimSpace(op.Name)
+		if name == "" {
+			return rejected("name must not be empty"), nil
+		}
+
+		return p.update(ctx, list, op, func(groceryItem *models.GroceryItem) bool {
+			changed := groceryItem.Name != name
+			groceryItem.Name = name
+			return changed
+		})
+	case models.CheckOp, models.UncheckOp:
+		checked := op.Type == models.CheckOp
+
+		return p.update(ctx, list, op, func(groceryItem *models.GroceryItem) bool {
+			changed := groceryItem.Checked != checked
+			groceryItem.Checked = checked
+			return changed
+		})
+	case models.DeleteOp:
+		groceryItem, err := p.Groceries.GetGroceryItem(ctx, householdId, op.ItemId)
+		if errors.Is(err, proxy.ErrNotFound) {
+			// Deleting twice, or an item that was never synced, leaves the list as the client expects
+			return models.GroceryOpResult{Status: models.OpApplied}, nil
+		}
+		if err != nil {
+			return models.GroceryOpResult{}, err
+		}
+		if !groceryItem.InList(list.Id) {
+			return rejected(notOnList), nil
+		}
+
+		if err := p.Groceries.DeleteGroceryItem(ctx, householdId, op.ItemId); err != nil {
+			return models.GroceryOpResult{}, err
+		}
+
+		return models.GroceryOpResult{Status: models.OpApplied}, nil
+	case models.ReorderOp:
+		err := p.Layouts.MoveItem(ctx, householdId, list.Id, op.ItemId, op.AfterId)
+		if errors.Is(err, layouts.ErrBlockNotFound) {
+			return rejected("afterId is not on the list"), nil
+		}
+		if errors.Is(err, proxy.ErrNotFound) {
+			return rejected("item has been deleted or moved to another list"), nil
+		}
+		if err != nil {
+			return models.GroceryOpResult{}, err
+		}
+
+		return models.GroceryOpResult{Status: models.OpApplied}, nil
+	default:
+		return rejected(fmt.Sprintf("unknown operation type %q", op.Type)), nil
+	}
+}
+
+func (p *Processor) add(ctx context.Context, list models.HouseholdList, userId string, op models.GroceryOp) (models.GroceryOpResult, error) {
+	householdId := list.HouseholdId
+
+	name := strings.TrimSpace(op.Name)
+	if name == "" {
+		return rejected("name must not be empty"), nil
+	}
+
+	// Ids are made up by clients, only uuids keep them from colliding
+	if _, err := uuid.Parse(op.ItemId); err != nil {
+		return rejected("itemId must be a uuid"), nil
+	}
+
+	stored, err := p.Groceries.GetGroceryItem(ctx, householdId, op.ItemId)
+	switch {
+	case err == nil && stored.InList(list.Id):
+		return models.GroceryOpResult{Status: models.OpApplied}, nil
+	case err == nil:
+		return rejected(notOnList), nil
+	case errors.Is(err, providers.ErrGroceryItemDeleted):
+		return rejected(itemDeleted), nil
+	case !errors.Is(err, proxy.ErrNotFound):
+		return models.GroceryOpResult{}, err
+	}
+
+	if list.Archived {
+		return rejected("list is archived"), nil
+	}
+
+	groceryItem := models.GroceryItem{HouseholdId: householdId, Id: op.ItemId, ListId: list.Id, Name: name, AddedBy: userId}
+	if quantity, ok := parsing.ParseQuantity(name); ok {
+		groceryItem.SetQuantity(quantity.Amount, quantity.Unit, quantity.Name)
+	}
+
+	if _, err := p.Groceries.CreateGroceryItem(ctx, groceryItem); err != nil {
+		return models.GroceryOpResult{}, err
+	}
+
+	if op.AfterId != "" {
+		err := p.Layouts.MoveItem(ctx, householdId, list.Id, op.ItemId, op.AfterId)
+		if !errors.Is(err, layouts.ErrBlockNotFound) {
+			return models.GroceryOpResult{Status: models.OpApplied}, err
+		}
+	}
+
+	return models.GroceryOpResult{Status: models.OpApplied}, p.Layouts.PlaceItem(ctx, householdId, list.Id, op.ItemId, "")
+}
+
+// update applies change to the item unless the operation lost to a newer change, change reports
+// whether it changed anything
+func (p *Processor) update(ctx context.Context, list models.HouseholdList, op models.GroceryOp, change func(*models.GroceryItem) bool) (models.GroceryOpResult, error) {
+	madeAt := min(op.ClientTimestamp, time.Now().UnixMilli())
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		groceryItem, err := p.Groceries.GetGroceryItem(ctx, list.HouseholdId, op.ItemId)
+		if errors.Is(err, proxy.ErrNotFound) {
+			return rejected(itemDeleted), nil
+		}
+		if err != nil {
+			return models.GroceryOpResult{}, err
+		}
+		if !groceryItem.InList(list.Id) {
+			return rejected(notOnList), nil
+		}
+
+		if groceryItem.Version != op.BaseVersion && madeAt < groceryItem.UpdatedAt {
+			return models.GroceryOpResult{Status: models.OpSuperseded, Reason: "item was changed after the operation was made"}, nil
+		}
+
+		stored := groceryItem
+		if !change(&groceryItem) {
+			return models.GroceryOpResult{Status: models.OpApplied}, nil
+		}
+		groceryItem.Replaces(stored, time.Now())
+
+		_, err = p.Groceries.UpdateGroceryItem(ctx, groceryItem)
+
+		// Changed in the meantime, decide again against the new version
+		var conflict *providers.GroceryItemConflictError
+		if errors.As(err, &conflict) {
+			continue
+		}
+		if err != nil {
+			return models.GroceryOpResult{}, err
+		}
+
+		return models.GroceryOpResult{Status: models.OpApplied}, nil
+	}
+
+	return models.GroceryOpResult{}, fmt.Errorf("item [%s] kept changing: %w", op.ItemId, proxy.ErrConditionFailed)
+}
+
+// Reasons operations on an item are rejected for
+const (
+	itemDeleted = "item has been deleted"
+	notOnList   = "item is on another list"
+)
+
+func rejected(reason string) models.GroceryOpResult {
+	return models.GroceryOpResult{Status: models.OpRejected, Reason: reason}
+}
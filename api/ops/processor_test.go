@@ -0,0 +1,277 @@
+package ops
+
+import (
+	"api/layouts"
+	"api/models"
+	"api/providers"
+	"api/proxy"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Items newTestProcessor sets up for household h1
+const (
+	// onList is on list-1 and was renamed after it was added, it is at version 2
+	onList = "00000000-0000-0000-0000-00000000000a"
+	// onOtherList is on list-2
+	onOtherList = "00000000-0000-0000-0000-00000000000b"
+	// deleted was on list-1 and has been deleted
+	deleted = "00000000-0000-0000-0000-00000000000c"
+	// unknown was never synced
+	unknown = "00000000-0000-0000-0000-00000000000d"
+)
+
+func newTestProcessor(t *testing.T) *Processor {
+	t.Helper()
+	ctx := context.Background()
+
+	groceries := providers.NewMemoryGroceryRepository()
+	processor := &Processor{
+		Groceries: groceries,
+		Layouts:   &layouts.Editor{Groceries: groceries, Layouts: providers.NewMemoryLayoutRepository()},
+		Results:   providers.NewMemoryGroceryOpRepository(),
+	}
+
+	for _, groceryItem := range []models.GroceryItem{
+		{HouseholdId: "h1", Id: onList, ListId: "list-1", Name: "bread"},
+		{HouseholdId: "h1", Id: onOtherList, ListId: "list-2", Name: "nails"},
+		{HouseholdId: "h1", Id: deleted, ListId: "list-1", Name: "eggs"},
+	} {
+		if _, err := groceries.CreateGroceryItem(ctx, groceryItem); err != nil {
+			t.Fatalf("CreateGroceryItem() error = %v", err)
+		}
+	}
+
+	renamed, err := groceries.GetGroceryItem(ctx, "h1", onList)
+	if err != nil {
+		t.Fatalf("GetGroceryItem() error = %v", err)
+	}
+	renamed.Name = "milk"
+	if _, err := groceries.UpdateGroceryItem(ctx, renamed); err != nil {
+		t.Fatalf("UpdateGroceryItem() error = %v", err)
+	}
+
+	if err := groceries.DeleteGroceryItem(ctx, "h1", deleted); err != nil {
+		t.Fatalf("DeleteGroceryItem() error = %v", err)
+	}
+
+	return processor
+}
+
+func TestProcessorApply(t *testing.T) {
+	// Ops that lost to the rename of onList were made before it, ops in the future count as now
+	before := int64(1)
+	future := time.Now().Add(time.Hour).UnixMilli()
+
+	tests := []struct {
+		name       string
+		archived   bool
+		op         models.GroceryOp
+		wantStatus models.GroceryOpStatus
+		wantReason string
+		// check looks at the items after the operation was applied
+		check func(t *testing.T, groceries providers.GroceryRepository)
+	}{
+		{
+			name:       "add",
+			op:         models.GroceryOp{Type: models.AddOp, ItemId: unknown, Name: "2 l oat milk"},
+			wantStatus: models.OpApplied,
+			check: func(t *testing.T, groceries providers.GroceryRepository) {
+				groceryItem := getItem(t, groceries, unknown)
+				if !groceryItem.InList("list-1") || groceryItem.Name != "oat milk" || groceryItem.Quantity != 2 || groceryItem.AddedBy != "u1" {
+					t.Errorf("added item = %+v", groceryItem)
+				}
+			},
+		},
+		{
+			name:       "add an item on the list",
+			op:         models.GroceryOp{Type: models.AddOp, ItemId: onList, Name: "bread"},
+			wantStatus: models.OpApplied,
+			check: func(t *testing.T, groceries providers.GroceryRepository) {
+				if groceryItem := getItem(t, groceries, onList); groceryItem.Name != "milk" {
+					t.Errorf("item = %+v, want it left alone", groceryItem)
+				}
+			},
+		},
+		{
+			name:       "add an item on another list",
+			op:         models.GroceryOp{Type: models.AddOp, ItemId: onOtherList, Name: "nails"},
+			wantStatus: models.OpRejected,
+			wantReason: notOnList,
+		},
+		{
+			name:       "add a deleted item",
+			op:         models.GroceryOp{Type: models.AddOp, ItemId: deleted, Name: "eggs"},
+			wantStatus: models.OpRejected,
+			wantReason: itemDeleted,
+		},
+		{
+			name:       "add to an archived list",
+			archived:   true,
+			op:         models.GroceryOp{Type: models.AddOp, ItemId: unknown, Name: "eggs"},
+			wantStatus: models.OpRejected,
+		},
+		{
+			name:       "add without a uuid",
+			op:         models.GroceryOp{Type: models.AddOp, ItemId: "item-1", Name: "eggs"},
+			wantStatus: models.OpRejected,
+		},
+		{
+			name:       "rename the current version",
+			op:         models.GroceryOp{Type: models.RenameOp, ItemId: onList, Name: "butter", BaseVersion: 2, ClientTimestamp: before},
+			wantStatus: models.OpApplied,
+			check:      wantName(onList, "butter"),
+		},
+		{
+			name:       "rename an older version before it changed",
+			op:         models.GroceryOp{Type: models.RenameOp, ItemId: onList, Name: "butter", BaseVersion: 1, ClientTimestamp: before},
+			wantStatus: models.OpSuperseded,
+			check:      wantName(onList, "milk"),
+		},
+		{
+			name:       "rename an older version after it changed",
+			op:         models.GroceryOp{Type: models.RenameOp, ItemId: onList, Name: "butter", BaseVersion: 1, ClientTimestamp: future},
+			wantStatus: models.OpApplied,
+			check:      wantName(onList, "butter"),
+		},
+		{
+			name:       "rename to nothing",
+			op:         models.GroceryOp{Type: models.RenameOp, ItemId: onList, Name: " ", BaseVersion: 2},
+			wantStatus: models.OpRejected,
+		},
+		{
+			name:       "check",
+			op:         models.GroceryOp{Type: models.CheckOp, ItemId: onList, BaseVersion: 2},
+			wantStatus: models.OpApplied,
+			check: func(t *testing.T, groceries providers.GroceryRepository) {
+				if groceryItem := getItem(t, groceries, onList); !groceryItem.Checked || groceryItem.CheckedAt == 0 {
+					t.Errorf("item = %+v, want it checked", groceryItem)
+				}
+			},
+		},
+		{
+			name:       "check a deleted item",
+			op:         models.GroceryOp{Type: models.CheckOp, ItemId: deleted, BaseVersion: 1, ClientTimestamp: future},
+			wantStatus: models.OpRejected,
+			wantReason: itemDeleted,
+		},
+		{
+			name:       "check an item on another list",
+			op:         models.GroceryOp{Type: models.CheckOp, ItemId: onOtherList, BaseVersion: 1},
+			wantStatus: models.OpRejected,
+			wantReason: notOnList,
+		},
+		{
+			name:       "delete",
+			op:         models.GroceryOp{Type: models.DeleteOp, ItemId: onList},
+			wantStatus: models.OpApplied,
+			check: func(t *testing.T, groceries providers.GroceryRepository) {
+				if _, err := groceries.GetGroceryItem(context.Background(), "h1", onList); !errors.Is(err, providers.ErrGroceryItemDeleted) {
+					t.Errorf("GetGroceryItem() error = %v, want the item deleted", err)
+				}
+			},
+		},
+		{
+			name:       "delete an item that was never synced",
+			op:         models.GroceryOp{Type: models.DeleteOp, ItemId: unknown},
+			wantStatus: models.OpApplied,
+		},
+		{
+			name:       "delete an item on another list",
+			op:         models.GroceryOp{Type: models.DeleteOp, ItemId: onOtherList},
+			wantStatus: models.OpRejected,
+			wantReason: notOnList,
+			check: func(t *testing.T, groceries providers.GroceryRepository) {
+				getItem(t, groceries, onOtherList)
+			},
+		},
+		{
+			name:       "reorder after a block that isn't on the list",
+			op:         models.GroceryOp{Type: models.ReorderOp, ItemId: onList, AfterId: onOtherList},
+			wantStatus: models.OpRejected,
+		},
+		{
+			name:       "reorder a deleted item",
+			op:         models.GroceryOp{Type: models.ReorderOp, ItemId: deleted},
+			wantStatus: models.OpRejected,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			processor := newTestProcessor(t)
+			list := models.HouseholdList{HouseholdId: "h1", Id: "list-1", Archived: test.archived}
+
+			test.op.Id = "op-1"
+			results, err := processor.Apply(context.Background(), list, "u1", []models.GroceryOp{test.op})
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+
+			result := results[0]
+			if result.OpId != test.op.Id || result.Status != test.wantStatus {
+				t.Fatalf("Apply() result = %+v, want %s", result, test.wantStatus)
+			}
+			if test.wantReason != "" && result.Reason != test.wantReason {
+				t.Errorf("Apply() reason = %q, want %q", result.Reason, test.wantReason)
+			}
+
+			if test.check != nil {
+				test.check(t, processor.Groceries)
+			}
+		})
+	}
+}
+
+func TestProcessorApplyRepeatedOp(t *testing.T) {
+	ctx := context.Background()
+	processor := newTestProcessor(t)
+	list := models.HouseholdList{HouseholdId: "h1", Id: "list-1"}
+
+	rename := models.GroceryOp{Id: "op-1", Type: models.RenameOp, ItemId: onList, Name: "butter", BaseVersion: 2}
+	if _, err := processor.Apply(ctx, list, "u1", []models.GroceryOp{rename}); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	// Someone else renames the item, repeating the operation must not undo that
+	groceryItem := getItem(t, processor.Groceries, onList)
+	groceryItem.Name = "cheese"
+	if _, err := processor.Groceries.UpdateGroceryItem(ctx, groceryItem); err != nil {
+		t.Fatalf("UpdateGroceryItem() error = %v", err)
+	}
+
+	results, err := processor.Apply(ctx, list, "u1", []models.GroceryOp{rename})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if results[0].Status != models.OpApplied {
+		t.Errorf("repeated result = %+v, want the first result", results[0])
+	}
+	wantName(onList, "cheese")(t, processor.Groceries)
+}
+
+func getItem(t *testing.T, groceries providers.GroceryRepository, itemId string) models.GroceryItem {
+	t.Helper()
+
+	groceryItem, err := groceries.GetGroceryItem(context.Background(), "h1", itemId)
+	if errors.Is(err, proxy.ErrNotFound) {
+		t.Fatalf("item [%s] is gone", itemId)
+	}
+	if err != nil {
+		t.Fatalf("GetGroceryItem() error = %v", err)
+	}
+
+	return groceryItem
+}
+
+func wantName(itemId string, name string) func(t *testing.T, groceries providers.GroceryRepository) {
+	return func(t *testing.T, groceries providers.GroceryRepository) {
+		t.Helper()
+
+		if groceryItem := getItem(t, groceries, itemId); groceryItem.Name != name {
+			t.Errorf("item name = %q, want %q", groceryItem.Name, name)
+		}
+	}
+}
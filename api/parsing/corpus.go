@@ -7388,8 +7388,10 @@ var corpusMeasures = []string{" tablespoon.. ",
 	" tablespoon ",
 	" teaspoon.. ",
 	" teaspoons. ",
+	" kilograms ",
 	" teaspoon. ",
 	" teaspoons ",
+	" kilogram ",
 	" teaspoon ",
 	" canned. ",
 	" ounces. ",
@@ -7400,6 +7402,8 @@ var corpusMeasures = []string{" tablespoon.. ",
 	" canned ",
 	" gram.. ",
 	" grams. ",
+	" liters ",
+	" litres ",
 	" ounce. ",
 	" ounces ",
 	" pints. ",
@@ -7417,6 +7421,9 @@ var corpusMeasures = []string{" tablespoon.. ",
 	" cups. ",
 	" gram. ",
 	" grams ",
+	" kilos ",
+	" liter ",
+	" litre ",
 	" ounce ",
 	" pint. ",
 	" pints ",
@@ -7432,6 +7439,7 @@ var corpusMeasures = []string{" tablespoon.. ",
 	" cup. ",
 	" cups ",
 	" gram ",
+	" kilo ",
 	" ml.. ",
 	" pint ",
 	" tbl. ",
@@ -7442,6 +7450,8 @@ var corpusMeasures = []string{" tablespoon.. ",
 	" tsps ",
 	" can ",
 	" cup ",
+	" kgs ",
+	" lbs ",
 	" ml. ",
 	" oz. ",
 	" tbl ",
@@ -7449,11 +7459,14 @@ var corpusMeasures = []string{" tablespoon.. ",
 	" tsp ",
 	" c. ",
 	" g. ",
+	" kg ",
+	" lb ",
 	" ml ",
 	" oz ",
 	" t. ",
 	" c ",
 	" g ",
+	" l ",
 	" t "}
 var corpusNumbers = []string{" 1/2 ",
 	" 1/3 ",
@@ -8369,6 +8382,19 @@ var corpusMeasuresMap = map[string]string{
 	"gram..":       "gram",
 	"grams":        "gram",
 	"grams.":       "gram",
+	"kg":           "kilogram",
+	"kgs":          "kilogram",
+	"kilo":         "kilogram",
+	"kilogram":     "kilogram",
+	"kilograms":    "kilogram",
+	"kilos":        "kilogram",
+	"l":            "liter",
+	"lb":           "pound",
+	"lbs":          "pound",
+	"liter":        "liter",
+	"liters":       "liter",
+	"litre":        "liter",
+	"litres":       "liter",
 	"milliliter":   "milliliter",
 	"milliliter.":  "milliliter",
 	"ml":           "milliliter",
@@ -1,6 +1,7 @@
 package parsing
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"regexp"
@@ -16,11 +17,23 @@ func NewFromHTML(name, htmlstring string) (r *Recipe, err error) {
 	return
 }
 
-func NewFromURL(url string) (r *Recipe, err error) {
-	client := http.Client{
-		Timeout: 10 * time.Second,
+// defaultFetchTimeout bounds a recipe fetch when the caller's context has no deadline of its own
+const defaultFetchTimeout = 10 * time.Second
+
+// NewFromURL fetches a recipe page and parses it, the request is cancelled along with ctx
+func NewFromURL(ctx context.Context, url string) (r *Recipe, err error) {
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultFetchTimeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return
 	}
-	resp, err := client.Get(url)
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return
 	}
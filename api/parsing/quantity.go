@@ -0,0 +1,120 @@
+package parsing
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Quantity is how much of something a line of a shopping list asks for
+type Quantity struct {
+	Amount float64
+	// Unit is one of the values of corpusMeasuresMap, e.g. "kilogram", empty for a number of things
+	Unit string
+	// Name is what is left of the line, as it was written
+	Name string
+}
+
+// gluedUnitRegexp finds an amount written together with its unit, like "2kg" or "1.5l"
+var gluedUnitRegexp = regexp.MustCompile(`^(\s*[0-9][0-9./]*)([a-zA-Z]+\.*)(\s|$)`)
+
+// ParseQuantity splits a line like "2kg chicken thighs" or "1 1/2 cups of flour" into the amount,
+// unit and name. A count of packs, as in "3 x 500g mince", is multiplied out to 1500 grams of mince.
+// ok is false when the line doesn't start with an amount or nothing follows it. Signed amounts and
+// ranges like "2-3 apples" aren't read as amounts, the line is left as it is.
+func ParseQuantity(line string) (quantity Quantity, ok bool) {
+	words := strings.Fields(separateGluedUnit(line))
+
+	used := 0
+	for used < len(words) {
+		amount, isAmount := parseAmount(words[used])
+		if !isAmount {
+			break
+		}
+
+		// Only a fraction can follow a whole number, as in "1 1/2"
+		if used > 0 && (amount >= 1 || quantity.Amount != math.Trunc(quantity.Amount)) {
+			break
+		}
+
+		quantity.Amount += amount
+		used++
+	}
+
+	if used == 0 {
+		return Quantity{}, false
+	}
+
+	switch {
+	case used == len(words):
+	case isTimes(words[used]):
+		used++
+
+		// "3 x 500g mince" is 1500g of it, "4 x yoghurt" is 4 of them
+		if pack, ok := ParseQuantity(strings.Join(words[used:], " ")); ok && pack.Unit != "" {
+			pack.Amount *= quantity.Amount
+			return pack, true
+		}
+	default:
+		if measures := GetMeasuresInString(SanitizeLine(words[used])); len(measures) == 1 {
+			quantity.Unit = corpusMeasuresMap[measures[0].Word]
+			used++
+		}
+	}
+
+	if quantity.Unit != "" && used < len(words) && strings.EqualFold(words[used], "of") {
+		used++
+	}
+
+	quantity.Name = strings.Join(words[used:], " ")
+	if quantity.Name == "" {
+		return Quantity{}, false
+	}
+
+	return quantity, true
+}
+
+// parseAmount reads a word that is a number, like "2", "1.5", "1/2" or "½". SanitizeLine drops
+// signs, so words with one are turned down before it sees them.
+func parseAmount(word string) (amount float64, ok bool) {
+	if strings.ContainsAny(word, "+-−") {
+		return 0, false
+	}
+
+	parts := strings.Fields(SanitizeLine(word))
+	if len(parts) == 0 {
+		return 0, false
+	}
+
+	for _, part := range parts {
+		if _, err := strconv.ParseFloat(part, 64); err != nil && len(GetNumbersInString(" "+part+" ")) == 0 {
+			return 0, false
+		}
+
+		amount += ConvertStringToNumber(part)
+	}
+
+	return amount, amount > 0
+}
+
+// separateGluedUnit puts a space between an amount and the unit written right after it, so that
+// "2kg chicken" reads as "2 kg chicken", or the x in "4x yoghurt". Amounts glued to anything else,
+// like "7up", are left alone.
+func separateGluedUnit(line string) string {
+	match := gluedUnitRegexp.FindStringSubmatchIndex(line)
+	if match == nil {
+		return line
+	}
+
+	unit := line[match[4]:match[5]]
+	if _, isUnit := corpusMeasuresMap[strings.ToLower(unit)]; !isUnit && !isTimes(unit) {
+		return line
+	}
+
+	return line[:match[3]] + " " + line[match[4]:]
+}
+
+func isTimes(word string) bool {
+	return word == "x" || word == "X" || word == "×"
+}
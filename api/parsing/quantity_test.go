@@ -0,0 +1,41 @@
+package parsing
+
+import "testing"
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		line   string
+		want   Quantity
+		wantOk bool
+	}{
+		{line: "2kg chicken thighs", want: Quantity{Amount: 2, Unit: "kilogram", Name: "chicken thighs"}, wantOk: true},
+		{line: "2 kg chicken thighs", want: Quantity{Amount: 2, Unit: "kilogram", Name: "chicken thighs"}, wantOk: true},
+		{line: "1 1/2 cups of flour", want: Quantity{Amount: 1.5, Unit: "cup", Name: "flour"}, wantOk: true},
+		{line: "½ cup of sugar", want: Quantity{Amount: 0.5, Unit: "cup", Name: "sugar"}, wantOk: true},
+		{line: "4 x yoghurt", want: Quantity{Amount: 4, Name: "yoghurt"}, wantOk: true},
+		{line: "4x yoghurt", want: Quantity{Amount: 4, Name: "yoghurt"}, wantOk: true},
+		{line: "3 x 500g mince", want: Quantity{Amount: 1500, Unit: "gram", Name: "mince"}, wantOk: true},
+		{line: "2 × 1 l milk", want: Quantity{Amount: 2, Unit: "liter", Name: "milk"}, wantOk: true},
+		{line: "6 eggs", want: Quantity{Amount: 6, Name: "eggs"}, wantOk: true},
+		{line: "7up", wantOk: false},
+		{line: "-3 apples", wantOk: false},
+		{line: "+3 apples", wantOk: false},
+		{line: "2-3 apples", wantOk: false},
+		{line: "apples", wantOk: false},
+		{line: "3", wantOk: false},
+		{line: "3 x", wantOk: false},
+		{line: "", wantOk: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.line, func(t *testing.T) {
+			got, ok := ParseQuantity(test.line)
+			if ok != test.wantOk {
+				t.Fatalf("ParseQuantity(%q) ok = %v, want %v", test.line, ok, test.wantOk)
+			}
+			if got != test.want {
+				t.Errorf("ParseQuantity(%q) = %+v, want %+v", test.line, got, test.want)
+			}
+		})
+	}
+}
@@ -244,7 +244,7 @@ func getIngredientLinesInHTML(htmlS string) (lineInfos []LineInfo, err error) {
 		if score > 2 && len(childrenLineInfo) < 25 && len(childrenLineInfo) > 2 {
 			*lineInfos = append(*lineInfos, childrenLineInfo...)
 			for _, child := range childrenLineInfo {
-				log.Println("[%s]", child.LineOriginal)
+				log.Printf("[%s]\n", child.LineOriginal)
 			}
 		}
 		if len(childrenLineInfo) > 0 {
@@ -3,11 +3,36 @@ package providers
 import (
 	"api/models"
 	s3proxy "api/proxy/s3"
+	"context"
+	"sync"
 )
 
-var CatalogBucket = "store-comparison-bucket-001"
-var CatalogKey = "catalog.json"
+// CatalogProvider reads the store price catalog, it is cached after the first successful fetch
+type CatalogProvider struct {
+	blobs   s3proxy.BlobStore
+	bucket  string
+	key     string
+	mu      sync.Mutex
+	catalog *models.Catalog
+}
+
+func NewCatalogProvider(blobs s3proxy.BlobStore, bucket string, key string) *CatalogProvider {
+	return &CatalogProvider{blobs: blobs, bucket: bucket, key: key}
+}
+
+func (p *CatalogProvider) GetCatalog(ctx context.Context) (models.Catalog, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.catalog != nil {
+		return *p.catalog, nil
+	}
+
+	catalog, err := s3proxy.GetDocument[models.Catalog](ctx, p.blobs, p.bucket, p.key)
+	if err != nil {
+		return models.Catalog{}, err
+	}
 
-func GetCatalog() models.Catalog {
-	return s3proxy.GetDocument[models.Catalog](CatalogBucket, CatalogKey)
+	p.catalog = &catalog
+	return catalog, nil
 }
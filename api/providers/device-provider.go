@@ -0,0 +1,118 @@
+package providers
+
+import (
+	"api/models"
+	"api/proxy"
+	ddbproxy "api/proxy/ddb"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+var ErrPairingCodeExpired = errors.New("pairing code has expired")
+
+// DeviceRepository stores the devices users are signed in on and the codes used to link new ones
+type DeviceRepository interface {
+	// GetDevice fails with proxy.ErrNotFound for devices that were never seen
+	GetDevice(ctx context.Context, id string) (models.Device, error)
+	UpdateDevice(ctx context.Context, device models.Device) error
+	// GetUserDevices returns every device linked to the user, including revoked ones
+	GetUserDevices(ctx context.Context, userId string) ([]models.Device, error)
+	DeleteDevice(ctx context.Context, id string) error
+	// CreatePairingCode stores pairing under a freshly generated code and returns it
+	CreatePairingCode(ctx context.Context, pairing models.PairingCode) (models.PairingCode, error)
+	// RedeemPairingCode removes the code and returns it, failing with ErrPairingCodeExpired when it
+	// has expired and proxy.ErrNotFound when it does not exist or was redeemed already
+	RedeemPairingCode(ctx context.Context, code string) (models.PairingCode, error)
+}
+
+// DynamoDeviceRepository is a DeviceRepository backed by the Devices table, with a userId index
+// for listing a user's devices, and the PairingCodes table
+type DynamoDeviceRepository struct {
+	tableName         string
+	pairingsTableName string
+}
+
+const devicesUserIndex = "userId-index"
+
+func NewDynamoDeviceRepository(tableName string, pairingsTableName string) *DynamoDeviceRepository {
+	return &DynamoDeviceRepository{tableName: tableName, pairingsTableName: pairingsTableName}
+}
+
+func (r *DynamoDeviceRepository) GetDevice(ctx context.Context, id string) (models.Device, error) {
+	key := map[string]types.AttributeValue{
+		"id": &types.AttributeValueMemberS{Value: id},
+	}
+
+	return ddbproxy.GetItem[models.Device](ctx, r.tableName, key)
+}
+
+func (r *DynamoDeviceRepository) UpdateDevice(ctx context.Context, device models.Device) error {
+	key := map[string]types.AttributeValue{
+		"id": &types.AttributeValueMemberS{Value: device.Id},
+	}
+
+	return ddbproxy.UpdateItem(ctx, r.tableName, key, device, []string{"id"}, nil)
+}
+
+func (r *DynamoDeviceRepository) GetUserDevices(ctx context.Context, userId string) ([]models.Device, error) {
+	hashKeyAttributeValues := map[string]types.AttributeValue{
+		":uId": &types.AttributeValueMemberS{Value: userId},
+	}
+
+	return ddbproxy.QueryIndex[models.Device](ctx, r.tableName, devicesUserIndex, "userId = :uId", hashKeyAttributeValues)
+}
+
+func (r *DynamoDeviceRepository) DeleteDevice(ctx context.Context, id string) error {
+	key := map[string]types.AttributeValue{
+		"id": &types.AttributeValueMemberS{Value: id},
+	}
+
+	return ddbproxy.DeleteItem(ctx, r.tableName, key)
+}
+
+func (r *DynamoDeviceRepository) CreatePairingCode(ctx context.Context, pairing models.PairingCode) (models.PairingCode, error) {
+	for attempt := 0; attempt < maxInviteCodeAttempts; attempt++ {
+		code, err := newShortCode()
+		if err != nil {
+			return models.PairingCode{}, err
+		}
+		pairing.Code = code
+
+		err = ddbproxy.CreateItemWithCondition(ctx, r.pairingsTableName, pairing, ddbproxy.NotExistsCondition("code"))
+		if errors.Is(err, proxy.ErrConditionFailed) {
+			continue
+		}
+
+		return pairing, err
+	}
+
+	return models.PairingCode{}, fmt.Errorf("unable to find an unused pairing code after %d attempts", maxInviteCodeAttempts)
+}
+
+func (r *DynamoDeviceRepository) RedeemPairingCode(ctx context.Context, code string) (models.PairingCode, error) {
+	key := map[string]types.AttributeValue{
+		"code": &types.AttributeValueMemberS{Value: code},
+	}
+
+	pairing, err := ddbproxy.GetItem[models.PairingCode](ctx, r.pairingsTableName, key)
+	if err != nil {
+		return models.PairingCode{}, err
+	}
+
+	// The table's TTL takes a while to remove expired codes
+	if time.Now().Unix() >= pairing.ExpiresAt {
+		return models.PairingCode{}, ErrPairingCodeExpired
+	}
+
+	// Whoever deletes the code first gets to redeem it
+	err = ddbproxy.DeleteItemWithCondition(ctx, r.pairingsTableName, key, ddbproxy.ExistsCondition("code"))
+	if errors.Is(err, proxy.ErrConditionFailed) {
+		return models.PairingCode{}, fmt.Errorf("pairing code was redeemed already: %w", proxy.ErrNotFound)
+	}
+
+	return pairing, err
+}
@@ -0,0 +1,48 @@
+package providers
+
+import (
+	s3proxy "api/proxy/s3"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExportProvider keeps account exports in their own bucket until they are downloaded. The bucket
+// expires them after a day, the presigned urls handed out for them only last minutes.
+type ExportProvider struct {
+	blobs      s3proxy.BlobStore
+	bucketName string
+}
+
+func NewExportProvider(blobs s3proxy.BlobStore, bucketName string) *ExportProvider {
+	return &ExportProvider{blobs: blobs, bucketName: bucketName}
+}
+
+// SaveExport stores the export of the user and returns an url to download it from
+func (p *ExportProvider) SaveExport(ctx context.Context, userId string, export []byte) (string, error) {
+	key := fmt.Sprintf("%s/%d-%s.zip", userId, time.Now().Unix(), uuid.NewString())
+
+	if err := p.blobs.PutObject(ctx, key, p.bucketName, export); err != nil {
+		return "", err
+	}
+
+	return p.blobs.GeneratePresignedDownloadUrl(ctx, p.bucketName, key)
+}
+
+// DeleteUserExports removes the exports of the user that haven't expired yet
+func (p *ExportProvider) DeleteUserExports(ctx context.Context, userId string) error {
+	keys, err := p.blobs.GetKeys(ctx, p.bucketName, userId+"/")
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := p.blobs.DeleteObject(ctx, p.bucketName, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -2,52 +2,310 @@ package providers
 
 import (
 	"api/models"
+	"api/proxy"
 	ddbproxy "api/proxy/ddb"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
-var groceriesTableName = "Groceries"
+// GroceryTombstoneRetention is how long deleted items are remembered as tombstones,
+// changes since longer ago than that can't be told apart from the whole list
+const GroceryTombstoneRetention = 30 * 24 * time.Hour
+
+// ErrGroceryItemDeleted is what GetGroceryItem fails with for items that have been deleted, it is
+// also a proxy.ErrNotFound
+var ErrGroceryItemDeleted = fmt.Errorf("grocery item has been deleted: %w", proxy.ErrNotFound)
+
+// GroceryRepository stores the grocery items that belong to a household.
+// Deleting an item leaves a tombstone behind, only GetGroceryItemChanges returns tombstones.
+type GroceryRepository interface {
+	GetGroceryItems(ctx context.Context, householdId string) ([]models.GroceryItem, error)
+	// GetGroceryItem fails with proxy.ErrNotFound when there is no such item, and with
+	// ErrGroceryItemDeleted while its tombstone is around
+	GetGroceryItem(ctx context.Context, householdId string, groceryItemId string) (models.GroceryItem, error)
+	// GetGroceryItemsPage returns up to limit items after cursor and the cursor for the next page,
+	// which is empty when there are no more items. Pages holding tombstones come back short.
+	// Cursors that weren't handed out for the household fail with proxy.ErrInvalidCursor.
+	GetGroceryItemsPage(ctx context.Context, householdId string, limit int32, cursor string) ([]models.GroceryItem, string, error)
+	// GetGroceryItemChanges returns the items and tombstones that changed at or after since,
+	// least recently changed first
+	GetGroceryItemChanges(ctx context.Context, householdId string, since time.Time) ([]models.GroceryItem, error)
+	// CreateGroceryItem returns the item as it was stored, with its version and timestamps set
+	CreateGroceryItem(ctx context.Context, groceryItem models.GroceryItem) (models.GroceryItem, error)
+	// UpdateGroceryItem stores the item if its version matches the stored one and returns it with
+	// the version bumped, otherwise it fails with a *GroceryItemConflictError. It never creates
+	// items, updating one that doesn't exist or has been deleted fails with proxy.ErrNotFound.
+	UpdateGroceryItem(ctx context.Context, groceryItem models.GroceryItem) (models.GroceryItem, error)
+	DeleteGroceryItem(ctx context.Context, householdId string, groceryItemId string) error
+	// BatchCreateGroceryItems returns the items it created as they were stored, the items missing
+	// from them could not be created
+	BatchCreateGroceryItems(ctx context.Context, groceryItems []models.GroceryItem) ([]models.GroceryItem, error)
+	// BatchDeleteGroceryItems returns the items that could not be deleted
+	BatchDeleteGroceryItems(ctx context.Context, groceryItems []models.GroceryItem) ([]models.GroceryItem, error)
+}
+
+// GroceryItemConflictError is returned when an update was based on a stale version of an item
+type GroceryItemConflictError struct {
+	// Current is the stored item, nil when it doesn't exist or has been deleted
+	Current *models.GroceryItem
+}
+
+func (e *GroceryItemConflictError) Error() string {
+	if e.Current == nil {
+		return "grocery item does not exist or has been deleted"
+	}
+
+	return fmt.Sprintf("grocery item has been updated, current version is %d", e.Current.Version)
+}
+
+func (e *GroceryItemConflictError) Unwrap() error {
+	if e.Current == nil {
+		return proxy.ErrNotFound
+	}
+
+	return proxy.ErrConditionFailed
+}
+
+// groceryChangesIndex is the index of the Groceries table sorted by updatedAt
+const groceryChangesIndex = "householdId-updatedAt-index"
+
+// DynamoGroceryRepository is a GroceryRepository backed by the Groceries table
+type DynamoGroceryRepository struct {
+	tableName string
+}
+
+func NewDynamoGroceryRepository(tableName string) *DynamoGroceryRepository {
+	return &DynamoGroceryRepository{tableName: tableName}
+}
+
+func (r *DynamoGroceryRepository) GetGroceryItems(ctx context.Context, householdId string) ([]models.GroceryItem, error) {
+	hashKeyAttributeValues := map[string]types.AttributeValue{
+		":hId": &types.AttributeValueMemberS{Value: householdId},
+	}
+
+	groceryItems, err := ddbproxy.QueryTable[models.GroceryItem](ctx, r.tableName, "householdId = :hId", hashKeyAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	return withoutTombstones(groceryItems), nil
+}
+
+func (r *DynamoGroceryRepository) GetGroceryItem(ctx context.Context, householdId string, groceryItemId string) (models.GroceryItem, error) {
+	key := map[string]types.AttributeValue{
+		"householdId": &types.AttributeValueMemberS{Value: householdId},
+		"id":          &types.AttributeValueMemberS{Value: groceryItemId},
+	}
+
+	groceryItem, err := ddbproxy.GetItem[models.GroceryItem](ctx, r.tableName, key)
+	if err == nil && groceryItem.Deleted {
+		return models.GroceryItem{}, fmt.Errorf("grocery item [%s]: %w", groceryItemId, ErrGroceryItemDeleted)
+	}
+
+	return groceryItem, err
+}
+
+func (r *DynamoGroceryRepository) GetGroceryItemsPage(ctx context.Context, householdId string, limit int32, cursor string) ([]models.GroceryItem, string, error) {
+	if _, err := ddbproxy.DecodePartitionCursor(cursor, "householdId", householdId, "id"); err != nil {
+		return nil, "", err
+	}
 
-func GetGroceryItems(householdId string) []models.GroceryItem {
 	hashKeyAttributeValues := map[string]types.AttributeValue{
 		":hId": &types.AttributeValueMemberS{Value: householdId},
 	}
 
-	return ddbproxy.QueryTable[models.GroceryItem](groceriesTableName, "householdId = :hId", hashKeyAttributeValues)
+	groceryItems, nextCursor, err := ddbproxy.QueryTablePage[models.GroceryItem](ctx, r.tableName, "householdId = :hId", hashKeyAttributeValues, limit, cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return withoutTombstones(groceryItems), nextCursor, nil
 }
 
-func CreateGroceryItem(groceryItem models.GroceryItem) error {
-	return ddbproxy.CreateItem(groceriesTableName, groceryItem)
+func (r *DynamoGroceryRepository) GetGroceryItemChanges(ctx context.Context, householdId string, since time.Time) ([]models.GroceryItem, error) {
+	keyAttributeValues := map[string]types.AttributeValue{
+		":hId":   &types.AttributeValueMemberS{Value: householdId},
+		":since": &types.AttributeValueMemberN{Value: strconv.FormatInt(since.UnixMilli(), 10)},
+	}
+
+	return ddbproxy.QueryIndex[models.GroceryItem](ctx, r.tableName, groceryChangesIndex, "householdId = :hId AND updatedAt >= :since", keyAttributeValues)
 }
 
-func UpdateGroceryItem(groceryItem models.GroceryItem) error {
+func (r *DynamoGroceryRepository) CreateGroceryItem(ctx context.Context, groceryItem models.GroceryItem) (models.GroceryItem, error) {
+	created(&groceryItem, time.Now())
+	if err := ddbproxy.CreateItem(ctx, r.tableName, groceryItem); err != nil {
+		return models.GroceryItem{}, err
+	}
+
+	return groceryItem, nil
+}
+
+func (r *DynamoGroceryRepository) UpdateGroceryItem(ctx context.Context, groceryItem models.GroceryItem) (models.GroceryItem, error) {
 	key := map[string]types.AttributeValue{
 		"householdId": &types.AttributeValueMemberS{Value: groceryItem.HouseholdId},
 		"id":          &types.AttributeValueMemberS{Value: groceryItem.Id},
 	}
 	ignoreKeys := []string{"id", "householdId"}
 
-	return ddbproxy.UpdateItem(groceriesTableName, key, groceryItem, ignoreKeys)
+	condition := ddbproxy.AndCondition(ddbproxy.ExistsCondition("id"), ddbproxy.VersionCondition("version", groceryItem.Version), notDeletedCondition)
+	groceryItem.Version++
+	touch(&groceryItem, time.Now())
+
+	err := ddbproxy.UpdateItem(ctx, r.tableName, key, groceryItem, ignoreKeys, condition)
+
+	var conditionFailed *ddbproxy.ConditionFailedError
+	if errors.As(err, &conditionFailed) {
+		conflict := &GroceryItemConflictError{}
+		if len(conditionFailed.Item) > 0 {
+			var current models.GroceryItem
+			if err := attributevalue.UnmarshalMap(conditionFailed.Item, &current); err != nil {
+				return models.GroceryItem{}, fmt.Errorf("%w conflicting item: %w", proxy.ErrUnmarshal, err)
+			}
+			if !current.Deleted {
+				conflict.Current = &current
+			}
+		}
+
+		return models.GroceryItem{}, conflict
+	}
+	if err != nil {
+		return models.GroceryItem{}, err
+	}
+
+	return groceryItem, nil
 }
 
-func DeleteGroceryItem(householdId string, groceryItemId string) error {
-	key := map[string]types.AttributeValue{
-		"householdId": &types.AttributeValueMemberS{Value: householdId},
-		"id":          &types.AttributeValueMemberS{Value: groceryItemId},
+// DeleteGroceryItem replaces the item with a tombstone, deleting an item that doesn't exist succeeds
+func (r *DynamoGroceryRepository) DeleteGroceryItem(ctx context.Context, householdId string, groceryItemId string) error {
+	err := ddbproxy.CreateItemWithCondition(ctx, r.tableName, tombstone(householdId, groceryItemId, time.Now()), ddbproxy.ExistsCondition("id"))
+	if errors.Is(err, proxy.ErrConditionFailed) {
+		return nil
 	}
 
-	return ddbproxy.DeleteItem(groceriesTableName, key)
+	return err
 }
 
-func BatchDeleteGroceryItems(groceryItems []models.GroceryItem) error {
-	keys := make([]map[string]types.AttributeValue, len(groceryItems))
+func (r *DynamoGroceryRepository) BatchCreateGroceryItems(ctx context.Context, groceryItems []models.GroceryItem) ([]models.GroceryItem, error) {
+	groceryItems = uniqueGroceryItems(groceryItems)
+	now := time.Now()
+	for index := range groceryItems {
+		created(&groceryItems[index], now)
+	}
+
+	notWritten, err := ddbproxy.BatchPutItems(ctx, r.tableName, groceryItems)
+
+	notCreated := make(map[[2]string]bool, len(notWritten))
+	for _, groceryItem := range notWritten {
+		notCreated[[2]string{groceryItem.HouseholdId, groceryItem.Id}] = true
+	}
+
+	stored := make([]models.GroceryItem, 0, len(groceryItems))
+	for _, groceryItem := range groceryItems {
+		if !notCreated[[2]string{groceryItem.HouseholdId, groceryItem.Id}] {
+			stored = append(stored, groceryItem)
+		}
+	}
+
+	return stored, err
+}
+
+// BatchDeleteGroceryItems replaces the items with tombstones. Batch writes can't be conditional,
+// so unlike DeleteGroceryItem it leaves tombstones behind for items that didn't exist.
+func (r *DynamoGroceryRepository) BatchDeleteGroceryItems(ctx context.Context, groceryItems []models.GroceryItem) ([]models.GroceryItem, error) {
+	groceryItems = uniqueGroceryItems(groceryItems)
+
+	now := time.Now()
+	tombstones := make([]models.GroceryItem, len(groceryItems))
 	for index, groceryItem := range groceryItems {
-		keys[index] = map[string]types.AttributeValue{
-			"householdId": &types.AttributeValueMemberS{Value: groceryItem.HouseholdId},
-			"id":          &types.AttributeValueMemberS{Value: groceryItem.Id},
+		tombstones[index] = tombstone(groceryItem.HouseholdId, groceryItem.Id, now)
+	}
+
+	notWritten, err := ddbproxy.BatchPutItems(ctx, r.tableName, tombstones)
+
+	failedItems := make([]models.GroceryItem, 0, len(notWritten))
+	for _, failed := range notWritten {
+		for _, groceryItem := range groceryItems {
+			if groceryItem.HouseholdId == failed.HouseholdId && groceryItem.Id == failed.Id {
+				failedItems = append(failedItems, groceryItem)
+				break
+			}
 		}
 	}
 
-	return ddbproxy.BatchDeleteItems(groceriesTableName, keys)
+	return failedItems, err
+}
+
+// notDeletedCondition keeps updates from bringing tombstones back to life
+var notDeletedCondition = &ddbproxy.Condition{
+	Expression: "attribute_not_exists(#conditionDeleted)",
+	Names:      map[string]string{"#conditionDeleted": "deleted"},
+}
+
+// touch records that the item changed at now. It also clears Deleted, which clients can't set,
+// items are only deleted by replacing them with a tombstone.
+func touch(groceryItem *models.GroceryItem, now time.Time) {
+	groceryItem.UpdatedAt = now.UnixMilli()
+	groceryItem.Deleted = false
+	groceryItem.ExpiresAt = 0
+}
+
+// created stamps an item that is being created, items that are created checked count as checked then
+func created(groceryItem *models.GroceryItem, now time.Time) {
+	groceryItem.Version = 1
+	touch(groceryItem, now)
+	groceryItem.CreatedAt = groceryItem.UpdatedAt
+
+	groceryItem.CheckedAt = 0
+	if groceryItem.Checked {
+		groceryItem.CheckedAt = groceryItem.UpdatedAt
+	}
+}
+
+// tombstone stands in for a deleted item until the Groceries table's TTL removes it. It only keeps
+// the key, nothing of what was on the list is remembered.
+func tombstone(householdId string, groceryItemId string, deletedAt time.Time) models.GroceryItem {
+	return models.GroceryItem{
+		HouseholdId: householdId,
+		Id:          groceryItemId,
+		UpdatedAt:   deletedAt.UnixMilli(),
+		Deleted:     true,
+		ExpiresAt:   deletedAt.Add(GroceryTombstoneRetention).Unix(),
+	}
+}
+
+func withoutTombstones(groceryItems []models.GroceryItem) []models.GroceryItem {
+	live := make([]models.GroceryItem, 0, len(groceryItems))
+	for _, groceryItem := range groceryItems {
+		if !groceryItem.Deleted {
+			live = append(live, groceryItem)
+		}
+	}
+
+	return live
+}
+
+// uniqueGroceryItems drops repeated keys, a batch write fails outright if it touches the same key twice.
+// The last occurrence of a key wins.
+func uniqueGroceryItems(groceryItems []models.GroceryItem) []models.GroceryItem {
+	indexes := make(map[[2]string]int)
+	unique := make([]models.GroceryItem, 0, len(groceryItems))
+
+	for _, groceryItem := range groceryItems {
+		key := [2]string{groceryItem.HouseholdId, groceryItem.Id}
+		if index, seen := indexes[key]; seen {
+			unique[index] = groceryItem
+			continue
+		}
+
+		indexes[key] = len(unique)
+		unique = append(unique, groceryItem)
+	}
+
+	return unique
 }
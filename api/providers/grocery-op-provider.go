@@ -0,0 +1,40 @@
+package providers
+
+import (
+	"api/models"
+	ddbproxy "api/proxy/ddb"
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// GroceryOpRepository remembers the results of applied grocery operations, so that a client
+// retrying a batch gets the same results without the operations being applied again
+type GroceryOpRepository interface {
+	// GetOpResult fails with proxy.ErrNotFound for operations that weren't applied
+	GetOpResult(ctx context.Context, householdId string, opId string) (models.GroceryOpResult, error)
+	SaveOpResult(ctx context.Context, result models.GroceryOpResult) error
+}
+
+// DynamoGroceryOpRepository is a GroceryOpRepository backed by the GroceryOps table,
+// whose TTL removes results once they expire
+type DynamoGroceryOpRepository struct {
+	tableName string
+}
+
+func NewDynamoGroceryOpRepository(tableName string) *DynamoGroceryOpRepository {
+	return &DynamoGroceryOpRepository{tableName: tableName}
+}
+
+func (r *DynamoGroceryOpRepository) GetOpResult(ctx context.Context, householdId string, opId string) (models.GroceryOpResult, error) {
+	key := map[string]types.AttributeValue{
+		"householdId": &types.AttributeValueMemberS{Value: householdId},
+		"opId":        &types.AttributeValueMemberS{Value: opId},
+	}
+
+	return ddbproxy.GetItem[models.GroceryOpResult](ctx, r.tableName, key)
+}
+
+func (r *DynamoGroceryOpRepository) SaveOpResult(ctx context.Context, result models.GroceryOpResult) error {
+	return ddbproxy.CreateItem(ctx, r.tableName, result)
+}
@@ -2,50 +2,353 @@ package providers
 
 import (
 	"api/models"
+	"api/proxy"
 	ddbproxy "api/proxy/ddb"
+	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/google/uuid"
 )
 
-var householdTableName = "Households"
+// HouseholdRepository stores households and the users that belong to them
+type HouseholdRepository interface {
+	// CreateHousehold creates a household owned by ownerId and makes them its first member
+	CreateHousehold(ctx context.Context, ownerId string, name string) (models.Household, error)
+	// GetHousehold fails with proxy.ErrNotFound for households that are being deleted
+	GetHousehold(ctx context.Context, householdId string) (models.Household, error)
+	// MarkHouseholdForDeletion hides the household and queues it for the deletion job
+	MarkHouseholdForDeletion(ctx context.Context, householdId string) error
+	// GetHouseholdsPendingDeletion returns the ids of households queued for deletion
+	GetHouseholdsPendingDeletion(ctx context.Context) ([]string, error)
+	// DeleteHousehold removes the household record itself, nothing that belongs to it
+	DeleteHousehold(ctx context.Context, householdId string) error
+	RenameHousehold(ctx context.Context, householdId string, name string) (models.Household, error)
+	// TransferHousehold makes toUserId the owner, failing with proxy.ErrConditionFailed unless
+	// fromUserId still owns the household. The previous owner stays on as an admin, households
+	// created before owners existed are transferred from "".
+	TransferHousehold(ctx context.Context, householdId string, fromUserId string, toUserId string) (models.Household, error)
+	JoinHousehold(ctx context.Context, userId string, householdId string) error
+	LeaveHousehold(ctx context.Context, userId string, householdId string) error
+	GetMembers(ctx context.Context, householdId string) ([]models.HouseholdMember, error)
+	// GetMember fails with proxy.ErrNotFound when the user has no entry in the members index
+	GetMember(ctx context.Context, householdId string, userId string) (models.HouseholdMember, error)
+	SetMemberRole(ctx context.Context, householdId string, userId string, role models.HouseholdRole) error
+}
+
+// DynamoHouseholdRepository is a HouseholdRepository backed by the Households and HouseholdMembers tables.
+// Membership is also stored on the user record, so it needs a UserRepository.
+type DynamoHouseholdRepository struct {
+	tableName        string
+	membersTableName string
+	users            UserRepository
+}
+
+func NewDynamoHouseholdRepository(tableName string, membersTableName string, users UserRepository) *DynamoHouseholdRepository {
+	return &DynamoHouseholdRepository{tableName: tableName, membersTableName: membersTableName, users: users}
+}
+
+func (r *DynamoHouseholdRepository) CreateHousehold(ctx context.Context, ownerId string, name string) (models.Household, error) {
+	household := newHousehold(ownerId, name)
+
+	if err := ddbproxy.CreateItem(ctx, r.tableName, household); err != nil {
+		return models.Household{}, err
+	}
+
+	return household, r.JoinHousehold(ctx, ownerId, household.Id)
+}
+
+func (r *DynamoHouseholdRepository) GetHousehold(ctx context.Context, householdId string) (models.Household, error) {
+	household, err := ddbproxy.GetItem[models.Household](ctx, r.tableName, householdKey(householdId))
+	if err != nil {
+		return models.Household{}, err
+	}
+
+	if household.DeletionStatus != "" {
+		return models.Household{}, fmt.Errorf("household [%s] is being deleted: %w", householdId, proxy.ErrNotFound)
+	}
+
+	return household, nil
+}
+
+// householdsDeletionIndex is a sparse index, only households being deleted have a deletionStatus
+const householdsDeletionIndex = "deletionStatus-index"
+
+func (r *DynamoHouseholdRepository) MarkHouseholdForDeletion(ctx context.Context, householdId string) error {
+	update := struct {
+		DeletionStatus models.DeletionStatus `dynamodbav:"deletionStatus"`
+		DeletedAt      int64                 `dynamodbav:"deletedAt"`
+	}{DeletionStatus: models.DeletionPending, DeletedAt: time.Now().Unix()}
+
+	err := ddbproxy.UpdateItem(ctx, r.tableName, householdKey(householdId), update, nil, householdActiveCondition())
+	if errors.Is(err, proxy.ErrConditionFailed) {
+		return fmt.Errorf("could not find household [%s]: %w", householdId, proxy.ErrNotFound)
+	}
+
+	return err
+}
+
+func (r *DynamoHouseholdRepository) GetHouseholdsPendingDeletion(ctx context.Context) ([]string, error) {
+	hashKeyAttributeValues := map[string]types.AttributeValue{
+		":status": &types.AttributeValueMemberS{Value: string(models.DeletionPending)},
+	}
+
+	households, err := ddbproxy.QueryIndex[models.Household](ctx, r.tableName, householdsDeletionIndex, "deletionStatus = :status", hashKeyAttributeValues)
+	if err != nil {
+		return nil, err
+	}
 
-func CreateHousehold() models.Household {
-	householdId := uuid.NewString()
-	household := models.Household{
-		Id: householdId,
+	householdIds := make([]string, len(households))
+	for i, household := range households {
+		householdIds[i] = household.Id
 	}
-	ddbproxy.CreateItem(householdTableName, household)
 
-	return household
+	return householdIds, nil
 }
 
-func JoinHousehold(userId string, householdId string) error {
-	user := GetOrCreateUser(userId)
+func (r *DynamoHouseholdRepository) DeleteHousehold(ctx context.Context, householdId string) error {
+	return ddbproxy.DeleteItem(ctx, r.tableName, householdKey(householdId))
+}
+
+func (r *DynamoHouseholdRepository) RenameHousehold(ctx context.Context, householdId string, name string) (models.Household, error) {
+	household, err := r.GetHousehold(ctx, householdId)
+	if err != nil {
+		return models.Household{}, err
+	}
+
+	update := struct {
+		Name string `dynamodbav:"name"`
+	}{Name: name}
+	household.Name = name
+
+	// The household may have been marked for deletion since it was read
+	err = ddbproxy.UpdateItem(ctx, r.tableName, householdKey(householdId), update, nil, householdActiveCondition())
+	if errors.Is(err, proxy.ErrConditionFailed) {
+		return models.Household{}, fmt.Errorf("could not find household [%s]: %w", householdId, proxy.ErrNotFound)
+	}
+	if err != nil {
+		return models.Household{}, err
+	}
+
+	return household, nil
+}
+
+func (r *DynamoHouseholdRepository) TransferHousehold(ctx context.Context, householdId string, fromUserId string, toUserId string) (models.Household, error) {
+	household, err := r.GetHousehold(ctx, householdId)
+	if err != nil {
+		return models.Household{}, err
+	}
+
+	update := struct {
+		OwnerId string `dynamodbav:"ownerId"`
+	}{OwnerId: toUserId}
+	expression := "#householdOwner = :householdOwner"
+	if fromUserId == "" {
+		// Households created before owners existed have no owner attribute at all
+		expression = "attribute_not_exists(#householdOwner) OR " + expression
+	}
+	condition := &ddbproxy.Condition{
+		Expression: expression,
+		Names:      map[string]string{"#householdOwner": "ownerId"},
+		Values: map[string]types.AttributeValue{
+			":householdOwner": &types.AttributeValueMemberS{Value: fromUserId},
+		},
+	}
+
+	updates := []ddbproxy.Update{
+		{TableName: r.tableName, Key: householdKey(householdId), Record: update, Condition: condition},
+	}
 
-	// TODO: support joining multiple households,
-	// for now this makes life simpler. If you join one you'll leave others
-	user.HouseholdIds = []string{householdId}
-	return UpdateUser(user)
+	// Households without an owner have nobody to demote, neither do owners missing from the members index
+	if fromUserId != "" {
+		_, err := r.GetMember(ctx, householdId, fromUserId)
+		switch {
+		case err == nil:
+			updates = append(updates, r.memberRoleUpdate(householdId, fromUserId, models.AdminRole))
+		case !errors.Is(err, proxy.ErrNotFound):
+			return models.Household{}, err
+		}
+	}
+
+	// The owner is swapped and demoted together, so a failure can't leave the household with two owners
+	if err := ddbproxy.TransactUpdateItems(ctx, updates...); err != nil {
+		return models.Household{}, err
+	}
+	household.OwnerId = toUserId
+
+	return household, nil
 }
 
-func LeaveHousehold(userId string, householdIdToRemove string) error {
-	users := GetUsers(userId)
+func (r *DynamoHouseholdRepository) JoinHousehold(ctx context.Context, userId string, householdId string) error {
+	member := newHouseholdMember(householdId, userId)
 
-	if len(users) == 0 {
-		return fmt.Errorf("could not find user [%s]", userId)
+	// Joining again must not reset the role of an existing member
+	err := ddbproxy.CreateItemWithCondition(ctx, r.membersTableName, member, ddbproxy.NotExistsCondition("userId"))
+	if err != nil && !errors.Is(err, proxy.ErrConditionFailed) {
+		return err
 	}
 
-	user := users[0]
+	return joinHousehold(ctx, r.users, userId, householdId)
+}
+
+func (r *DynamoHouseholdRepository) LeaveHousehold(ctx context.Context, userId string, householdId string) error {
+	// A member whose user record is gone still has to be taken out of the members index
+	if err := leaveHousehold(ctx, r.users, userId, householdId); err != nil && !errors.Is(err, proxy.ErrNotFound) {
+		return err
+	}
 
-	newHouseholdIds := make([]string, 0)
+	return ddbproxy.DeleteItem(ctx, r.membersTableName, memberKey(householdId, userId))
+}
 
-	for _, householdId := range user.HouseholdIds {
-		if householdId != householdIdToRemove {
-			newHouseholdIds = append(newHouseholdIds, householdId)
+func (r *DynamoHouseholdRepository) GetMembers(ctx context.Context, householdId string) ([]models.HouseholdMember, error) {
+	hashKeyAttributeValues := map[string]types.AttributeValue{
+		":hId": &types.AttributeValueMemberS{Value: householdId},
+	}
+
+	return ddbproxy.QueryTable[models.HouseholdMember](ctx, r.membersTableName, "householdId = :hId", hashKeyAttributeValues)
+}
+
+func (r *DynamoHouseholdRepository) GetMember(ctx context.Context, householdId string, userId string) (models.HouseholdMember, error) {
+	return ddbproxy.GetItem[models.HouseholdMember](ctx, r.membersTableName, memberKey(householdId, userId))
+}
+
+func (r *DynamoHouseholdRepository) SetMemberRole(ctx context.Context, householdId string, userId string, role models.HouseholdRole) error {
+	update := r.memberRoleUpdate(householdId, userId, role)
+
+	err := ddbproxy.UpdateItem(ctx, update.TableName, update.Key, update.Record, nil, update.Condition)
+	if errors.Is(err, proxy.ErrConditionFailed) {
+		return fmt.Errorf("could not find member [%s] of household [%s]: %w", userId, householdId, proxy.ErrNotFound)
+	}
+
+	return err
+}
+
+func (r *DynamoHouseholdRepository) memberRoleUpdate(householdId string, userId string, role models.HouseholdRole) ddbproxy.Update {
+	record := struct {
+		Role models.HouseholdRole `dynamodbav:"role"`
+	}{Role: role}
+
+	return ddbproxy.Update{
+		TableName: r.membersTableName,
+		Key:       memberKey(householdId, userId),
+		Record:    record,
+		// Only update members that exist, otherwise the update would create a partial entry
+		Condition: &ddbproxy.Condition{
+			Expression: "attribute_exists(#memberUserId)",
+			Names:      map[string]string{"#memberUserId": "userId"},
+		},
+	}
+}
+
+// householdActiveCondition only lets a write through to households that exist and aren't being deleted
+func householdActiveCondition() *ddbproxy.Condition {
+	return &ddbproxy.Condition{
+		Expression: "attribute_exists(#householdId) AND attribute_not_exists(#householdDeletionStatus)",
+		Names:      map[string]string{"#householdId": "id", "#householdDeletionStatus": "deletionStatus"},
+	}
+}
+
+func householdKey(householdId string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"id": &types.AttributeValueMemberS{Value: householdId},
+	}
+}
+
+func memberKey(householdId string, userId string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"householdId": &types.AttributeValueMemberS{Value: householdId},
+		"userId":      &types.AttributeValueMemberS{Value: userId},
+	}
+}
+
+func newHousehold(ownerId string, name string) models.Household {
+	return models.Household{
+		Id:        uuid.NewString(),
+		Name:      name,
+		CreatedAt: time.Now().Unix(),
+		OwnerId:   ownerId,
+	}
+}
+
+func newHouseholdMember(householdId string, userId string) models.HouseholdMember {
+	return models.HouseholdMember{
+		HouseholdId: householdId,
+		UserId:      userId,
+		Role:        models.MemberRole,
+		JoinedAt:    time.Now().Unix(),
+	}
+}
+
+// joinHousehold adds the household to the user's households, the first household joined becomes their default
+func joinHousehold(ctx context.Context, users UserRepository, userId string, householdId string) error {
+	_, err := updateUser(ctx, users, userId, func(user *models.User) (bool, error) {
+		if slices.Contains(user.HouseholdIds, householdId) {
+			return false, nil
+		}
+
+		user.HouseholdIds = append(user.HouseholdIds, householdId)
+		if user.DefaultHouseholdId == "" {
+			user.DefaultHouseholdId = user.DefaultHousehold()
+		}
+
+		return true, nil
+	})
+
+	return err
+}
+
+// leaveHousehold removes the household from the user's households, picking a new default if it was theirs
+func leaveHousehold(ctx context.Context, users UserRepository, userId string, householdIdToRemove string) error {
+	_, err := updateUser(ctx, users, userId, func(user *models.User) (bool, error) {
+		if !slices.Contains(user.HouseholdIds, householdIdToRemove) {
+			return false, nil
+		}
+
+		newHouseholdIds := make([]string, 0, len(user.HouseholdIds))
+
+		for _, householdId := range user.HouseholdIds {
+			if householdId != householdIdToRemove {
+				newHouseholdIds = append(newHouseholdIds, householdId)
+			}
+		}
+
+		user.HouseholdIds = newHouseholdIds
+		if user.DefaultHouseholdId == householdIdToRemove {
+			user.DefaultHouseholdId = ""
+		}
+		user.DefaultHouseholdId = user.DefaultHousehold()
+
+		return true, nil
+	})
+
+	return err
+}
+
+// GetHouseholdUserIds returns everyone in the household, whether they are in the members index or
+// only have the household on their user, as those who joined before the index existed do
+func GetHouseholdUserIds(ctx context.Context, households HouseholdRepository, users UserRepository, householdId string) ([]string, error) {
+	members, err := households.GetMembers(ctx, householdId)
+	if err != nil {
+		return nil, err
+	}
+
+	householdUsers, err := users.GetHouseholdUsers(ctx, householdId)
+	if err != nil {
+		return nil, err
+	}
+
+	userIds := make([]string, 0, len(members)+len(householdUsers))
+	for _, member := range members {
+		userIds = append(userIds, member.UserId)
+	}
+	for _, user := range householdUsers {
+		if !slices.Contains(userIds, user.Id) {
+			userIds = append(userIds, user.Id)
 		}
 	}
 
-	user.HouseholdIds = newHouseholdIds
-	return UpdateUser(user)
+	return userIds, nil
 }
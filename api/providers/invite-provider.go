@@ -0,0 +1,174 @@
+package providers
+
+import (
+	"api/models"
+	"api/proxy"
+	ddbproxy "api/proxy/ddb"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+var ErrInviteExpired = errors.New("invite has expired")
+var ErrInviteUsedUp = errors.New("invite has been used up")
+
+// InviteRepository stores the invite codes used to join households
+type InviteRepository interface {
+	// CreateInvite stores invite under a freshly generated code and returns it
+	CreateInvite(ctx context.Context, invite models.HouseholdInvite) (models.HouseholdInvite, error)
+	GetInvite(ctx context.Context, code string) (models.HouseholdInvite, error)
+	GetHouseholdInvites(ctx context.Context, householdId string) ([]models.HouseholdInvite, error)
+	// RedeemInvite uses up one use of the invite, failing with ErrInviteExpired or ErrInviteUsedUp
+	// when it can no longer be redeemed
+	RedeemInvite(ctx context.Context, code string) (models.HouseholdInvite, error)
+	// ReleaseInvite gives back a use of a redeemed invite the caller couldn't join with,
+	// invites deleted since are left alone
+	ReleaseInvite(ctx context.Context, code string) error
+	DeleteInvite(ctx context.Context, code string) error
+}
+
+// maxInviteCodeAttempts bounds the retries when a generated code is already taken
+const maxInviteCodeAttempts = 5
+
+// maxRedeemAttempts bounds the retries when the invite is redeemed concurrently
+const maxRedeemAttempts = 5
+
+func checkInvite(invite models.HouseholdInvite, now time.Time) error {
+	if now.Unix() >= invite.ExpiresAt {
+		return ErrInviteExpired
+	}
+
+	if invite.Uses >= invite.MaxUses {
+		return ErrInviteUsedUp
+	}
+
+	return nil
+}
+
+// DynamoInviteRepository is an InviteRepository backed by the HouseholdInvites table,
+// keyed by code with a householdId index for listing a household's invites
+type DynamoInviteRepository struct {
+	tableName string
+}
+
+const invitesHouseholdIndex = "householdId-index"
+
+func NewDynamoInviteRepository(tableName string) *DynamoInviteRepository {
+	return &DynamoInviteRepository{tableName: tableName}
+}
+
+func (r *DynamoInviteRepository) CreateInvite(ctx context.Context, invite models.HouseholdInvite) (models.HouseholdInvite, error) {
+	for attempt := 0; attempt < maxInviteCodeAttempts; attempt++ {
+		code, err := newShortCode()
+		if err != nil {
+			return models.HouseholdInvite{}, err
+		}
+		invite.Code = code
+
+		err = ddbproxy.CreateItemWithCondition(ctx, r.tableName, invite, ddbproxy.NotExistsCondition("code"))
+		if errors.Is(err, proxy.ErrConditionFailed) {
+			continue
+		}
+
+		return invite, err
+	}
+
+	return models.HouseholdInvite{}, fmt.Errorf("unable to find an unused invite code after %d attempts", maxInviteCodeAttempts)
+}
+
+func (r *DynamoInviteRepository) GetInvite(ctx context.Context, code string) (models.HouseholdInvite, error) {
+	key := map[string]types.AttributeValue{
+		"code": &types.AttributeValueMemberS{Value: code},
+	}
+
+	return ddbproxy.GetItem[models.HouseholdInvite](ctx, r.tableName, key)
+}
+
+func (r *DynamoInviteRepository) GetHouseholdInvites(ctx context.Context, householdId string) ([]models.HouseholdInvite, error) {
+	hashKeyAttributeValues := map[string]types.AttributeValue{
+		":hId": &types.AttributeValueMemberS{Value: householdId},
+	}
+
+	return ddbproxy.QueryIndex[models.HouseholdInvite](ctx, r.tableName, invitesHouseholdIndex, "householdId = :hId", hashKeyAttributeValues)
+}
+
+func (r *DynamoInviteRepository) RedeemInvite(ctx context.Context, code string) (models.HouseholdInvite, error) {
+	key := map[string]types.AttributeValue{
+		"code": &types.AttributeValueMemberS{Value: code},
+	}
+
+	for attempt := 0; attempt < maxRedeemAttempts; attempt++ {
+		invite, err := r.GetInvite(ctx, code)
+		if err != nil {
+			return models.HouseholdInvite{}, err
+		}
+
+		if err := checkInvite(invite, time.Now()); err != nil {
+			return models.HouseholdInvite{}, err
+		}
+
+		// Only count the use if nobody else redeemed or revoked the invite since it was read
+		condition := &ddbproxy.Condition{
+			Expression: "attribute_exists(#inviteCode) AND #inviteUses = :inviteUses",
+			Names:      map[string]string{"#inviteCode": "code", "#inviteUses": "uses"},
+			Values: map[string]types.AttributeValue{
+				":inviteUses": &types.AttributeValueMemberN{Value: fmt.Sprint(invite.Uses)},
+			},
+		}
+		invite.Uses++
+
+		err = ddbproxy.UpdateItem(ctx, r.tableName, key, invite, []string{"code"}, condition)
+		if errors.Is(err, proxy.ErrConditionFailed) {
+			continue
+		}
+
+		return invite, err
+	}
+
+	return models.HouseholdInvite{}, fmt.Errorf("invite is being redeemed by others: %w", proxy.ErrConditionFailed)
+}
+
+func (r *DynamoInviteRepository) ReleaseInvite(ctx context.Context, code string) error {
+	key := map[string]types.AttributeValue{
+		"code": &types.AttributeValueMemberS{Value: code},
+	}
+
+	for attempt := 0; attempt < maxRedeemAttempts; attempt++ {
+		invite, err := r.GetInvite(ctx, code)
+		if errors.Is(err, proxy.ErrNotFound) || (err == nil && invite.Uses == 0) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		condition := &ddbproxy.Condition{
+			Expression: "attribute_exists(#inviteCode) AND #inviteUses = :inviteUses",
+			Names:      map[string]string{"#inviteCode": "code", "#inviteUses": "uses"},
+			Values: map[string]types.AttributeValue{
+				":inviteUses": &types.AttributeValueMemberN{Value: fmt.Sprint(invite.Uses)},
+			},
+		}
+		invite.Uses--
+
+		err = ddbproxy.UpdateItem(ctx, r.tableName, key, invite, []string{"code"}, condition)
+		if errors.Is(err, proxy.ErrConditionFailed) {
+			continue
+		}
+
+		return err
+	}
+
+	return fmt.Errorf("invite is being redeemed by others: %w", proxy.ErrConditionFailed)
+}
+
+func (r *DynamoInviteRepository) DeleteInvite(ctx context.Context, code string) error {
+	key := map[string]types.AttributeValue{
+		"code": &types.AttributeValueMemberS{Value: code},
+	}
+
+	return ddbproxy.DeleteItem(ctx, r.tableName, key)
+}
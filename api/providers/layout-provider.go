@@ -0,0 +1,69 @@
+package providers
+
+import (
+	"api/models"
+	"api/proxy"
+	ddbproxy "api/proxy/ddb"
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// LayoutRepository stores how households arranged their lists
+type LayoutRepository interface {
+	// GetLayout returns an empty layout at version 0 for lists that were never arranged
+	GetLayout(ctx context.Context, householdId string, listId string) (models.GroceryLayout, error)
+	// SaveLayout stores the layout if its version matches the stored one and returns it with the
+	// version bumped, otherwise it fails with proxy.ErrConditionFailed
+	SaveLayout(ctx context.Context, layout models.GroceryLayout) (models.GroceryLayout, error)
+	DeleteLayout(ctx context.Context, householdId string, listId string) error
+}
+
+// DynamoLayoutRepository is a LayoutRepository backed by the GroceryLayouts table
+type DynamoLayoutRepository struct {
+	tableName string
+}
+
+func NewDynamoLayoutRepository(tableName string) *DynamoLayoutRepository {
+	return &DynamoLayoutRepository{tableName: tableName}
+}
+
+func (r *DynamoLayoutRepository) GetLayout(ctx context.Context, householdId string, listId string) (models.GroceryLayout, error) {
+	key := map[string]types.AttributeValue{
+		"householdId": &types.AttributeValueMemberS{Value: householdId},
+		"listId":      &types.AttributeValueMemberS{Value: listId},
+	}
+
+	layout, err := ddbproxy.GetItem[models.GroceryLayout](ctx, r.tableName, key)
+	if errors.Is(err, proxy.ErrNotFound) {
+		return models.GroceryLayout{HouseholdId: householdId, ListId: listId, Blocks: []models.LayoutBlock{}}, nil
+	}
+
+	return layout, err
+}
+
+func (r *DynamoLayoutRepository) SaveLayout(ctx context.Context, layout models.GroceryLayout) (models.GroceryLayout, error) {
+	key := map[string]types.AttributeValue{
+		"householdId": &types.AttributeValueMemberS{Value: layout.HouseholdId},
+		"listId":      &types.AttributeValueMemberS{Value: layout.ListId},
+	}
+
+	condition := ddbproxy.VersionCondition("version", layout.Version)
+	layout.Version++
+
+	if err := ddbproxy.UpdateItem(ctx, r.tableName, key, layout, []string{"householdId", "listId"}, condition); err != nil {
+		return models.GroceryLayout{}, err
+	}
+
+	return layout, nil
+}
+
+func (r *DynamoLayoutRepository) DeleteLayout(ctx context.Context, householdId string, listId string) error {
+	key := map[string]types.AttributeValue{
+		"householdId": &types.AttributeValueMemberS{Value: householdId},
+		"listId":      &types.AttributeValueMemberS{Value: listId},
+	}
+
+	return ddbproxy.DeleteItem(ctx, r.tableName, key)
+}
@@ -0,0 +1,121 @@
+package providers
+
+import (
+	"api/models"
+	"api/proxy"
+	ddbproxy "api/proxy/ddb"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+var ErrListArchived = fmt.Errorf("list is archived: %w", proxy.ErrConditionFailed)
+
+// ListRepository stores the named lists of households. Every household has the default list, it is
+// only stored once it has been renamed.
+type ListRepository interface {
+	// GetLists returns the household's lists, the default list first and the others in the order
+	// they were created
+	GetLists(ctx context.Context, householdId string) ([]models.HouseholdList, error)
+	// GetList fails with proxy.ErrNotFound when the household has no such list
+	GetList(ctx context.Context, householdId string, listId string) (models.HouseholdList, error)
+	CreateList(ctx context.Context, list models.HouseholdList) error
+	// UpdateList stores the name and archived flag of the list, failing with proxy.ErrNotFound
+	// when the list doesn't exist
+	UpdateList(ctx context.Context, list models.HouseholdList) error
+	DeleteList(ctx context.Context, householdId string, listId string) error
+}
+
+// DynamoListRepository is a ListRepository backed by the GroceryLists table
+type DynamoListRepository struct {
+	tableName string
+}
+
+func NewDynamoListRepository(tableName string) *DynamoListRepository {
+	return &DynamoListRepository{tableName: tableName}
+}
+
+func (r *DynamoListRepository) GetLists(ctx context.Context, householdId string) ([]models.HouseholdList, error) {
+	hashKeyAttributeValues := map[string]types.AttributeValue{
+		":hId": &types.AttributeValueMemberS{Value: householdId},
+	}
+
+	lists, err := ddbproxy.QueryTable[models.HouseholdList](ctx, r.tableName, "householdId = :hId", hashKeyAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	return sortLists(householdId, lists), nil
+}
+
+func (r *DynamoListRepository) GetList(ctx context.Context, householdId string, listId string) (models.HouseholdList, error) {
+	key := map[string]types.AttributeValue{
+		"householdId": &types.AttributeValueMemberS{Value: householdId},
+		"id":          &types.AttributeValueMemberS{Value: listId},
+	}
+
+	list, err := ddbproxy.GetItem[models.HouseholdList](ctx, r.tableName, key)
+	if errors.Is(err, proxy.ErrNotFound) && listId == models.DefaultListId {
+		return defaultList(householdId), nil
+	}
+
+	return list, err
+}
+
+func (r *DynamoListRepository) CreateList(ctx context.Context, list models.HouseholdList) error {
+	return ddbproxy.CreateItemWithCondition(ctx, r.tableName, list, ddbproxy.NotExistsCondition("id"))
+}
+
+func (r *DynamoListRepository) UpdateList(ctx context.Context, list models.HouseholdList) error {
+	key := map[string]types.AttributeValue{
+		"householdId": &types.AttributeValueMemberS{Value: list.HouseholdId},
+		"id":          &types.AttributeValueMemberS{Value: list.Id},
+	}
+
+	// The default list is stored by its first update, other lists must not come back once deleted
+	var condition *ddbproxy.Condition
+	if list.Id != models.DefaultListId {
+		condition = ddbproxy.ExistsCondition("id")
+	}
+
+	err := ddbproxy.UpdateItem(ctx, r.tableName, key, list, []string{"householdId", "id"}, condition)
+	if errors.Is(err, proxy.ErrConditionFailed) {
+		return fmt.Errorf("could not find list [%s]: %w", list.Id, proxy.ErrNotFound)
+	}
+
+	return err
+}
+
+func (r *DynamoListRepository) DeleteList(ctx context.Context, householdId string, listId string) error {
+	key := map[string]types.AttributeValue{
+		"householdId": &types.AttributeValueMemberS{Value: householdId},
+		"id":          &types.AttributeValueMemberS{Value: listId},
+	}
+
+	return ddbproxy.DeleteItem(ctx, r.tableName, key)
+}
+
+func defaultList(householdId string) models.HouseholdList {
+	return models.HouseholdList{HouseholdId: householdId, Id: models.DefaultListId, Name: models.DefaultListName}
+}
+
+// sortLists puts the default list first, adding it when it was never stored, and the others in
+// the order they were created
+func sortLists(householdId string, lists []models.HouseholdList) []models.HouseholdList {
+	sort.SliceStable(lists, func(i, j int) bool {
+		if lists[i].Id == models.DefaultListId || lists[j].Id == models.DefaultListId {
+			return lists[i].Id == models.DefaultListId
+		}
+
+		return lists[i].CreatedAt < lists[j].CreatedAt
+	})
+
+	if len(lists) == 0 || lists[0].Id != models.DefaultListId {
+		lists = append([]models.HouseholdList{defaultList(householdId)}, lists...)
+	}
+
+	return lists
+}
@@ -0,0 +1,111 @@
+package providers
+
+import (
+	"api/models"
+	"api/proxy"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryDeviceRepository is a DeviceRepository that keeps everything in process
+type MemoryDeviceRepository struct {
+	mu       sync.Mutex
+	devices  map[string]models.Device
+	pairings map[string]models.PairingCode
+}
+
+func NewMemoryDeviceRepository() *MemoryDeviceRepository {
+	return &MemoryDeviceRepository{
+		devices:  make(map[string]models.Device),
+		pairings: make(map[string]models.PairingCode),
+	}
+}
+
+func (r *MemoryDeviceRepository) GetDevice(ctx context.Context, id string) (models.Device, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	device, ok := r.devices[id]
+	if !ok {
+		return models.Device{}, fmt.Errorf("could not find device [%s]: %w", id, proxy.ErrNotFound)
+	}
+
+	return device, nil
+}
+
+func (r *MemoryDeviceRepository) UpdateDevice(ctx context.Context, device models.Device) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.devices[device.Id] = device
+	return nil
+}
+
+func (r *MemoryDeviceRepository) GetUserDevices(ctx context.Context, userId string) ([]models.Device, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	devices := make([]models.Device, 0)
+	for _, device := range r.devices {
+		if device.UserId == userId {
+			devices = append(devices, device)
+		}
+	}
+
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].LinkedAt < devices[j].LinkedAt
+	})
+
+	return devices, nil
+}
+
+func (r *MemoryDeviceRepository) DeleteDevice(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.devices, id)
+	return nil
+}
+
+func (r *MemoryDeviceRepository) CreatePairingCode(ctx context.Context, pairing models.PairingCode) (models.PairingCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for attempt := 0; attempt < maxInviteCodeAttempts; attempt++ {
+		code, err := newShortCode()
+		if err != nil {
+			return models.PairingCode{}, err
+		}
+
+		if _, taken := r.pairings[code]; taken {
+			continue
+		}
+
+		pairing.Code = code
+		r.pairings[code] = pairing
+
+		return pairing, nil
+	}
+
+	return models.PairingCode{}, fmt.Errorf("unable to find an unused pairing code after %d attempts", maxInviteCodeAttempts)
+}
+
+func (r *MemoryDeviceRepository) RedeemPairingCode(ctx context.Context, code string) (models.PairingCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pairing, ok := r.pairings[code]
+	if !ok {
+		return models.PairingCode{}, fmt.Errorf("could not find pairing code [%s]: %w", code, proxy.ErrNotFound)
+	}
+
+	if time.Now().Unix() >= pairing.ExpiresAt {
+		return models.PairingCode{}, ErrPairingCodeExpired
+	}
+
+	delete(r.pairings, code)
+	return pairing, nil
+}
@@ -0,0 +1,201 @@
+package providers
+
+import (
+	"api/models"
+	"api/proxy"
+	ddbproxy "api/proxy/ddb"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// MemoryGroceryRepository is a GroceryRepository that keeps everything in process,
+// used for running the router locally and in tests without AWS credentials.
+// Items are returned ordered by id, matching the sort key of the Groceries table.
+// Tombstones are kept around for good, there is no TTL to remove them.
+type MemoryGroceryRepository struct {
+	mu sync.RWMutex
+	// householdId -> id -> grocery item or tombstone
+	items map[string]map[string]models.GroceryItem
+}
+
+func NewMemoryGroceryRepository() *MemoryGroceryRepository {
+	return &MemoryGroceryRepository{
+		items: make(map[string]map[string]models.GroceryItem),
+	}
+}
+
+func (r *MemoryGroceryRepository) GetGroceryItems(ctx context.Context, householdId string) ([]models.GroceryItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return withoutTombstones(r.sortedItems(householdId)), nil
+}
+
+func (r *MemoryGroceryRepository) GetGroceryItem(ctx context.Context, householdId string, groceryItemId string) (models.GroceryItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	groceryItem, ok := r.items[householdId][groceryItemId]
+	if !ok {
+		return models.GroceryItem{}, fmt.Errorf("could not find grocery item [%s]: %w", groceryItemId, proxy.ErrNotFound)
+	}
+	if groceryItem.Deleted {
+		return models.GroceryItem{}, fmt.Errorf("grocery item [%s]: %w", groceryItemId, ErrGroceryItemDeleted)
+	}
+
+	return groceryItem, nil
+}
+
+func (r *MemoryGroceryRepository) GetGroceryItemsPage(ctx context.Context, householdId string, limit int32, cursor string) ([]models.GroceryItem, string, error) {
+	startKey, err := ddbproxy.DecodePartitionCursor(cursor, "householdId", householdId, "id")
+	if err != nil {
+		return nil, "", err
+	}
+
+	startId := ""
+	if startKey != nil {
+		startId = startKey["id"].(*types.AttributeValueMemberS).Value
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	groceryItems := r.sortedItems(householdId)
+	start := sort.Search(len(groceryItems), func(i int) bool {
+		return strings.Compare(groceryItems[i].Id, startId) > 0
+	})
+	groceryItems = groceryItems[start:]
+
+	// Like DynamoDB, the limit counts tombstones that are dropped from the page
+	if int32(len(groceryItems)) <= limit {
+		return withoutTombstones(groceryItems), "", nil
+	}
+
+	groceryItems = groceryItems[:limit]
+	last := groceryItems[len(groceryItems)-1]
+	nextCursor, err := ddbproxy.EncodeCursor(map[string]types.AttributeValue{
+		"householdId": &types.AttributeValueMemberS{Value: last.HouseholdId},
+		"id":          &types.AttributeValueMemberS{Value: last.Id},
+	})
+
+	return withoutTombstones(groceryItems), nextCursor, err
+}
+
+func (r *MemoryGroceryRepository) GetGroceryItemChanges(ctx context.Context, householdId string, since time.Time) ([]models.GroceryItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	changes := make([]models.GroceryItem, 0)
+	for _, groceryItem := range r.items[householdId] {
+		if groceryItem.UpdatedAt >= since.UnixMilli() {
+			changes = append(changes, groceryItem)
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].UpdatedAt < changes[j].UpdatedAt
+	})
+
+	return changes, nil
+}
+
+func (r *MemoryGroceryRepository) CreateGroceryItem(ctx context.Context, groceryItem models.GroceryItem) (models.GroceryItem, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// PutItem replaces an existing item with the same key
+	created(&groceryItem, time.Now())
+	r.put(groceryItem)
+
+	return groceryItem, nil
+}
+
+func (r *MemoryGroceryRepository) UpdateGroceryItem(ctx context.Context, groceryItem models.GroceryItem) (models.GroceryItem, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Mirrors the conditions of DynamoGroceryRepository.UpdateGroceryItem
+	current, exists := r.items[groceryItem.HouseholdId][groceryItem.Id]
+	if !exists || current.Deleted {
+		return models.GroceryItem{}, &GroceryItemConflictError{}
+	}
+	if current.Version != groceryItem.Version {
+		return models.GroceryItem{}, &GroceryItemConflictError{Current: &current}
+	}
+
+	// Like UpdateItem, attributes that are left out keep their value
+	if groceryItem.ListId == "" {
+		groceryItem.ListId = current.ListId
+	}
+	if groceryItem.Text == "" {
+		groceryItem.Text = current.Text
+	}
+
+	groceryItem.Version++
+	touch(&groceryItem, time.Now())
+	r.put(groceryItem)
+
+	return groceryItem, nil
+}
+
+func (r *MemoryGroceryRepository) DeleteGroceryItem(ctx context.Context, householdId string, groceryItemId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.items[householdId][groceryItemId]; exists {
+		r.put(tombstone(householdId, groceryItemId, time.Now()))
+	}
+
+	return nil
+}
+
+func (r *MemoryGroceryRepository) BatchCreateGroceryItems(ctx context.Context, groceryItems []models.GroceryItem) ([]models.GroceryItem, error) {
+	groceryItems = uniqueGroceryItems(groceryItems)
+
+	stored := make([]models.GroceryItem, 0, len(groceryItems))
+	for _, groceryItem := range groceryItems {
+		groceryItem, _ = r.CreateGroceryItem(ctx, groceryItem)
+		stored = append(stored, groceryItem)
+	}
+
+	return stored, nil
+}
+
+func (r *MemoryGroceryRepository) BatchDeleteGroceryItems(ctx context.Context, groceryItems []models.GroceryItem) ([]models.GroceryItem, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, groceryItem := range groceryItems {
+		r.put(tombstone(groceryItem.HouseholdId, groceryItem.Id, now))
+	}
+
+	return []models.GroceryItem{}, nil
+}
+
+func (r *MemoryGroceryRepository) put(groceryItem models.GroceryItem) {
+	if r.items[groceryItem.HouseholdId] == nil {
+		r.items[groceryItem.HouseholdId] = make(map[string]models.GroceryItem)
+	}
+
+	r.items[groceryItem.HouseholdId][groceryItem.Id] = groceryItem
+}
+
+func (r *MemoryGroceryRepository) sortedItems(householdId string) []models.GroceryItem {
+	groceryItems := make([]models.GroceryItem, 0, len(r.items[householdId]))
+	for _, groceryItem := range r.items[householdId] {
+		groceryItems = append(groceryItems, groceryItem)
+	}
+
+	sort.Slice(groceryItems, func(i, j int) bool {
+		return groceryItems[i].Id < groceryItems[j].Id
+	})
+
+	return groceryItems
+}
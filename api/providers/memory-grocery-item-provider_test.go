@@ -0,0 +1,203 @@
+package providers
+
+import (
+	"api/models"
+	"api/proxy"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestMemoryGroceryRepository(t *testing.T) {
+	tests := []struct {
+		name string
+		// run gets milk at version 1 and returns the error of its last step
+		run     func(ctx context.Context, repository *MemoryGroceryRepository, milk models.GroceryItem) error
+		wantErr error
+		// wantMilk is the name of milk afterwards, empty when it is gone
+		wantMilk string
+	}{
+		{
+			name: "get a missing item",
+			run: func(ctx context.Context, repository *MemoryGroceryRepository, milk models.GroceryItem) error {
+				_, err := repository.GetGroceryItem(ctx, "h1", "bread")
+				return err
+			},
+			wantErr:  proxy.ErrNotFound,
+			wantMilk: "milk",
+		},
+		{
+			name: "update",
+			run: func(ctx context.Context, repository *MemoryGroceryRepository, milk models.GroceryItem) error {
+				milk.Name = "oat milk"
+				updated, err := repository.UpdateGroceryItem(ctx, milk)
+				if err == nil && updated.Version != 2 {
+					return fmt.Errorf("version = %d, want 2", updated.Version)
+				}
+				return err
+			},
+			wantMilk: "oat milk",
+		},
+		{
+			name: "update a stale version",
+			run: func(ctx context.Context, repository *MemoryGroceryRepository, milk models.GroceryItem) error {
+				milk.Version = 0
+				milk.Name = "oat milk"
+				_, err := repository.UpdateGroceryItem(ctx, milk)
+
+				var conflict *GroceryItemConflictError
+				if errors.As(err, &conflict) && (conflict.Current == nil || conflict.Current.Name != "milk") {
+					return fmt.Errorf("conflict = %+v, want the stored item", conflict.Current)
+				}
+				return err
+			},
+			wantErr:  &GroceryItemConflictError{},
+			wantMilk: "milk",
+		},
+		{
+			name: "update a missing item",
+			run: func(ctx context.Context, repository *MemoryGroceryRepository, milk models.GroceryItem) error {
+				milk.Id = "bread"
+				_, err := repository.UpdateGroceryItem(ctx, milk)
+				return err
+			},
+			wantErr:  proxy.ErrNotFound,
+			wantMilk: "milk",
+		},
+		{
+			name: "delete",
+			run: func(ctx context.Context, repository *MemoryGroceryRepository, milk models.GroceryItem) error {
+				if err := repository.DeleteGroceryItem(ctx, "h1", milk.Id); err != nil {
+					return err
+				}
+				_, err := repository.GetGroceryItem(ctx, "h1", milk.Id)
+				return err
+			},
+			wantErr: ErrGroceryItemDeleted,
+		},
+		{
+			name: "update a deleted item",
+			run: func(ctx context.Context, repository *MemoryGroceryRepository, milk models.GroceryItem) error {
+				if err := repository.DeleteGroceryItem(ctx, "h1", milk.Id); err != nil {
+					return err
+				}
+				_, err := repository.UpdateGroceryItem(ctx, milk)
+				return err
+			},
+			wantErr: proxy.ErrNotFound,
+		},
+		{
+			name: "batch delete",
+			run: func(ctx context.Context, repository *MemoryGroceryRepository, milk models.GroceryItem) error {
+				failed, err := repository.BatchDeleteGroceryItems(ctx, []models.GroceryItem{milk})
+				if err == nil && len(failed) != 0 {
+					return fmt.Errorf("failed = %v", failed)
+				}
+				return err
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			repository := NewMemoryGroceryRepository()
+
+			milk, err := repository.CreateGroceryItem(ctx, models.GroceryItem{HouseholdId: "h1", Id: "milk", Name: "milk"})
+			if err != nil || milk.Version != 1 || milk.CreatedAt == 0 {
+				t.Fatalf("CreateGroceryItem() = %+v, %v", milk, err)
+			}
+
+			err = test.run(ctx, repository, milk)
+			var conflict *GroceryItemConflictError
+			switch {
+			case test.wantErr == nil && err != nil:
+				t.Fatalf("error = %v", err)
+			case errors.As(test.wantErr, &conflict):
+				if !errors.As(err, &conflict) {
+					t.Fatalf("error = %v, want a conflict", err)
+				}
+			case test.wantErr != nil && !errors.Is(err, test.wantErr):
+				t.Fatalf("error = %v, want %v", err, test.wantErr)
+			}
+
+			groceryItems, err := repository.GetGroceryItems(ctx, "h1")
+			if err != nil {
+				t.Fatalf("GetGroceryItems() error = %v", err)
+			}
+
+			name := ""
+			for _, groceryItem := range groceryItems {
+				if groceryItem.Id == "milk" {
+					name = groceryItem.Name
+				}
+			}
+			if name != test.wantMilk {
+				t.Errorf("milk is named %q, want %q", name, test.wantMilk)
+			}
+		})
+	}
+}
+
+func TestMemoryGroceryRepositoryPages(t *testing.T) {
+	ctx := context.Background()
+	repository := NewMemoryGroceryRepository()
+
+	for _, householdId := range []string{"h1", "h2"} {
+		for i := 0; i < 5; i++ {
+			groceryItem := models.GroceryItem{HouseholdId: householdId, Id: fmt.Sprintf("item-%d", i), Name: "milk"}
+			if _, err := repository.CreateGroceryItem(ctx, groceryItem); err != nil {
+				t.Fatalf("CreateGroceryItem() error = %v", err)
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatalf("paging didn't end, cursor %q", cursor)
+		}
+
+		page, next, err := repository.GetGroceryItemsPage(ctx, "h1", 2, cursor)
+		if err != nil {
+			t.Fatalf("GetGroceryItemsPage(%q) error = %v", cursor, err)
+		}
+		for _, groceryItem := range page {
+			if groceryItem.HouseholdId != "h1" || seen[groceryItem.Id] {
+				t.Fatalf("GetGroceryItemsPage(%q) returned %+v again or from another household", cursor, groceryItem)
+			}
+			seen[groceryItem.Id] = true
+		}
+
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if len(seen) != 5 {
+		t.Errorf("paged through %d items, want 5", len(seen))
+	}
+
+	_, h2Cursor, err := repository.GetGroceryItemsPage(ctx, "h2", 2, "")
+	if err != nil || h2Cursor == "" {
+		t.Fatalf("GetGroceryItemsPage(h2) cursor = %q, error = %v", h2Cursor, err)
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "other household", cursor: h2Cursor},
+		{name: "garbage", cursor: "zzz"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, err := repository.GetGroceryItemsPage(ctx, "h1", 2, test.cursor); !errors.Is(err, proxy.ErrInvalidCursor) {
+				t.Errorf("GetGroceryItemsPage(%q) error = %v, want ErrInvalidCursor", test.cursor, err)
+			}
+		})
+	}
+}
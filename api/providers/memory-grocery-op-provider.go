@@ -0,0 +1,47 @@
+package providers
+
+import (
+	"api/models"
+	"api/proxy"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryGroceryOpRepository is a GroceryOpRepository that keeps everything in process
+type MemoryGroceryOpRepository struct {
+	mu sync.Mutex
+	// householdId -> opId -> result
+	results map[string]map[string]models.GroceryOpResult
+}
+
+func NewMemoryGroceryOpRepository() *MemoryGroceryOpRepository {
+	return &MemoryGroceryOpRepository{
+		results: make(map[string]map[string]models.GroceryOpResult),
+	}
+}
+
+func (r *MemoryGroceryOpRepository) GetOpResult(ctx context.Context, householdId string, opId string) (models.GroceryOpResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result, ok := r.results[householdId][opId]
+	if !ok || result.ExpiresAt < time.Now().Unix() {
+		return models.GroceryOpResult{}, fmt.Errorf("could not find result of operation [%s]: %w", opId, proxy.ErrNotFound)
+	}
+
+	return result, nil
+}
+
+func (r *MemoryGroceryOpRepository) SaveOpResult(ctx context.Context, result models.GroceryOpResult) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.results[result.HouseholdId] == nil {
+		r.results[result.HouseholdId] = make(map[string]models.GroceryOpResult)
+	}
+
+	r.results[result.HouseholdId][result.OpId] = result
+	return nil
+}
@@ -0,0 +1,205 @@
+package providers
+
+import (
+	"api/models"
+	"api/proxy"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryHouseholdRepository is a HouseholdRepository that keeps everything in process
+type MemoryHouseholdRepository struct {
+	mu         sync.RWMutex
+	households map[string]models.Household
+	members    map[string]map[string]models.HouseholdMember
+	users      UserRepository
+}
+
+func NewMemoryHouseholdRepository(users UserRepository) *MemoryHouseholdRepository {
+	return &MemoryHouseholdRepository{
+		households: make(map[string]models.Household),
+		members:    make(map[string]map[string]models.HouseholdMember),
+		users:      users,
+	}
+}
+
+func (r *MemoryHouseholdRepository) CreateHousehold(ctx context.Context, ownerId string, name string) (models.Household, error) {
+	household := newHousehold(ownerId, name)
+
+	r.mu.Lock()
+	r.households[household.Id] = household
+	r.mu.Unlock()
+
+	return household, r.JoinHousehold(ctx, ownerId, household.Id)
+}
+
+func (r *MemoryHouseholdRepository) GetHousehold(ctx context.Context, householdId string) (models.Household, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.get(householdId)
+}
+
+func (r *MemoryHouseholdRepository) MarkHouseholdForDeletion(ctx context.Context, householdId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	household, err := r.get(householdId)
+	if err != nil {
+		return err
+	}
+
+	household.DeletionStatus = models.DeletionPending
+	household.DeletedAt = time.Now().Unix()
+	r.households[householdId] = household
+
+	return nil
+}
+
+func (r *MemoryHouseholdRepository) GetHouseholdsPendingDeletion(ctx context.Context) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	householdIds := make([]string, 0)
+	for _, household := range r.households {
+		if household.DeletionStatus != "" {
+			householdIds = append(householdIds, household.Id)
+		}
+	}
+	sort.Strings(householdIds)
+
+	return householdIds, nil
+}
+
+func (r *MemoryHouseholdRepository) DeleteHousehold(ctx context.Context, householdId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.households, householdId)
+	delete(r.members, householdId)
+
+	return nil
+}
+
+func (r *MemoryHouseholdRepository) RenameHousehold(ctx context.Context, householdId string, name string) (models.Household, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	household, err := r.get(householdId)
+	if err != nil {
+		return models.Household{}, err
+	}
+
+	household.Name = name
+	r.households[householdId] = household
+
+	return household, nil
+}
+
+func (r *MemoryHouseholdRepository) TransferHousehold(ctx context.Context, householdId string, fromUserId string, toUserId string) (models.Household, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	household, err := r.get(householdId)
+	if err != nil {
+		return models.Household{}, err
+	}
+
+	if household.OwnerId != fromUserId {
+		return models.Household{}, fmt.Errorf("household [%s] is no longer owned by [%s]: %w", householdId, fromUserId, proxy.ErrConditionFailed)
+	}
+
+	household.OwnerId = toUserId
+	r.households[householdId] = household
+
+	if member, ok := r.members[householdId][fromUserId]; ok {
+		member.Role = models.AdminRole
+		r.members[householdId][fromUserId] = member
+	}
+
+	return household, nil
+}
+
+func (r *MemoryHouseholdRepository) JoinHousehold(ctx context.Context, userId string, householdId string) error {
+	r.mu.Lock()
+	if r.members[householdId] == nil {
+		r.members[householdId] = make(map[string]models.HouseholdMember)
+	}
+	if _, ok := r.members[householdId][userId]; !ok {
+		r.members[householdId][userId] = newHouseholdMember(householdId, userId)
+	}
+	r.mu.Unlock()
+
+	return joinHousehold(ctx, r.users, userId, householdId)
+}
+
+func (r *MemoryHouseholdRepository) LeaveHousehold(ctx context.Context, userId string, householdId string) error {
+	// A member whose user record is gone still has to be taken out of the members index
+	if err := leaveHousehold(ctx, r.users, userId, householdId); err != nil && !errors.Is(err, proxy.ErrNotFound) {
+		return err
+	}
+
+	r.mu.Lock()
+	delete(r.members[householdId], userId)
+	r.mu.Unlock()
+
+	return nil
+}
+
+func (r *MemoryHouseholdRepository) GetMembers(ctx context.Context, householdId string) ([]models.HouseholdMember, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	members := make([]models.HouseholdMember, 0, len(r.members[householdId]))
+	for _, member := range r.members[householdId] {
+		members = append(members, member)
+	}
+
+	// Match the sort key order of the members table
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].UserId < members[j].UserId
+	})
+
+	return members, nil
+}
+
+func (r *MemoryHouseholdRepository) GetMember(ctx context.Context, householdId string, userId string) (models.HouseholdMember, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	member, ok := r.members[householdId][userId]
+	if !ok {
+		return models.HouseholdMember{}, fmt.Errorf("could not find member [%s] of household [%s]: %w", userId, householdId, proxy.ErrNotFound)
+	}
+
+	return member, nil
+}
+
+func (r *MemoryHouseholdRepository) SetMemberRole(ctx context.Context, householdId string, userId string, role models.HouseholdRole) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	member, ok := r.members[householdId][userId]
+	if !ok {
+		return fmt.Errorf("could not find member [%s] of household [%s]: %w", userId, householdId, proxy.ErrNotFound)
+	}
+
+	member.Role = role
+	r.members[householdId][userId] = member
+
+	return nil
+}
+
+// get hides households that are being deleted, like the Dynamo repository does
+func (r *MemoryHouseholdRepository) get(householdId string) (models.Household, error) {
+	household, ok := r.households[householdId]
+	if !ok || household.DeletionStatus != "" {
+		return models.Household{}, fmt.Errorf("could not find household [%s]: %w", householdId, proxy.ErrNotFound)
+	}
+
+	return household, nil
+}
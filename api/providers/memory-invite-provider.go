@@ -0,0 +1,122 @@
+package providers
+
+import (
+	"api/models"
+	"api/proxy"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryInviteRepository is an InviteRepository that keeps everything in process
+type MemoryInviteRepository struct {
+	mu      sync.Mutex
+	invites map[string]models.HouseholdInvite
+}
+
+func NewMemoryInviteRepository() *MemoryInviteRepository {
+	return &MemoryInviteRepository{
+		invites: make(map[string]models.HouseholdInvite),
+	}
+}
+
+func (r *MemoryInviteRepository) CreateInvite(ctx context.Context, invite models.HouseholdInvite) (models.HouseholdInvite, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for attempt := 0; attempt < maxInviteCodeAttempts; attempt++ {
+		code, err := newShortCode()
+		if err != nil {
+			return models.HouseholdInvite{}, err
+		}
+
+		if _, taken := r.invites[code]; taken {
+			continue
+		}
+
+		invite.Code = code
+		r.invites[code] = invite
+
+		return invite, nil
+	}
+
+	return models.HouseholdInvite{}, fmt.Errorf("unable to find an unused invite code after %d attempts", maxInviteCodeAttempts)
+}
+
+func (r *MemoryInviteRepository) GetInvite(ctx context.Context, code string) (models.HouseholdInvite, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.get(code)
+}
+
+func (r *MemoryInviteRepository) GetHouseholdInvites(ctx context.Context, householdId string) ([]models.HouseholdInvite, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	invites := make([]models.HouseholdInvite, 0)
+	for _, invite := range r.invites {
+		if invite.HouseholdId == householdId {
+			invites = append(invites, invite)
+		}
+	}
+
+	sort.Slice(invites, func(i, j int) bool {
+		return invites[i].CreatedAt < invites[j].CreatedAt
+	})
+
+	return invites, nil
+}
+
+func (r *MemoryInviteRepository) RedeemInvite(ctx context.Context, code string) (models.HouseholdInvite, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	invite, err := r.get(code)
+	if err != nil {
+		return models.HouseholdInvite{}, err
+	}
+
+	if err := checkInvite(invite, time.Now()); err != nil {
+		return models.HouseholdInvite{}, err
+	}
+
+	invite.Uses++
+	r.invites[code] = invite
+
+	return invite, nil
+}
+
+func (r *MemoryInviteRepository) ReleaseInvite(ctx context.Context, code string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	invite, ok := r.invites[code]
+	if !ok || invite.Uses == 0 {
+		return nil
+	}
+
+	invite.Uses--
+	r.invites[code] = invite
+
+	return nil
+}
+
+func (r *MemoryInviteRepository) DeleteInvite(ctx context.Context, code string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.invites, code)
+	return nil
+}
+
+func (r *MemoryInviteRepository) get(code string) (models.HouseholdInvite, error) {
+	invite, ok := r.invites[code]
+	if !ok {
+		return models.HouseholdInvite{}, fmt.Errorf("could not find invite [%s]: %w", code, proxy.ErrNotFound)
+	}
+
+	return invite, nil
+}
@@ -0,0 +1,61 @@
+package providers
+
+import (
+	"api/models"
+	"api/proxy"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// MemoryLayoutRepository is a LayoutRepository that keeps everything in process
+type MemoryLayoutRepository struct {
+	mu sync.Mutex
+	// householdId -> listId -> layout
+	layouts map[string]map[string]models.GroceryLayout
+}
+
+func NewMemoryLayoutRepository() *MemoryLayoutRepository {
+	return &MemoryLayoutRepository{
+		layouts: make(map[string]map[string]models.GroceryLayout),
+	}
+}
+
+func (r *MemoryLayoutRepository) GetLayout(ctx context.Context, householdId string, listId string) (models.GroceryLayout, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	layout, ok := r.layouts[householdId][listId]
+	if !ok {
+		return models.GroceryLayout{HouseholdId: householdId, ListId: listId, Blocks: []models.LayoutBlock{}}, nil
+	}
+
+	layout.Blocks = append([]models.LayoutBlock{}, layout.Blocks...)
+	return layout, nil
+}
+
+func (r *MemoryLayoutRepository) SaveLayout(ctx context.Context, layout models.GroceryLayout) (models.GroceryLayout, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if current := r.layouts[layout.HouseholdId][layout.ListId]; current.Version != layout.Version {
+		return models.GroceryLayout{}, fmt.Errorf("layout of list [%s] is at version %d: %w", layout.ListId, current.Version, proxy.ErrConditionFailed)
+	}
+
+	layout.Version++
+	layout.Blocks = append([]models.LayoutBlock{}, layout.Blocks...)
+	if r.layouts[layout.HouseholdId] == nil {
+		r.layouts[layout.HouseholdId] = make(map[string]models.GroceryLayout)
+	}
+	r.layouts[layout.HouseholdId][layout.ListId] = layout
+
+	return layout, nil
+}
+
+func (r *MemoryLayoutRepository) DeleteLayout(ctx context.Context, householdId string, listId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.layouts[householdId], listId)
+	return nil
+}
@@ -0,0 +1,89 @@
+package providers
+
+import (
+	"api/models"
+	"api/proxy"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// MemoryListRepository is a ListRepository that keeps everything in process
+type MemoryListRepository struct {
+	mu sync.Mutex
+	// householdId -> id -> list
+	lists map[string]map[string]models.HouseholdList
+}
+
+func NewMemoryListRepository() *MemoryListRepository {
+	return &MemoryListRepository{
+		lists: make(map[string]map[string]models.HouseholdList),
+	}
+}
+
+func (r *MemoryListRepository) GetLists(ctx context.Context, householdId string) ([]models.HouseholdList, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lists := make([]models.HouseholdList, 0, len(r.lists[householdId])+1)
+	for _, list := range r.lists[householdId] {
+		lists = append(lists, list)
+	}
+
+	return sortLists(householdId, lists), nil
+}
+
+func (r *MemoryListRepository) GetList(ctx context.Context, householdId string, listId string) (models.HouseholdList, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list, ok := r.lists[householdId][listId]
+	if ok {
+		return list, nil
+	}
+	if listId == models.DefaultListId {
+		return defaultList(householdId), nil
+	}
+
+	return models.HouseholdList{}, fmt.Errorf("could not find list [%s]: %w", listId, proxy.ErrNotFound)
+}
+
+func (r *MemoryListRepository) CreateList(ctx context.Context, list models.HouseholdList) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.lists[list.HouseholdId][list.Id]; exists {
+		return fmt.Errorf("list [%s] already exists: %w", list.Id, proxy.ErrConditionFailed)
+	}
+
+	r.put(list)
+	return nil
+}
+
+func (r *MemoryListRepository) UpdateList(ctx context.Context, list models.HouseholdList) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.lists[list.HouseholdId][list.Id]; !exists && list.Id != models.DefaultListId {
+		return fmt.Errorf("could not find list [%s]: %w", list.Id, proxy.ErrNotFound)
+	}
+
+	r.put(list)
+	return nil
+}
+
+func (r *MemoryListRepository) DeleteList(ctx context.Context, householdId string, listId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.lists[householdId], listId)
+	return nil
+}
+
+func (r *MemoryListRepository) put(list models.HouseholdList) {
+	if r.lists[list.HouseholdId] == nil {
+		r.lists[list.HouseholdId] = make(map[string]models.HouseholdList)
+	}
+
+	r.lists[list.HouseholdId][list.Id] = list
+}
@@ -0,0 +1,113 @@
+package providers
+
+import (
+	"api/models"
+	"api/proxy"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryStapleRepository is a StapleRepository that keeps everything in process
+type MemoryStapleRepository struct {
+	mu sync.Mutex
+	// householdId -> id -> staple
+	staples map[string]map[string]models.Staple
+}
+
+func NewMemoryStapleRepository() *MemoryStapleRepository {
+	return &MemoryStapleRepository{
+		staples: make(map[string]map[string]models.Staple),
+	}
+}
+
+func (r *MemoryStapleRepository) GetStaples(ctx context.Context, householdId string) ([]models.Staple, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	staples := make([]models.Staple, 0, len(r.staples[householdId]))
+	for _, staple := range r.staples[householdId] {
+		staples = append(staples, staple)
+	}
+
+	sortStaples(staples)
+	return staples, nil
+}
+
+func (r *MemoryStapleRepository) GetStaple(ctx context.Context, householdId string, stapleId string) (models.Staple, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	staple, ok := r.staples[householdId][stapleId]
+	if !ok {
+		return models.Staple{}, fmt.Errorf("could not find staple [%s]: %w", stapleId, proxy.ErrNotFound)
+	}
+
+	return staple, nil
+}
+
+func (r *MemoryStapleRepository) GetDueStaples(ctx context.Context, now time.Time) ([]models.Staple, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []models.Staple
+	for _, staples := range r.staples {
+		for _, staple := range staples {
+			if staple.Due(now) {
+				due = append(due, staple)
+			}
+		}
+	}
+
+	sortDueStaples(due)
+	return due, nil
+}
+
+func (r *MemoryStapleRepository) CreateStaple(ctx context.Context, staple models.Staple) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.staples[staple.HouseholdId][staple.Id]; exists {
+		return fmt.Errorf("staple [%s] already exists: %w", staple.Id, proxy.ErrConditionFailed)
+	}
+
+	r.put(staple)
+	return nil
+}
+
+func (r *MemoryStapleRepository) UpdateStaple(ctx context.Context, staple models.Staple) (models.Staple, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Mirrors the conditions of DynamoStapleRepository.UpdateStaple
+	current, exists := r.staples[staple.HouseholdId][staple.Id]
+	if !exists {
+		return models.Staple{}, fmt.Errorf("could not find staple [%s]: %w", staple.Id, proxy.ErrNotFound)
+	}
+	if current.Version != staple.Version {
+		return models.Staple{}, fmt.Errorf("staple [%s] has been updated: %w", staple.Id, proxy.ErrConditionFailed)
+	}
+
+	staple.Version++
+	return r.put(staple), nil
+}
+
+func (r *MemoryStapleRepository) DeleteStaple(ctx context.Context, householdId string, stapleId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.staples[householdId], stapleId)
+	return nil
+}
+
+func (r *MemoryStapleRepository) put(staple models.Staple) models.Staple {
+	staple.ScheduleId = models.StapleScheduleId(staple.HouseholdId)
+
+	if r.staples[staple.HouseholdId] == nil {
+		r.staples[staple.HouseholdId] = make(map[string]models.Staple)
+	}
+
+	r.staples[staple.HouseholdId][staple.Id] = staple
+	return staple
+}
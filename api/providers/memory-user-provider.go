@@ -0,0 +1,91 @@
+package providers
+
+import (
+	"api/models"
+	"api/proxy"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// MemoryUserRepository is a UserRepository that keeps everything in process
+type MemoryUserRepository struct {
+	mu    sync.RWMutex
+	users map[string]models.User
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{
+		users: make(map[string]models.User),
+	}
+}
+
+func (r *MemoryUserRepository) CreateUser(ctx context.Context) (models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user := models.User{
+		Id:           uuid.NewString(),
+		HouseholdIds: []string{},
+	}
+	r.users[user.Id] = user
+
+	return cloneUser(user), nil
+}
+
+func (r *MemoryUserRepository) UpdateUser(ctx context.Context, user models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Mirrors the version condition of DynamoUserRepository.UpdateUser
+	if stored := r.users[user.Id]; stored.Version != user.Version {
+		return fmt.Errorf("user [%s] has been updated, current version is %d: %w", user.Id, stored.Version, proxy.ErrConditionFailed)
+	}
+
+	user.Version++
+	r.users[user.Id] = cloneUser(user)
+	return nil
+}
+
+func (r *MemoryUserRepository) GetUsers(ctx context.Context, id string) ([]models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return []models.User{}, nil
+	}
+
+	return []models.User{cloneUser(user)}, nil
+}
+
+func (r *MemoryUserRepository) GetHouseholdUsers(ctx context.Context, householdId string) ([]models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make([]models.User, 0)
+	for _, user := range r.users {
+		if slices.Contains(user.HouseholdIds, householdId) {
+			users = append(users, cloneUser(user))
+		}
+	}
+
+	return users, nil
+}
+
+func (r *MemoryUserRepository) DeleteUser(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.users, id)
+	return nil
+}
+
+// cloneUser copies the slices on a user so callers cannot mutate the stored record
+func cloneUser(user models.User) models.User {
+	user.HouseholdIds = slices.Clone(user.HouseholdIds)
+	return user
+}
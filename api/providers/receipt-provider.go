@@ -3,42 +3,143 @@ package providers
 import (
 	"api/models"
 	s3proxy "api/proxy/s3"
+	"context"
 	"fmt"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-var unprocessedReceiptBucketName = "unprocessed-receipts-001"
-var processedReceiptBucketName = "processed-receipts-001"
-var maxObjectSize int64 = 10 * 1024 * 1024 // 10MB
+// receiptTextExtension is appended to the key of a receipt to store the text read off it
+const receiptTextExtension = ".txt"
 
-func GetPresignedReceiptUploadUrl(fileName string, contentLength int64, receiptContext models.ReceiptContext) (string, error) {
-	if contentLength == 0 || contentLength > maxObjectSize {
+// ReceiptProvider moves uploaded receipts from the unprocessed to the processed bucket
+type ReceiptProvider struct {
+	blobs                        s3proxy.BlobStore
+	unprocessedReceiptBucketName string
+	processedReceiptBucketName   string
+	maxObjectSize                int64
+}
+
+func NewReceiptProvider(blobs s3proxy.BlobStore, unprocessedReceiptBucketName string, processedReceiptBucketName string, maxObjectSize int64) *ReceiptProvider {
+	return &ReceiptProvider{
+		blobs:                        blobs,
+		unprocessedReceiptBucketName: unprocessedReceiptBucketName,
+		processedReceiptBucketName:   processedReceiptBucketName,
+		maxObjectSize:                maxObjectSize,
+	}
+}
+
+// GetPresignedReceiptUploadUrl returns an url to upload a receipt to, the receipt is stored
+// under the id of the user uploading it so it can be found for their export or erasure
+func (p *ReceiptProvider) GetPresignedReceiptUploadUrl(ctx context.Context, userId string, fileName string, contentLength int64, receiptContext models.ReceiptContext) (string, error) {
+	if contentLength == 0 || contentLength > p.maxObjectSize {
 		return "", fmt.Errorf("invalid content length")
 	}
 
 	extension := filepath.Ext(fileName)
 	t := time.Now()
 	randomId := uuid.NewString()
-	key := fmt.Sprintf("%d-%d-%d-%s%s", t.Year(), t.Month(), t.Day(), randomId, extension)
+	key := fmt.Sprintf("%s/%d-%d-%d-%s%s", userId, t.Year(), t.Month(), t.Day(), randomId, extension)
 
 	if receiptContext == models.BusinessReceipt {
 		key = fmt.Sprintf("business/%s", key)
 	}
 
-	return s3proxy.GeneratePresignedUrl(unprocessedReceiptBucketName, key, &contentLength)
+	return p.blobs.GeneratePresignedUrl(ctx, p.unprocessedReceiptBucketName, key, &contentLength)
+}
+
+func (p *ReceiptProvider) GetUnprocessedReceiptKeys(ctx context.Context, prefix string) ([]string, error) {
+	return p.blobs.GetKeys(ctx, p.unprocessedReceiptBucketName, prefix)
+}
+
+func (p *ReceiptProvider) GetUnprocessedReceipt(ctx context.Context, key string) ([]byte, error) {
+	return p.blobs.GetDocumentFile(ctx, p.unprocessedReceiptBucketName, key)
+}
+
+func (p *ReceiptProvider) MarkReceiptAsProcessed(ctx context.Context, key string) error {
+	return p.blobs.MoveObject(ctx, key, p.unprocessedReceiptBucketName, p.processedReceiptBucketName)
+}
+
+// SaveReceiptText stores the text read off the receipt with the given key in the processed bucket
+func (p *ReceiptProvider) SaveReceiptText(ctx context.Context, key string, text string) error {
+	return p.blobs.PutObject(ctx, key+receiptTextExtension, p.processedReceiptBucketName, []byte(text))
+}
+
+func (p *ReceiptProvider) GetReceiptText(ctx context.Context, key string) (string, error) {
+	text, err := p.blobs.GetDocumentFile(ctx, p.processedReceiptBucketName, key+receiptTextExtension)
+	return string(text), err
+}
+
+// GetReceipt returns the uploaded file of a receipt from whichever bucket it is in
+func (p *ReceiptProvider) GetReceipt(ctx context.Context, receipt models.Receipt) ([]byte, error) {
+	if receipt.Processed {
+		return p.blobs.GetDocumentFile(ctx, p.processedReceiptBucketName, receipt.Key)
+	}
+
+	return p.blobs.GetDocumentFile(ctx, p.unprocessedReceiptBucketName, receipt.Key)
 }
 
-func GetUnprocessedReceiptKeys(prefix string) ([]string, error) {
-	return s3proxy.GetKeys(unprocessedReceiptBucketName, prefix)
+// GetUserReceipts returns the receipts uploaded by the user from both receipt buckets.
+// Receipts uploaded before they were stored by user can't be attributed and are left out.
+func (p *ReceiptProvider) GetUserReceipts(ctx context.Context, userId string) ([]models.Receipt, error) {
+	receipts := make([]models.Receipt, 0)
+	texts := make(map[string]bool)
+
+	for _, prefix := range userReceiptPrefixes(userId) {
+		unprocessedKeys, err := p.blobs.GetKeys(ctx, p.unprocessedReceiptBucketName, prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range unprocessedKeys {
+			receipts = append(receipts, models.Receipt{Key: key})
+		}
+
+		processedKeys, err := p.blobs.GetKeys(ctx, p.processedReceiptBucketName, prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range processedKeys {
+			if receiptKey, ok := strings.CutSuffix(key, receiptTextExtension); ok {
+				texts[receiptKey] = true
+			} else {
+				receipts = append(receipts, models.Receipt{Key: key, Processed: true})
+			}
+		}
+	}
+
+	for i := range receipts {
+		receipts[i].HasText = texts[receipts[i].Key]
+	}
+
+	return receipts, nil
 }
 
-func GetUnprocessedReceipt(key string) ([]byte, error) {
-	return s3proxy.GetDocumentFile(unprocessedReceiptBucketName, key)
+// DeleteUserReceipts removes the receipts uploaded by the user and their text from both receipt buckets
+func (p *ReceiptProvider) DeleteUserReceipts(ctx context.Context, userId string) error {
+	for _, bucketName := range []string{p.unprocessedReceiptBucketName, p.processedReceiptBucketName} {
+		for _, prefix := range userReceiptPrefixes(userId) {
+			keys, err := p.blobs.GetKeys(ctx, bucketName, prefix)
+			if err != nil {
+				return err
+			}
+
+			for _, key := range keys {
+				if err := p.blobs.DeleteObject(ctx, bucketName, key); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
 }
 
-func MarkReceiptAsProcessed(key string) error {
-	return s3proxy.MoveObject(key, unprocessedReceiptBucketName, processedReceiptBucketName)
+// userReceiptPrefixes are the key prefixes GetPresignedReceiptUploadUrl stores the user's receipts under
+func userReceiptPrefixes(userId string) []string {
+	return []string{userId + "/", "business/" + userId + "/"}
 }
@@ -0,0 +1,45 @@
+package providers
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// shortCodeAlphabet leaves out characters that are easily confused with each other, like 0/O and 1/I/L,
+// so codes are easy to read out and type
+const shortCodeAlphabet = "23456789ABCDEFGHJKMNPQRSTVWXYZ"
+const shortCodeLength = 8
+
+// newShortCode returns a random code formatted as XXXX-XXXX
+func newShortCode() (string, error) {
+	var code strings.Builder
+	alphabetSize := big.NewInt(int64(len(shortCodeAlphabet)))
+
+	for i := 0; i < shortCodeLength; i++ {
+		if i == shortCodeLength/2 {
+			code.WriteByte('-')
+		}
+
+		n, err := rand.Int(rand.Reader, alphabetSize)
+		if err != nil {
+			return "", fmt.Errorf("unable to generate code, %v", err)
+		}
+		code.WriteByte(shortCodeAlphabet[n.Int64()])
+	}
+
+	return code.String(), nil
+}
+
+// NormalizeShortCode turns a code as typed by a person into the form it is stored in
+func NormalizeShortCode(code string) string {
+	code = strings.ToUpper(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+
+	if len(code) != shortCodeLength {
+		return code
+	}
+
+	return code[:shortCodeLength/2] + "-" + code[shortCodeLength/2:]
+}
@@ -0,0 +1,134 @@
+package providers
+
+import (
+	"api/models"
+	"api/proxy"
+	ddbproxy "api/proxy/ddb"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// staplesScheduleIndex is the index of the GroceryStaples table that sorts every staple by nextDueAt
+const staplesScheduleIndex = "scheduleId-nextDueAt-index"
+
+// StapleRepository stores the staples of households
+type StapleRepository interface {
+	// GetStaples returns the household's staples in the order they were created
+	GetStaples(ctx context.Context, householdId string) ([]models.Staple, error)
+	// GetStaple fails with proxy.ErrNotFound when the household has no such staple
+	GetStaple(ctx context.Context, householdId string, stapleId string) (models.Staple, error)
+	// GetDueStaples returns the staples of every household that are due at now, soonest due first
+	GetDueStaples(ctx context.Context, now time.Time) ([]models.Staple, error)
+	CreateStaple(ctx context.Context, staple models.Staple) error
+	// UpdateStaple stores the staple if its version matches the stored one and returns it with the
+	// version bumped, otherwise it fails with proxy.ErrConditionFailed. It fails with
+	// proxy.ErrNotFound when the staple doesn't exist.
+	UpdateStaple(ctx context.Context, staple models.Staple) (models.Staple, error)
+	DeleteStaple(ctx context.Context, householdId string, stapleId string) error
+}
+
+// DynamoStapleRepository is a StapleRepository backed by the GroceryStaples table
+type DynamoStapleRepository struct {
+	tableName string
+}
+
+func NewDynamoStapleRepository(tableName string) *DynamoStapleRepository {
+	return &DynamoStapleRepository{tableName: tableName}
+}
+
+func (r *DynamoStapleRepository) GetStaples(ctx context.Context, householdId string) ([]models.Staple, error) {
+	hashKeyAttributeValues := map[string]types.AttributeValue{
+		":hId": &types.AttributeValueMemberS{Value: householdId},
+	}
+
+	staples, err := ddbproxy.QueryTable[models.Staple](ctx, r.tableName, "householdId = :hId", hashKeyAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	sortStaples(staples)
+	return staples, nil
+}
+
+func (r *DynamoStapleRepository) GetStaple(ctx context.Context, householdId string, stapleId string) (models.Staple, error) {
+	return ddbproxy.GetItem[models.Staple](ctx, r.tableName, stapleKey(householdId, stapleId))
+}
+
+// GetDueStaples reads every shard of the schedule index
+func (r *DynamoStapleRepository) GetDueStaples(ctx context.Context, now time.Time) ([]models.Staple, error) {
+	due := make([]models.Staple, 0)
+
+	for _, scheduleId := range models.StapleScheduleIds() {
+		keyAttributeValues := map[string]types.AttributeValue{
+			":schedule": &types.AttributeValueMemberS{Value: scheduleId},
+			":now":      &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+		}
+
+		staples, err := ddbproxy.QueryIndex[models.Staple](ctx, r.tableName, staplesScheduleIndex, "scheduleId = :schedule AND nextDueAt <= :now", keyAttributeValues)
+		if err != nil {
+			return nil, err
+		}
+		due = append(due, staples...)
+	}
+
+	sortDueStaples(due)
+	return due, nil
+}
+
+func (r *DynamoStapleRepository) CreateStaple(ctx context.Context, staple models.Staple) error {
+	staple.ScheduleId = models.StapleScheduleId(staple.HouseholdId)
+	return ddbproxy.CreateItemWithCondition(ctx, r.tableName, staple, ddbproxy.NotExistsCondition("id"))
+}
+
+func (r *DynamoStapleRepository) UpdateStaple(ctx context.Context, staple models.Staple) (models.Staple, error) {
+	staple.ScheduleId = models.StapleScheduleId(staple.HouseholdId)
+
+	condition := ddbproxy.AndCondition(ddbproxy.ExistsCondition("id"), ddbproxy.VersionCondition("version", staple.Version))
+	staple.Version++
+
+	err := ddbproxy.UpdateItem(ctx, r.tableName, stapleKey(staple.HouseholdId, staple.Id), staple, []string{"householdId", "id"}, condition)
+
+	var conditionFailed *ddbproxy.ConditionFailedError
+	if errors.As(err, &conditionFailed) {
+		if len(conditionFailed.Item) == 0 {
+			return models.Staple{}, fmt.Errorf("could not find staple [%s]: %w", staple.Id, proxy.ErrNotFound)
+		}
+
+		return models.Staple{}, fmt.Errorf("staple [%s] has been updated: %w", staple.Id, proxy.ErrConditionFailed)
+	}
+	if err != nil {
+		return models.Staple{}, err
+	}
+
+	return staple, nil
+}
+
+func (r *DynamoStapleRepository) DeleteStaple(ctx context.Context, householdId string, stapleId string) error {
+	return ddbproxy.DeleteItem(ctx, r.tableName, stapleKey(householdId, stapleId))
+}
+
+func stapleKey(householdId string, stapleId string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"householdId": &types.AttributeValueMemberS{Value: householdId},
+		"id":          &types.AttributeValueMemberS{Value: stapleId},
+	}
+}
+
+func sortStaples(staples []models.Staple) {
+	sort.SliceStable(staples, func(i, j int) bool {
+		return staples[i].CreatedAt < staples[j].CreatedAt
+	})
+}
+
+// sortDueStaples orders due staples like the schedule index does within a shard
+func sortDueStaples(staples []models.Staple) {
+	sort.SliceStable(staples, func(i, j int) bool {
+		return staples[i].NextDueAt < staples[j].NextDueAt
+	})
+}
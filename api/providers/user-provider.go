@@ -2,60 +2,152 @@ package providers
 
 import (
 	"api/models"
+	"api/proxy"
 	ddbproxy "api/proxy/ddb"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/google/uuid"
 )
 
-var usersTableName = "Users"
+// UserRepository stores the anonymous users of the app
+type UserRepository interface {
+	CreateUser(ctx context.Context) (models.User, error)
+	// UpdateUser stores the user if its version matches the stored one, otherwise it fails with
+	// proxy.ErrConditionFailed. Users that don't exist yet are created from version 0.
+	UpdateUser(ctx context.Context, user models.User) error
+	GetUsers(ctx context.Context, id string) ([]models.User, error)
+	// GetHouseholdUsers returns the users that have the household among their HouseholdIds. It
+	// goes through every user, it is only meant for background jobs.
+	GetHouseholdUsers(ctx context.Context, householdId string) ([]models.User, error)
+	// DeleteUser removes the user record only, the user must have left their households already
+	DeleteUser(ctx context.Context, id string) error
+}
+
+// DynamoUserRepository is a UserRepository backed by the Users table
+type DynamoUserRepository struct {
+	tableName string
+}
+
+func NewDynamoUserRepository(tableName string) *DynamoUserRepository {
+	return &DynamoUserRepository{tableName: tableName}
+}
 
-func CreateUser() models.User {
+func (r *DynamoUserRepository) CreateUser(ctx context.Context) (models.User, error) {
 	id := uuid.NewString()
 
-	hashKeyAttributeValues := map[string]types.AttributeValue{
-		":uId": &types.AttributeValueMemberS{Value: id},
+	results, err := r.GetUsers(ctx, id)
+	if err != nil {
+		return models.User{}, err
 	}
 
-	results := ddbproxy.QueryTable[models.User](usersTableName, "id = :uId", hashKeyAttributeValues)
-
 	if len(results) == 0 {
 		user := models.User{
 			Id:           id,
 			HouseholdIds: []string{},
 		}
 
-		ddbproxy.CreateItem(usersTableName, user)
+		return user, ddbproxy.CreateItem(ctx, r.tableName, user)
+	}
 
-		return user
+	return r.CreateUser(ctx)
+}
+
+func (r *DynamoUserRepository) GetHouseholdUsers(ctx context.Context, householdId string) ([]models.User, error) {
+	attributeValues := map[string]types.AttributeValue{
+		":hId": &types.AttributeValueMemberS{Value: householdId},
 	}
 
-	return CreateUser()
+	return ddbproxy.ScanTable[models.User](ctx, r.tableName, "contains(householdIds, :hId)", attributeValues)
 }
 
-func UpdateUser(user models.User) error {
+func (r *DynamoUserRepository) UpdateUser(ctx context.Context, user models.User) error {
 	key := map[string]types.AttributeValue{
 		"id": &types.AttributeValueMemberS{Value: user.Id},
 	}
 	ignoreKeys := []string{"id"}
 
-	return ddbproxy.UpdateItem(usersTableName, key, user, ignoreKeys)
+	condition := ddbproxy.VersionCondition("version", user.Version)
+	user.Version++
+
+	return ddbproxy.UpdateItem(ctx, r.tableName, key, user, ignoreKeys, condition)
 }
 
-func GetUsers(id string) []models.User {
+func (r *DynamoUserRepository) GetUsers(ctx context.Context, id string) ([]models.User, error) {
 	hashKeyAttributeValues := map[string]types.AttributeValue{
 		":uId": &types.AttributeValueMemberS{Value: id},
 	}
 
-	return ddbproxy.QueryTable[models.User](usersTableName, "id = :uId", hashKeyAttributeValues)
+	return ddbproxy.QueryTable[models.User](ctx, r.tableName, "id = :uId", hashKeyAttributeValues)
+}
+
+func (r *DynamoUserRepository) DeleteUser(ctx context.Context, id string) error {
+	key := map[string]types.AttributeValue{
+		"id": &types.AttributeValueMemberS{Value: id},
+	}
+
+	return ddbproxy.DeleteItem(ctx, r.tableName, key)
 }
 
-func GetOrCreateUser(id string) models.User {
-	users := GetUsers(id)
+// GetUser returns the user with the given id, failing with proxy.ErrNotFound when there is none
+func GetUser(ctx context.Context, users UserRepository, id string) (models.User, error) {
+	results, err := users.GetUsers(ctx, id)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if len(results) == 0 {
+		return models.User{}, fmt.Errorf("could not find user [%s]: %w", id, proxy.ErrNotFound)
+	}
+
+	return results[0], nil
+}
+
+// SetDefaultHousehold makes householdId the user's default, they must already be a member of it
+func SetDefaultHousehold(ctx context.Context, users UserRepository, userId string, householdId string) (models.User, error) {
+	return updateUser(ctx, users, userId, func(user *models.User) (bool, error) {
+		if !slices.Contains(user.HouseholdIds, householdId) {
+			return false, fmt.Errorf("user [%s] is not a member of household [%s]: %w", userId, householdId, proxy.ErrNotFound)
+		}
+
+		changed := user.DefaultHouseholdId != householdId
+		user.DefaultHouseholdId = householdId
+		return changed, nil
+	})
+}
+
+// maxUserUpdateAttempts bounds how often a change to a user is made again when the user changes under it
+const maxUserUpdateAttempts = 5
+
+// updateUser applies change to the user and stores it, starting over when the user was updated in
+// between so concurrent changes, e.g. joining two households at once, don't undo each other. change
+// reports whether it changed anything.
+func updateUser(ctx context.Context, users UserRepository, userId string, change func(*models.User) (bool, error)) (models.User, error) {
+	for attempt := 0; attempt < maxUserUpdateAttempts; attempt++ {
+		user, err := GetUser(ctx, users, userId)
+		if err != nil {
+			return models.User{}, err
+		}
+
+		changed, err := change(&user)
+		if err != nil || !changed {
+			return user, err
+		}
+
+		err = users.UpdateUser(ctx, user)
+		if errors.Is(err, proxy.ErrConditionFailed) {
+			continue
+		}
+		if err != nil {
+			return models.User{}, err
+		}
 
-	if len(users) > 0 {
-		return users[0]
+		user.Version++
+		return user, nil
 	}
 
-	return CreateUser()
+	return models.User{}, fmt.Errorf("user [%s] kept changing: %w", userId, proxy.ErrConditionFailed)
 }
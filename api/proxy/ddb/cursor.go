@@ -0,0 +1,77 @@
+package ddbproxy
+
+import (
+	"api/proxy"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// EncodeCursor turns a LastEvaluatedKey into an opaque token that can be handed to clients.
+// An empty key encodes to an empty cursor.
+func EncodeCursor(key map[string]types.AttributeValue) (string, error) {
+	if len(key) == 0 {
+		return "", nil
+	}
+
+	var values map[string]interface{}
+	if err := attributevalue.UnmarshalMap(key, &values); err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+
+	body, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(body), nil
+}
+
+// DecodeCursor turns a token from EncodeCursor back into an ExclusiveStartKey.
+// An empty cursor decodes to a nil key, which starts from the first page.
+func DecodeCursor(cursor string) (map[string]types.AttributeValue, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	body, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", proxy.ErrInvalidCursor, err)
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal(body, &values); err != nil || len(values) == 0 {
+		return nil, fmt.Errorf("%w: %q", proxy.ErrInvalidCursor, cursor)
+	}
+
+	key, err := attributevalue.MarshalMap(values)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", proxy.ErrInvalidCursor, err)
+	}
+
+	return key, nil
+}
+
+// DecodePartitionCursor is DecodeCursor for pages of a single partition of a table whose keys are
+// strings. Cursors come back from clients, so the key must be made of nothing but the partition
+// key, holding partitionValue, and the sort key, otherwise it fails with proxy.ErrInvalidCursor.
+func DecodePartitionCursor(cursor string, partitionKey string, partitionValue string, sortKey string) (map[string]types.AttributeValue, error) {
+	key, err := DecodeCursor(cursor)
+	if err != nil || key == nil {
+		return key, err
+	}
+
+	partition, ok := key[partitionKey].(*types.AttributeValueMemberS)
+	if !ok || partition.Value != partitionValue {
+		return nil, fmt.Errorf("%w: not a cursor of %s %q", proxy.ErrInvalidCursor, partitionKey, partitionValue)
+	}
+
+	if _, ok := key[sortKey].(*types.AttributeValueMemberS); !ok || len(key) != 2 {
+		return nil, fmt.Errorf("%w: %q", proxy.ErrInvalidCursor, cursor)
+	}
+
+	return key, nil
+}
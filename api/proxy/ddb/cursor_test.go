@@ -0,0 +1,113 @@
+package ddbproxy
+
+import (
+	"api/proxy"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		key  map[string]types.AttributeValue
+	}{
+		{name: "empty", key: nil},
+		{name: "strings", key: map[string]types.AttributeValue{
+			"householdId": &types.AttributeValueMemberS{Value: "h1"},
+			"id":          &types.AttributeValueMemberS{Value: "i1"},
+		}},
+		{name: "number", key: map[string]types.AttributeValue{
+			"householdId": &types.AttributeValueMemberS{Value: "h1"},
+			"createdAt":   &types.AttributeValueMemberN{Value: "1700000000"},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cursor, err := EncodeCursor(test.key)
+			if err != nil {
+				t.Fatalf("EncodeCursor() error = %v", err)
+			}
+			if len(test.key) == 0 && cursor != "" {
+				t.Fatalf("EncodeCursor() = %q, want an empty cursor", cursor)
+			}
+
+			key, err := DecodeCursor(cursor)
+			if err != nil {
+				t.Fatalf("DecodeCursor(%q) error = %v", cursor, err)
+			}
+			if len(test.key) == 0 {
+				if key != nil {
+					t.Fatalf("DecodeCursor(%q) = %v, want nil", cursor, key)
+				}
+				return
+			}
+			if !reflect.DeepEqual(key, test.key) {
+				t.Errorf("DecodeCursor(%q) = %#v, want %#v", cursor, key, test.key)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejectsGarbage(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "not base64", cursor: "not a cursor!"},
+		{name: "not json", cursor: base64.RawURLEncoding.EncodeToString([]byte("zzz"))},
+		{name: "empty object", cursor: base64.RawURLEncoding.EncodeToString([]byte("{}"))},
+		{name: "not an object", cursor: base64.RawURLEncoding.EncodeToString([]byte(`["h1"]`))},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := DecodeCursor(test.cursor); !errors.Is(err, proxy.ErrInvalidCursor) {
+				t.Errorf("DecodeCursor(%q) error = %v, want ErrInvalidCursor", test.cursor, err)
+			}
+		})
+	}
+}
+
+func TestDecodePartitionCursor(t *testing.T) {
+	encode := func(body string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(body))
+	}
+
+	tests := []struct {
+		name    string
+		cursor  string
+		wantErr bool
+	}{
+		{name: "empty", cursor: ""},
+		{name: "same household", cursor: encode(`{"householdId":"h1","id":"i1"}`)},
+		{name: "other household", cursor: encode(`{"householdId":"h2","id":"i1"}`), wantErr: true},
+		{name: "missing household", cursor: encode(`{"id":"i1"}`), wantErr: true},
+		{name: "missing sort key", cursor: encode(`{"householdId":"h1"}`), wantErr: true},
+		{name: "numeric sort key", cursor: encode(`{"householdId":"h1","id":1}`), wantErr: true},
+		{name: "extra attribute", cursor: encode(`{"householdId":"h1","id":"i1","name":"milk"}`), wantErr: true},
+		{name: "garbage", cursor: "zzz", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key, err := DecodePartitionCursor(test.cursor, "householdId", "h1", "id")
+			if test.wantErr {
+				if !errors.Is(err, proxy.ErrInvalidCursor) {
+					t.Errorf("DecodePartitionCursor(%q) error = %v, want ErrInvalidCursor", test.cursor, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodePartitionCursor(%q) error = %v", test.cursor, err)
+			}
+			if test.cursor == "" && key != nil {
+				t.Errorf("DecodePartitionCursor(%q) = %v, want nil", test.cursor, key)
+			}
+		})
+	}
+}
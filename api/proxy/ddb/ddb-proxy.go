@@ -1,10 +1,15 @@
 package ddbproxy
 
 import (
+	"api/proxy"
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"math/rand"
 	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -15,18 +20,48 @@ import (
 
 var svc *dynamodb.Client
 
-func init() {
+// Configure creates the DynamoDB client used by every function in this package,
+// it must be called once at startup before any table is accessed
+func Configure(ctx context.Context, region string) error {
 	// Load the shared AWS configuration
-	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion("ap-southeast-2"))
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region), config.WithRetryMaxAttempts(proxy.RetryMaxAttempts))
 	if err != nil {
-		log.Fatalf("unable to load SDK config, %v", err)
+		return fmt.Errorf("unable to load SDK config, %v", err)
 	}
 
 	// Create a DynamoDB client
 	svc = dynamodb.NewFromConfig(cfg)
+	return nil
+}
+
+// GetItem returns the item at key, failing with proxy.ErrNotFound when there is none
+func GetItem[T interface{}](ctx context.Context, tableName string, key map[string]types.AttributeValue) (T, error) {
+	var item T
+
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(tableName),
+		Key:       key,
+	}
+
+	result, err := svc.GetItem(ctx, input)
+	if err != nil {
+		return item, wrapError("failed to get item", err)
+	}
+
+	if len(result.Item) == 0 {
+		return item, fmt.Errorf("no item in table %q: %w", tableName, proxy.ErrNotFound)
+	}
+
+	if err := attributevalue.UnmarshalMap(result.Item, &item); err != nil {
+		return item, fmt.Errorf("%w item: %w", proxy.ErrUnmarshal, err)
+	}
+
+	return item, nil
 }
 
-func QueryTable[T interface{}](tableName string, keyExpression string, hashKeyAttributeValues map[string]types.AttributeValue) []T {
+// QueryTable returns every item matching the key expression, following LastEvaluatedKey
+// until DynamoDB has returned every page
+func QueryTable[T interface{}](ctx context.Context, tableName string, keyExpression string, hashKeyAttributeValues map[string]types.AttributeValue) ([]T, error) {
 	// Create the query input parameters
 	input := &dynamodb.QueryInput{
 		TableName:                 aws.String(tableName),
@@ -34,25 +69,117 @@ func QueryTable[T interface{}](tableName string, keyExpression string, hashKeyAt
 		ExpressionAttributeValues: hashKeyAttributeValues,
 	}
 
-	// Query the table
-	result, err := svc.Query(context.TODO(), input)
+	return queryAll[T](ctx, input)
+}
+
+// QueryIndex is QueryTable against a secondary index of the table
+func QueryIndex[T interface{}](ctx context.Context, tableName string, indexName string, keyExpression string, hashKeyAttributeValues map[string]types.AttributeValue) ([]T, error) {
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(tableName),
+		IndexName:                 aws.String(indexName),
+		KeyConditionExpression:    aws.String(keyExpression),
+		ExpressionAttributeValues: hashKeyAttributeValues,
+	}
+
+	return queryAll[T](ctx, input)
+}
+
+// ScanTable returns every item of the table that matches the filter expression. It reads the
+// whole table, so it is only meant for background jobs.
+func ScanTable[T interface{}](ctx context.Context, tableName string, filterExpression string, attributeValues map[string]types.AttributeValue) ([]T, error) {
+	input := &dynamodb.ScanInput{
+		TableName:                 aws.String(tableName),
+		FilterExpression:          aws.String(filterExpression),
+		ExpressionAttributeValues: attributeValues,
+	}
+
+	items := make([]T, 0)
+	paginator := dynamodb.NewScanPaginator(svc, input)
+
+	for paginator.HasMorePages() {
+		result, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, wrapError("failed to scan table", err)
+		}
+
+		var pageItems []T
+		err = attributevalue.UnmarshalListOfMaps(result.Items, &pageItems)
+		if err != nil {
+			return nil, fmt.Errorf("%w scan result items: %w", proxy.ErrUnmarshal, err)
+		}
+
+		items = append(items, pageItems...)
+	}
+
+	return items, nil
+}
+
+func queryAll[T interface{}](ctx context.Context, input *dynamodb.QueryInput) ([]T, error) {
+	items := make([]T, 0)
+	paginator := dynamodb.NewQueryPaginator(svc, input)
+
+	for paginator.HasMorePages() {
+		result, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, wrapError("failed to query table", err)
+		}
+
+		var pageItems []T
+		err = attributevalue.UnmarshalListOfMaps(result.Items, &pageItems)
+		if err != nil {
+			return nil, fmt.Errorf("%w query result items: %w", proxy.ErrUnmarshal, err)
+		}
+
+		items = append(items, pageItems...)
+	}
+
+	return items, nil
+}
+
+// QueryTablePage returns a single page of at most limit items starting after cursor,
+// along with the cursor for the next page. The next cursor is empty once there are no more pages.
+func QueryTablePage[T interface{}](ctx context.Context, tableName string, keyExpression string, hashKeyAttributeValues map[string]types.AttributeValue, limit int32, cursor string) ([]T, string, error) {
+	exclusiveStartKey, err := DecodeCursor(cursor)
 	if err != nil {
-		log.Fatalf("failed to query table, %v", err)
+		return nil, "", err
 	}
 
-	var items []T
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(tableName),
+		KeyConditionExpression:    aws.String(keyExpression),
+		ExpressionAttributeValues: hashKeyAttributeValues,
+		ExclusiveStartKey:         exclusiveStartKey,
+		Limit:                     aws.Int32(limit),
+	}
+
+	result, err := svc.Query(ctx, input)
+	if err != nil {
+		return nil, "", wrapError("failed to query table", err)
+	}
+
+	items := make([]T, 0, len(result.Items))
 	err = attributevalue.UnmarshalListOfMaps(result.Items, &items)
 	if err != nil {
-		log.Fatalf("failed to unmarshal query result items, %v", err)
+		return nil, "", fmt.Errorf("%w query result items: %w", proxy.ErrUnmarshal, err)
 	}
 
-	return items
+	nextCursor, err := EncodeCursor(result.LastEvaluatedKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return items, nextCursor, nil
+}
+
+func CreateItem(ctx context.Context, tableName string, record interface{}) error {
+	return CreateItemWithCondition(ctx, tableName, record, nil)
 }
 
-func CreateItem(tableName string, record interface{}) error {
+// CreateItemWithCondition puts record only if condition holds against the item it would replace
+func CreateItemWithCondition(ctx context.Context, tableName string, record interface{}, condition *Condition) error {
 	av, err := attributevalue.MarshalMap(record)
 	if err != nil {
-		return fmt.Errorf("failed to marshal GroceryItem: %v", err)
+		return fmt.Errorf("failed to marshal item: %w", err)
 	}
 
 	input := &dynamodb.PutItemInput{
@@ -60,18 +187,180 @@ func CreateItem(tableName string, record interface{}) error {
 		Item:      av,
 	}
 
-	_, err = svc.PutItem(context.TODO(), input)
+	if condition != nil {
+		input.ConditionExpression = aws.String(condition.Expression)
+		input.ExpressionAttributeNames = condition.Names
+		input.ExpressionAttributeValues = condition.Values
+		input.ReturnValuesOnConditionCheckFailure = types.ReturnValuesOnConditionCheckFailureAllOld
+	}
+
+	_, err = svc.PutItem(ctx, input)
+
+	var conditionalCheckFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionalCheckFailed) {
+		return &ConditionFailedError{Item: conditionalCheckFailed.Item, err: err}
+	}
 	if err != nil {
-		return fmt.Errorf("failed to put item: %v: %v", err, av)
+		return wrapError("failed to put item", err)
 	}
 
 	return nil
 }
 
-func UpdateItem(tableName string, key map[string]types.AttributeValue, record interface{}, ignoreKeys []string) error {
+// Condition guards a write, the write fails with a *ConditionFailedError when it does not hold.
+// Placeholders must not clash with the #attribute / :attribute ones UpdateItem generates.
+type Condition struct {
+	Expression string
+	Names      map[string]string
+	Values     map[string]types.AttributeValue
+}
+
+// VersionCondition only lets a write through while versionAttribute still holds expectedVersion.
+// A missing attribute counts as version 0, so records written before versioning can still be updated.
+func VersionCondition(versionAttribute string, expectedVersion int) *Condition {
+	expression := "#conditionVersion = :conditionVersion"
+	if expectedVersion == 0 {
+		expression = "attribute_not_exists(#conditionVersion) OR " + expression
+	}
+
+	return &Condition{
+		Expression: expression,
+		Names:      map[string]string{"#conditionVersion": versionAttribute},
+		Values: map[string]types.AttributeValue{
+			":conditionVersion": &types.AttributeValueMemberN{Value: strconv.Itoa(expectedVersion)},
+		},
+	}
+}
+
+// NotExistsCondition only lets a write through when there is no item with the same key yet
+func NotExistsCondition(keyAttribute string) *Condition {
+	return &Condition{
+		Expression: "attribute_not_exists(#conditionKey)",
+		Names:      map[string]string{"#conditionKey": keyAttribute},
+	}
+}
+
+// ExistsCondition only lets a write through while the item it targets exists
+func ExistsCondition(keyAttribute string) *Condition {
+	return &Condition{
+		Expression: "attribute_exists(#conditionKey)",
+		Names:      map[string]string{"#conditionKey": keyAttribute},
+	}
+}
+
+// AndCondition holds when every one of conditions holds, their placeholders must not clash
+func AndCondition(conditions ...*Condition) *Condition {
+	combined := &Condition{}
+
+	expressions := make([]string, len(conditions))
+	for index, condition := range conditions {
+		expressions[index] = "(" + condition.Expression + ")"
+		for k, v := range condition.Names {
+			if combined.Names == nil {
+				combined.Names = make(map[string]string)
+			}
+			combined.Names[k] = v
+		}
+		for k, v := range condition.Values {
+			// DynamoDB rejects empty expression attribute maps
+			if combined.Values == nil {
+				combined.Values = make(map[string]types.AttributeValue)
+			}
+			combined.Values[k] = v
+		}
+	}
+	combined.Expression = strings.Join(expressions, " AND ")
+
+	return combined
+}
+
+// ConditionFailedError is returned when a Condition did not hold.
+// Item is the record as currently stored, empty when it does not exist.
+type ConditionFailedError struct {
+	Item map[string]types.AttributeValue
+	err  error
+}
+
+func (e *ConditionFailedError) Error() string {
+	return fmt.Sprintf("%v: %v", proxy.ErrConditionFailed, e.err)
+}
+
+func (e *ConditionFailedError) Unwrap() []error {
+	return []error{proxy.ErrConditionFailed, e.err}
+}
+
+// UpdateItem sets every attribute of record on the item at key, except for ignoreKeys.
+// When condition is not nil the update only happens if it holds.
+func UpdateItem(ctx context.Context, tableName string, key map[string]types.AttributeValue, record interface{}, ignoreKeys []string, condition *Condition) error {
+	input, err := updateItemInput(tableName, key, record, ignoreKeys, condition)
+	if err != nil {
+		return err
+	}
+
+	_, err = svc.UpdateItem(ctx, input)
+
+	var conditionalCheckFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionalCheckFailed) {
+		return &ConditionFailedError{Item: conditionalCheckFailed.Item, err: err}
+	}
+	if err != nil {
+		return wrapError("failed to update item", err)
+	}
+
+	return nil
+}
+
+// Update is one of the writes of TransactUpdateItems, its fields are the arguments of UpdateItem
+type Update struct {
+	TableName  string
+	Key        map[string]types.AttributeValue
+	Record     interface{}
+	IgnoreKeys []string
+	Condition  *Condition
+}
+
+// TransactUpdateItems applies every update or none of them. When the condition of one of them
+// does not hold it fails with a *ConditionFailedError holding the item that failed it.
+func TransactUpdateItems(ctx context.Context, updates ...Update) error {
+	transactItems := make([]types.TransactWriteItem, 0, len(updates))
+	for _, update := range updates {
+		input, err := updateItemInput(update.TableName, update.Key, update.Record, update.IgnoreKeys, update.Condition)
+		if err != nil {
+			return err
+		}
+
+		transactItems = append(transactItems, types.TransactWriteItem{Update: &types.Update{
+			TableName:                           input.TableName,
+			Key:                                 input.Key,
+			UpdateExpression:                    input.UpdateExpression,
+			ConditionExpression:                 input.ConditionExpression,
+			ExpressionAttributeNames:            input.ExpressionAttributeNames,
+			ExpressionAttributeValues:           input.ExpressionAttributeValues,
+			ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailure(input.ReturnValuesOnConditionCheckFailure),
+		}})
+	}
+
+	_, err := svc.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: transactItems})
+
+	var cancelled *types.TransactionCanceledException
+	if errors.As(err, &cancelled) {
+		for _, reason := range cancelled.CancellationReasons {
+			if aws.ToString(reason.Code) == "ConditionalCheckFailed" {
+				return &ConditionFailedError{Item: reason.Item, err: err}
+			}
+		}
+	}
+	if err != nil {
+		return wrapError("failed to update items", err)
+	}
+
+	return nil
+}
+
+func updateItemInput(tableName string, key map[string]types.AttributeValue, record interface{}, ignoreKeys []string, condition *Condition) (*dynamodb.UpdateItemInput, error) {
 	av, err := attributevalue.MarshalMap(record)
 	if err != nil {
-		return fmt.Errorf("failed to marshal GroceryItem: %v", err)
+		return nil, fmt.Errorf("failed to marshal item: %w", err)
 	}
 
 	updateExpression := "SET"
@@ -102,28 +391,53 @@ func UpdateItem(tableName string, key map[string]types.AttributeValue, record in
 		ExpressionAttributeValues: expressionAttributeValues,
 	}
 
-	_, err = svc.UpdateItem(context.TODO(), input)
-	if err != nil {
-		return fmt.Errorf("failed to update item: %w", err)
+	if condition != nil {
+		input.ConditionExpression = aws.String(condition.Expression)
+		input.ReturnValuesOnConditionCheckFailure = types.ReturnValuesOnConditionCheckFailureAllOld
+		for k, v := range condition.Names {
+			expressionAttributeNames[k] = v
+		}
+		for k, v := range condition.Values {
+			expressionAttributeValues[k] = v
+		}
 	}
 
-	return nil
+	return input, nil
+}
+
+func DeleteItem(ctx context.Context, tableName string, key map[string]types.AttributeValue) error {
+	return DeleteItemWithCondition(ctx, tableName, key, nil)
 }
 
-func DeleteItem(tableName string, key map[string]types.AttributeValue) error {
+// DeleteItemWithCondition deletes the item only if condition holds against it
+func DeleteItemWithCondition(ctx context.Context, tableName string, key map[string]types.AttributeValue, condition *Condition) error {
 	input := &dynamodb.DeleteItemInput{
 		TableName: aws.String(tableName),
 		Key:       key,
 	}
 
-	_, err := svc.DeleteItem(context.TODO(), input)
+	if condition != nil {
+		input.ConditionExpression = aws.String(condition.Expression)
+		input.ExpressionAttributeNames = condition.Names
+		input.ExpressionAttributeValues = condition.Values
+		input.ReturnValuesOnConditionCheckFailure = types.ReturnValuesOnConditionCheckFailureAllOld
+	}
+
+	_, err := svc.DeleteItem(ctx, input)
+
+	var conditionalCheckFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionalCheckFailed) {
+		return &ConditionFailedError{Item: conditionalCheckFailed.Item, err: err}
+	}
 	if err != nil {
-		return fmt.Errorf("failed to delete item: %w", err)
+		return wrapError("failed to delete item", err)
 	}
 	return nil
 }
 
-func BatchDeleteItems(tableName string, keys []map[string]types.AttributeValue) error {
+// BatchDeleteItems deletes keys in batches of 25, retrying unprocessed items with backoff.
+// It returns the keys that could not be deleted, alongside any error that caused them to fail.
+func BatchDeleteItems(ctx context.Context, tableName string, keys []map[string]types.AttributeValue) ([]map[string]types.AttributeValue, error) {
 	writeReqs := make([]types.WriteRequest, len(keys))
 	for index, key := range keys {
 		writeReqs[index] = types.WriteRequest{
@@ -133,11 +447,127 @@ func BatchDeleteItems(tableName string, keys []map[string]types.AttributeValue)
 		}
 	}
 
-	_, err := svc.BatchWriteItem(context.TODO(), &dynamodb.BatchWriteItemInput{
-		RequestItems: map[string][]types.WriteRequest{
-			tableName: writeReqs,
-		},
-	})
+	failedReqs, err := batchWriteItems(ctx, tableName, writeReqs)
+
+	failedKeys := make([]map[string]types.AttributeValue, len(failedReqs))
+	for index, failedReq := range failedReqs {
+		failedKeys[index] = failedReq.DeleteRequest.Key
+	}
+
+	return failedKeys, err
+}
+
+// BatchPutItems puts records in batches of 25, retrying unprocessed items with backoff.
+// It returns the records that could not be written, alongside any error that caused them to fail.
+func BatchPutItems[T interface{}](ctx context.Context, tableName string, records []T) ([]T, error) {
+	writeReqs := make([]types.WriteRequest, len(records))
+	for index, record := range records {
+		av, err := attributevalue.MarshalMap(record)
+		if err != nil {
+			return records, fmt.Errorf("failed to marshal item: %w", err)
+		}
+
+		writeReqs[index] = types.WriteRequest{
+			PutRequest: &types.PutRequest{
+				Item: av,
+			},
+		}
+	}
+
+	failedReqs, err := batchWriteItems(ctx, tableName, writeReqs)
+
+	failedItems := make([]map[string]types.AttributeValue, len(failedReqs))
+	for index, failedReq := range failedReqs {
+		failedItems[index] = failedReq.PutRequest.Item
+	}
+
+	var failedRecords []T
+	if unmarshalErr := attributevalue.UnmarshalListOfMaps(failedItems, &failedRecords); unmarshalErr != nil {
+		return records, errors.Join(err, fmt.Errorf("%w failed items: %w", proxy.ErrUnmarshal, unmarshalErr))
+	}
+
+	return failedRecords, err
+}
+
+// BatchWriteItem accepts at most 25 requests per call
+const maxBatchWriteSize = 25
+
+// maxBatchWriteAttempts is how many times unprocessed items are resubmitted before giving up on them
+const maxBatchWriteAttempts = 5
+
+const batchWriteBaseDelay = 50 * time.Millisecond
+
+// batchWriteItems sends writeReqs in chunks, resubmitting UnprocessedItems with exponential backoff.
+// A chunk that errors outright is reported as failed and the remaining chunks are still attempted.
+func batchWriteItems(ctx context.Context, tableName string, writeReqs []types.WriteRequest) ([]types.WriteRequest, error) {
+	var failedReqs []types.WriteRequest
+	var errs []error
+
+	for start := 0; start < len(writeReqs); start += maxBatchWriteSize {
+		pendingReqs := writeReqs[start:min(start+maxBatchWriteSize, len(writeReqs))]
+
+		for attempt := 0; len(pendingReqs) > 0; attempt++ {
+			if attempt == maxBatchWriteAttempts {
+				errs = append(errs, fmt.Errorf("gave up on %d unprocessed items after %d attempts: %w", len(pendingReqs), attempt, proxy.ErrThrottled))
+				failedReqs = append(failedReqs, pendingReqs...)
+				break
+			}
+
+			if attempt > 0 {
+				if err := sleep(ctx, backoff(attempt)); err != nil {
+					errs = append(errs, err)
+					failedReqs = append(failedReqs, pendingReqs...)
+					break
+				}
+			}
+
+			result, err := svc.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+				RequestItems: map[string][]types.WriteRequest{
+					tableName: pendingReqs,
+				},
+			})
+			if err != nil {
+				errs = append(errs, wrapError("failed to batch write items", err))
+				failedReqs = append(failedReqs, pendingReqs...)
+				break
+			}
+
+			pendingReqs = result.UnprocessedItems[tableName]
+		}
+	}
+
+	return failedReqs, errors.Join(errs...)
+}
+
+// backoff returns an exponentially growing delay with full jitter for the given attempt
+func backoff(attempt int) time.Duration {
+	maxDelay := batchWriteBaseDelay << attempt
+	return time.Duration(rand.Int63n(int64(maxDelay)))
+}
+
+// sleep waits for d, returning early with the context's error if it is cancelled first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// wrapError tags DynamoDB errors with the matching proxy error so callers can check them with errors.Is
+func wrapError(message string, err error) error {
+	if proxy.IsThrottle(err) {
+		return fmt.Errorf("%s: %w: %w", message, proxy.ErrThrottled, err)
+	}
+
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return fmt.Errorf("%s: %w: %w", message, proxy.ErrNotFound, err)
+	}
 
-	return err
+	return fmt.Errorf("%s: %w", message, err)
 }
@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go"
+)
+
+// Errors returned by the data access layer, check for them with errors.Is.
+// They are always wrapped with the operation that failed.
+var (
+	// ErrNotFound means the table, bucket, object or record does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrThrottled means AWS kept throttling the request after the SDK retries ran out,
+	// the caller may try again later
+	ErrThrottled = errors.New("throttled")
+	// ErrUnmarshal means a stored record could not be decoded into its model
+	ErrUnmarshal = errors.New("failed to unmarshal")
+	// ErrInvalidCursor means a pagination cursor was not one we handed out
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrConditionFailed means a conditional write was rejected because the stored record changed
+	ErrConditionFailed = errors.New("condition failed")
+)
+
+// RetryMaxAttempts is how many times the AWS clients attempt a request before giving up,
+// throttling and transient errors are retried with exponential backoff by the SDK
+const RetryMaxAttempts = 5
+
+// IsThrottle reports whether err is one of the API error codes AWS uses for throttling
+func IsThrottle(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	_, isThrottle := retry.DefaultThrottleErrorCodes[apiErr.ErrorCode()]
+	return isThrottle
+}
+
+// HasErrorCode reports whether err is an AWS API error with one of the given codes
+func HasErrorCode(err error, codes ...string) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	for _, code := range codes {
+		if apiErr.ErrorCode() == code {
+			return true
+		}
+	}
+
+	return false
+}
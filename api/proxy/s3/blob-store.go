@@ -0,0 +1,38 @@
+package s3proxy
+
+import (
+	"api/proxy"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// BlobStore is the subset of S3 the app relies on, buckets are addressed by name
+type BlobStore interface {
+	GetDocumentFile(ctx context.Context, bucketName string, key string) ([]byte, error)
+	GetKeys(ctx context.Context, bucket string, prefix string) ([]string, error)
+	MoveObject(ctx context.Context, key string, fromBucket string, toBucket string) error
+	PutObject(ctx context.Context, key string, bucket string, fileContents []byte) error
+	// DeleteObject succeeds when there is no object with the key
+	DeleteObject(ctx context.Context, bucketName string, key string) error
+	GeneratePresignedUrl(ctx context.Context, bucketName string, key string, contentLength *int64) (string, error)
+	// GeneratePresignedDownloadUrl returns an url the object can be downloaded from for a few
+	// minutes, without credentials
+	GeneratePresignedDownloadUrl(ctx context.Context, bucketName string, key string) (string, error)
+}
+
+// GetDocument retrieves a document from the specified bucket and key, and unmarshals it into the provided struct
+func GetDocument[T interface{}](ctx context.Context, store BlobStore, bucketName string, key string) (T, error) {
+	var v T
+
+	body, err := store.GetDocumentFile(ctx, bucketName, key)
+	if err != nil {
+		return v, err
+	}
+
+	if err := json.Unmarshal(body, &v); err != nil {
+		return v, fmt.Errorf("%w json %q: %w", proxy.ErrUnmarshal, key, err)
+	}
+
+	return v, nil
+}
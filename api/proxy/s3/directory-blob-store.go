@@ -0,0 +1,250 @@
+package s3proxy
+
+import (
+	"api/proxy"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DirectoryBlobStore is a BlobStore that keeps every bucket as a directory under root.
+// Presigned uploads and downloads point back at the api itself (see routes.UploadBlob and
+// routes.DownloadBlob), signed with a secret that only lives as long as the process.
+type DirectoryBlobStore struct {
+	root    string
+	baseUrl string
+	secret  []byte
+}
+
+// NewDirectoryBlobStore creates a store rooted at root. baseUrl is where the api is served
+// from, e.g. http://localhost:8080, and is used to build presigned upload urls.
+func NewDirectoryBlobStore(root string, baseUrl string) (*DirectoryBlobStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("unable to create blob directory %q, %v", root, err)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("unable to generate presign secret, %v", err)
+	}
+
+	return &DirectoryBlobStore{
+		root:    root,
+		baseUrl: strings.TrimSuffix(baseUrl, "/"),
+		secret:  secret,
+	}, nil
+}
+
+func (s *DirectoryBlobStore) GetDocumentFile(ctx context.Context, bucketName string, key string) ([]byte, error) {
+	path, err := s.path(bucketName, key)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("unable to download item %q: %w", key, proxy.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to download item %q, %v", key, err)
+	}
+
+	return body, nil
+}
+
+func (s *DirectoryBlobStore) GetKeys(ctx context.Context, bucket string, prefix string) ([]string, error) {
+	bucketPath, err := s.path(bucket, "")
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0)
+	err = filepath.WalkDir(bucketPath, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(bucketPath, path)
+		if err != nil {
+			return err
+		}
+
+		key := filepath.ToSlash(relativePath)
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return keys, fmt.Errorf("unable to list items in bucket %q, %v", bucket, err)
+	}
+
+	// S3 lists keys in lexicographical order
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+func (s *DirectoryBlobStore) MoveObject(ctx context.Context, key string, fromBucket string, toBucket string) error {
+	fromPath, err := s.path(fromBucket, key)
+	if err != nil {
+		return err
+	}
+
+	toPath, err := s.path(toBucket, key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(toPath), 0o755); err != nil {
+		return fmt.Errorf("unable to copy object %q from bucket %q to bucket %q, %v", key, fromBucket, toBucket, err)
+	}
+
+	err = os.Rename(fromPath, toPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("unable to copy object %q from bucket %q to bucket %q: %w", key, fromBucket, toBucket, proxy.ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to copy object %q from bucket %q to bucket %q, %v", key, fromBucket, toBucket, err)
+	}
+
+	return nil
+}
+
+func (s *DirectoryBlobStore) PutObject(ctx context.Context, key string, bucket string, fileContents []byte) error {
+	path, err := s.path(bucket, key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("unable to put object %q in bucket %q, %v", key, bucket, err)
+	}
+
+	return os.WriteFile(path, fileContents, 0o644)
+}
+
+func (s *DirectoryBlobStore) DeleteObject(ctx context.Context, bucketName string, key string) error {
+	path, err := s.path(bucketName, key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("unable to delete object %q from bucket %q, %v", key, bucketName, err)
+	}
+
+	return nil
+}
+
+func (s *DirectoryBlobStore) GeneratePresignedUrl(ctx context.Context, bucketName string, key string, contentLength *int64) (string, error) {
+	var length int64
+	if contentLength != nil {
+		length = *contentLength
+	}
+
+	return s.presign(http.MethodPut, bucketName, key, length)
+}
+
+func (s *DirectoryBlobStore) GeneratePresignedDownloadUrl(ctx context.Context, bucketName string, key string) (string, error) {
+	return s.presign(http.MethodGet, bucketName, key, 0)
+}
+
+// presign builds an url to the blob routes of the api that only method is let through on
+func (s *DirectoryBlobStore) presign(method string, bucketName string, key string, contentLength int64) (string, error) {
+	if _, err := s.path(bucketName, key); err != nil {
+		return "", err
+	}
+
+	expires := time.Now().Add(presignedUrlExpiration).Unix()
+
+	query := url.Values{}
+	query.Set("contentLength", strconv.FormatInt(contentLength, 10))
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", s.sign(method, bucketName, key, contentLength, expires))
+
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return fmt.Sprintf("%s/blobs/%s/%s?%s", s.baseUrl, url.PathEscape(bucketName), strings.Join(segments, "/"), query.Encode()), nil
+}
+
+// VerifyPresignedUpload checks the query string of an url built by GeneratePresignedUrl
+// against the upload being made to it
+func (s *DirectoryBlobStore) VerifyPresignedUpload(bucketName string, key string, query url.Values, contentLength int64) error {
+	return s.verify(http.MethodPut, bucketName, key, query, contentLength)
+}
+
+// VerifyPresignedDownload checks the query string of an url built by GeneratePresignedDownloadUrl
+func (s *DirectoryBlobStore) VerifyPresignedDownload(bucketName string, key string, query url.Values) error {
+	return s.verify(http.MethodGet, bucketName, key, query, 0)
+}
+
+func (s *DirectoryBlobStore) verify(method string, bucketName string, key string, query url.Values, contentLength int64) error {
+	expectedLength, err := strconv.ParseInt(query.Get("contentLength"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid contentLength")
+	}
+
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid expires")
+	}
+
+	signature := s.sign(method, bucketName, key, expectedLength, expires)
+	if !hmac.Equal([]byte(signature), []byte(query.Get("signature"))) {
+		return fmt.Errorf("signature does not match")
+	}
+
+	if time.Now().Unix() > expires {
+		return fmt.Errorf("presigned url has expired")
+	}
+
+	if expectedLength != 0 && contentLength != expectedLength {
+		return fmt.Errorf("content length %d does not match signed length %d", contentLength, expectedLength)
+	}
+
+	return nil
+}
+
+// sign covers the method too, so an upload url can't be used to download the blob and the other way around
+func (s *DirectoryBlobStore) sign(method string, bucketName string, key string, contentLength int64, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%d\n%d", method, bucketName, key, contentLength, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// path resolves a bucket and key to a file under root, refusing anything that escapes it
+func (s *DirectoryBlobStore) path(bucketName string, key string) (string, error) {
+	if bucketName == "" || strings.ContainsAny(bucketName, `/\`) || bucketName == "." || bucketName == ".." {
+		return "", fmt.Errorf("invalid bucket %q", bucketName)
+	}
+
+	bucketPath := filepath.Join(s.root, bucketName)
+	path := filepath.Join(bucketPath, filepath.FromSlash(key))
+
+	if path != bucketPath && !strings.HasPrefix(path, bucketPath+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+
+	return path, nil
+}
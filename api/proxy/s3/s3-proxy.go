@@ -1,59 +1,41 @@
 package s3proxy
 
 import (
+	"api/proxy"
 	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
-var svc *s3.Client
-var presignClient *s3.PresignClient
-
-func init() {
-	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion("ap-southeast-2"))
-
-	if err != nil {
-		log.Fatalf("failed to load configuration, %v", err)
-	}
-
-	svc = s3.NewFromConfig(cfg)
-	presignClient = s3.NewPresignClient(svc)
+// S3BlobStore is a BlobStore backed by real S3 buckets
+type S3BlobStore struct {
+	svc           *s3.Client
+	presignClient *s3.PresignClient
 }
 
-// GetDocument retrieves a document from the specified S3 bucket and key, and unmarshals it into the provided struct
-func GetDocument[T interface{}](bucketName string, key string) T {
-	result, err := svc.GetObject(context.TODO(), &s3.GetObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(key),
-	})
-
+func NewS3BlobStore(ctx context.Context, region string) (*S3BlobStore, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region), config.WithRetryMaxAttempts(proxy.RetryMaxAttempts))
 	if err != nil {
-		log.Fatalf("unable to download item %q, %v", key, err)
+		return nil, fmt.Errorf("failed to load configuration, %v", err)
 	}
-	defer result.Body.Close()
 
-	body, err := io.ReadAll(result.Body)
-	if err != nil {
-		log.Fatalf("failed to read object body: %v", err)
-	}
-
-	var v T
-	if err := json.Unmarshal(body, &v); err != nil {
-		log.Fatalf("failed to unmarshal json: %v", err)
-	}
+	svc := s3.NewFromConfig(cfg)
 
-	return v
+	return &S3BlobStore{
+		svc:           svc,
+		presignClient: s3.NewPresignClient(svc),
+	}, nil
 }
 
-func GetKeys(bucket string, prefix string) ([]string, error) {
+func (s *S3BlobStore) GetKeys(ctx context.Context, bucket string, prefix string) ([]string, error) {
 	var keys []string
 	var continuationToken *string
 
@@ -65,9 +47,9 @@ func GetKeys(bucket string, prefix string) ([]string, error) {
 		}
 
 		// List objects in the bucket
-		result, err := svc.ListObjectsV2(context.TODO(), input)
+		result, err := s.svc.ListObjectsV2(ctx, input)
 		if err != nil {
-			return keys, fmt.Errorf("unable to list items in bucket %q, %v", bucket, err)
+			return keys, wrapError(fmt.Sprintf("unable to list items in bucket %q", bucket), err)
 		}
 
 		// Append keys to the slice
@@ -86,14 +68,14 @@ func GetKeys(bucket string, prefix string) ([]string, error) {
 	return keys, nil
 }
 
-func GetDocumentFile(bucketName string, key string) ([]byte, error) {
-	result, err := svc.GetObject(context.TODO(), &s3.GetObjectInput{
+func (s *S3BlobStore) GetDocumentFile(ctx context.Context, bucketName string, key string) ([]byte, error) {
+	result, err := s.svc.GetObject(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(bucketName),
 		Key:    aws.String(key),
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("unable to download item %q, %v", key, err)
+		return nil, wrapError(fmt.Sprintf("unable to download item %q", key), err)
 	}
 	defer result.Body.Close()
 
@@ -105,7 +87,7 @@ func GetDocumentFile(bucketName string, key string) ([]byte, error) {
 	return body, nil
 }
 
-func MoveObject(key string, fromBucket string, toBucket string) error {
+func (s *S3BlobStore) MoveObject(ctx context.Context, key string, fromBucket string, toBucket string) error {
 	copySource := fmt.Sprintf("%s/%s", fromBucket, key)
 
 	input := &s3.CopyObjectInput{
@@ -113,24 +95,24 @@ func MoveObject(key string, fromBucket string, toBucket string) error {
 		CopySource: aws.String(copySource),
 		Key:        aws.String(key),
 	}
-	_, err := svc.CopyObject(context.TODO(), input)
+	_, err := s.svc.CopyObject(ctx, input)
 
 	if err != nil {
-		return fmt.Errorf("unable to copy object %q from bucket %q to bucket %q, %v", key, fromBucket, toBucket, err)
+		return wrapError(fmt.Sprintf("unable to copy object %q from bucket %q to bucket %q", key, fromBucket, toBucket), err)
 	}
 
-	_, err = svc.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+	_, err = s.svc.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(fromBucket),
 		Key:    aws.String(key),
 	})
 	if err != nil {
-		return fmt.Errorf("unable to delete object %q from bucket %q, %v", key, fromBucket, err)
+		return wrapError(fmt.Sprintf("unable to delete object %q from bucket %q", key, fromBucket), err)
 	}
 
 	return nil
 }
 
-func PutObject(key string, bucket string, fileContents []byte) error {
+func (s *S3BlobStore) PutObject(ctx context.Context, key string, bucket string, fileContents []byte) error {
 	body := bytes.NewReader(fileContents)
 	contentLength := body.Size()
 
@@ -141,22 +123,72 @@ func PutObject(key string, bucket string, fileContents []byte) error {
 		ContentLength: &contentLength,
 	}
 
-	_, err := svc.PutObject(context.TODO(), input)
-	return err
+	_, err := s.svc.PutObject(ctx, input)
+	if err != nil {
+		return wrapError(fmt.Sprintf("unable to put object %q in bucket %q", key, bucket), err)
+	}
+
+	return nil
+}
+
+func (s *S3BlobStore) DeleteObject(ctx context.Context, bucketName string, key string) error {
+	_, err := s.svc.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return wrapError(fmt.Sprintf("unable to delete object %q from bucket %q", key, bucketName), err)
+	}
+
+	return nil
 }
 
-func GeneratePresignedUrl(bucketName string, key string, contentLength *int64) (string, error) {
+func (s *S3BlobStore) GeneratePresignedUrl(ctx context.Context, bucketName string, key string, contentLength *int64) (string, error) {
 	request := &s3.PutObjectInput{
 		Bucket:        aws.String(bucketName),
 		Key:           aws.String(key),
 		ContentLength: contentLength,
 	}
 
-	result, err := presignClient.PresignPutObject(context.TODO(), request, setPresignedUrlExpiration)
+	result, err := s.presignClient.PresignPutObject(ctx, request, setPresignedUrlExpiration)
+	if err != nil {
+		return "", err
+	}
 
-	return result.URL, err
+	return result.URL, nil
+}
+
+func (s *S3BlobStore) GeneratePresignedDownloadUrl(ctx context.Context, bucketName string, key string) (string, error) {
+	request := &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	}
+
+	result, err := s.presignClient.PresignGetObject(ctx, request, setPresignedUrlExpiration)
+	if err != nil {
+		return "", err
+	}
+
+	return result.URL, nil
+}
+
+// wrapError tags S3 errors with the matching proxy error so callers can check them with errors.Is
+func wrapError(message string, err error) error {
+	if proxy.IsThrottle(err) {
+		return fmt.Errorf("%s: %w: %w", message, proxy.ErrThrottled, err)
+	}
+
+	var noSuchKey *types.NoSuchKey
+	var noSuchBucket *types.NoSuchBucket
+	if errors.As(err, &noSuchKey) || errors.As(err, &noSuchBucket) || proxy.HasErrorCode(err, "NotFound") {
+		return fmt.Errorf("%s: %w: %w", message, proxy.ErrNotFound, err)
+	}
+
+	return fmt.Errorf("%s: %w", message, err)
 }
 
 func setPresignedUrlExpiration(opts *s3.PresignOptions) {
-	opts.Expires = time.Duration(5 * time.Minute)
+	opts.Expires = presignedUrlExpiration
 }
+
+const presignedUrlExpiration = 5 * time.Minute
@@ -4,8 +4,10 @@ import (
 	"api/models"
 	businessmodel "api/models/business"
 	"api/providers"
+	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -16,7 +18,7 @@ import (
 	"github.com/otiai10/gosseract/v2"
 )
 
-func ConvertAllReceiptsIntoText(receiptContext models.ReceiptContext) (string, error) {
+func ConvertAllReceiptsIntoText(ctx context.Context, receipts *providers.ReceiptProvider, receiptContext models.ReceiptContext) (string, error) {
 	var prefix string
 	if receiptContext == models.BusinessReceipt {
 		prefix = "business/"
@@ -24,7 +26,7 @@ func ConvertAllReceiptsIntoText(receiptContext models.ReceiptContext) (string, e
 		prefix = ""
 	}
 
-	keys, err := providers.GetUnprocessedReceiptKeys(prefix)
+	keys, err := receipts.GetUnprocessedReceiptKeys(ctx, prefix)
 	if err != nil {
 		return "", fmt.Errorf("could not get keys in bucket: %v", err)
 	}
@@ -32,14 +34,18 @@ func ConvertAllReceiptsIntoText(receiptContext models.ReceiptContext) (string, e
 	completeText := ""
 	var wg sync.WaitGroup
 
-	someKeys := keys[0:5]
+	someKeys := keys[0:min(5, len(keys))]
 	wg.Add(len(someKeys))
 
 	for _, key := range someKeys {
 		go func(key string) {
-			text, err := ConvertUnprocessedReceiptToText(key)
+			text, err := ConvertUnprocessedReceiptToText(ctx, receipts, key)
 			if err == nil {
 				completeText += text
+
+				if err := receipts.SaveReceiptText(ctx, key, text); err != nil {
+					log.Printf("could not save text of receipt %q: %v\n", key, err)
+				}
 			}
 			wg.Done()
 		}(key)
@@ -62,17 +68,17 @@ func ConvertAllReceiptsIntoText(receiptContext models.ReceiptContext) (string, e
 /**
  *
  */
-func ConvertUnprocessedReceiptToText(key string) (string, error) {
+func ConvertUnprocessedReceiptToText(ctx context.Context, receipts *providers.ReceiptProvider, key string) (string, error) {
 	extension := filepath.Ext(key)
-	data, err := providers.GetUnprocessedReceipt(key)
+	data, err := receipts.GetUnprocessedReceipt(ctx, key)
 	if err != nil {
 		return "", fmt.Errorf("could not get unprocessed receipt: %v", err)
 	}
 
 	switch extension {
-	case "png":
+	case ".png":
 		return ConvertUnprocessedReceiptPngToText(data)
-	case "pdf":
+	case ".pdf":
 		return ConvertUnprocessedReceiptPdfToText(data)
 	default:
 		return ConvertUnprocessedReceiptPdfToText(data)
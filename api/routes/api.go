@@ -0,0 +1,93 @@
+package routes
+
+import (
+	"api/accounts"
+	"api/auth"
+	"api/config"
+	"api/events"
+	"api/jobs"
+	"api/layouts"
+	"api/ops"
+	"api/providers"
+	s3proxy "api/proxy/s3"
+)
+
+// Api holds the repositories shared by the route handlers
+type Api struct {
+	Groceries    providers.GroceryRepository
+	Users        providers.UserRepository
+	Households   providers.HouseholdRepository
+	Invites      providers.InviteRepository
+	Devices      providers.DeviceRepository
+	Layouts      providers.LayoutRepository
+	Lists        providers.ListRepository
+	Staples      providers.StapleRepository
+	Blobs        s3proxy.BlobStore
+	Catalog      *providers.CatalogProvider
+	Receipts     *providers.ReceiptProvider
+	Tokens       *auth.Verifier
+	Events       events.Bus
+	Deletions    *jobs.HouseholdDeletionJob
+	StapleJob    *jobs.StapleJob
+	LayoutEditor *layouts.Editor
+	Ops          *ops.Processor
+	Accounts     *accounts.Accounts
+}
+
+// NewDynamoApi wires the handlers to the DynamoDB tables named in cfg
+func NewDynamoApi(cfg *config.Config, blobs s3proxy.BlobStore, tokens *auth.Verifier) *Api {
+	users := providers.NewDynamoUserRepository(cfg.Tables.Users)
+	bus := events.NewMemoryBus()
+
+	api := &Api{
+		Groceries:  events.NewPublishingGroceryRepository(providers.NewDynamoGroceryRepository(cfg.Tables.Groceries), bus),
+		Users:      users,
+		Households: providers.NewDynamoHouseholdRepository(cfg.Tables.Households, cfg.Tables.Members, users),
+		Invites:    providers.NewDynamoInviteRepository(cfg.Tables.Invites),
+		Devices:    providers.NewDynamoDeviceRepository(cfg.Tables.Devices, cfg.Tables.Pairings),
+		Layouts:    providers.NewDynamoLayoutRepository(cfg.Tables.Layouts),
+		Lists:      events.NewPublishingListRepository(providers.NewDynamoListRepository(cfg.Tables.Lists), bus),
+		Staples:    providers.NewDynamoStapleRepository(cfg.Tables.Staples),
+		Blobs:      blobs,
+		Catalog:    providers.NewCatalogProvider(blobs, cfg.Buckets.Catalog, cfg.CatalogKey),
+		Receipts:   providers.NewReceiptProvider(blobs, cfg.Buckets.UnprocessedReceipts, cfg.Buckets.ProcessedReceipts, cfg.MaxReceiptSize),
+		Tokens:     tokens,
+		Events:     bus,
+	}
+	api.Deletions = &jobs.HouseholdDeletionJob{Households: api.Households, Users: api.Users, Groceries: api.Groceries, Invites: api.Invites, Layouts: api.Layouts, Lists: api.Lists, Staples: api.Staples}
+	api.LayoutEditor = &layouts.Editor{Groceries: api.Groceries, Layouts: api.Layouts}
+	api.StapleJob = &jobs.StapleJob{Staples: api.Staples, Lists: api.Lists, Groceries: api.Groceries, Layouts: api.LayoutEditor}
+	api.Ops = &ops.Processor{Groceries: api.Groceries, Layouts: api.LayoutEditor, Results: providers.NewDynamoGroceryOpRepository(cfg.Tables.Ops)}
+	api.Accounts = &accounts.Accounts{Users: api.Users, Households: api.Households, Groceries: api.Groceries, Invites: api.Invites, Devices: api.Devices, Lists: api.Lists, Staples: api.Staples, Receipts: api.Receipts, Exports: providers.NewExportProvider(blobs, cfg.Buckets.Exports)}
+
+	return api
+}
+
+// NewMemoryApi wires the handlers to in-process storage, nothing is persisted
+func NewMemoryApi(cfg *config.Config, blobs s3proxy.BlobStore, tokens *auth.Verifier) *Api {
+	users := providers.NewMemoryUserRepository()
+	bus := events.NewMemoryBus()
+
+	api := &Api{
+		Groceries:  events.NewPublishingGroceryRepository(providers.NewMemoryGroceryRepository(), bus),
+		Users:      users,
+		Households: providers.NewMemoryHouseholdRepository(users),
+		Invites:    providers.NewMemoryInviteRepository(),
+		Devices:    providers.NewMemoryDeviceRepository(),
+		Layouts:    providers.NewMemoryLayoutRepository(),
+		Lists:      events.NewPublishingListRepository(providers.NewMemoryListRepository(), bus),
+		Staples:    providers.NewMemoryStapleRepository(),
+		Blobs:      blobs,
+		Catalog:    providers.NewCatalogProvider(blobs, cfg.Buckets.Catalog, cfg.CatalogKey),
+		Receipts:   providers.NewReceiptProvider(blobs, cfg.Buckets.UnprocessedReceipts, cfg.Buckets.ProcessedReceipts, cfg.MaxReceiptSize),
+		Tokens:     tokens,
+		Events:     bus,
+	}
+	api.Deletions = &jobs.HouseholdDeletionJob{Households: api.Households, Users: api.Users, Groceries: api.Groceries, Invites: api.Invites, Layouts: api.Layouts, Lists: api.Lists, Staples: api.Staples}
+	api.LayoutEditor = &layouts.Editor{Groceries: api.Groceries, Layouts: api.Layouts}
+	api.StapleJob = &jobs.StapleJob{Staples: api.Staples, Lists: api.Lists, Groceries: api.Groceries, Layouts: api.LayoutEditor}
+	api.Ops = &ops.Processor{Groceries: api.Groceries, Layouts: api.LayoutEditor, Results: providers.NewMemoryGroceryOpRepository()}
+	api.Accounts = &accounts.Accounts{Users: api.Users, Households: api.Households, Groceries: api.Groceries, Invites: api.Invites, Devices: api.Devices, Lists: api.Lists, Staples: api.Staples, Receipts: api.Receipts, Exports: providers.NewExportProvider(blobs, cfg.Buckets.Exports)}
+
+	return api
+}
@@ -0,0 +1,211 @@
+package routes
+
+import (
+	"api/models"
+	"api/providers"
+	"api/proxy"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	userContextKey   = "user"
+	deviceContextKey = "device"
+)
+
+// maxDeviceNameLength bounds the names devices are listed as, User-Agents can get long
+const maxDeviceNameLength = 100
+
+// Authenticate resolves the calling device from the bearer token and the user it is linked to.
+// Devices and their users are created the first time their token is seen.
+func (api *Api) Authenticate(c *gin.Context) {
+	token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !found || token == "" {
+		c.Header("WWW-Authenticate", "Bearer")
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
+		return
+	}
+
+	subject, err := api.Tokens.Verify(token)
+	if err != nil {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	device, err := api.resolveDevice(c.Request.Context(), subject, c.Request.UserAgent())
+	if err != nil {
+		respondWithError(c, err)
+		c.Abort()
+		return
+	}
+
+	if device.RevokedAt != 0 {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "device was unlinked"})
+		return
+	}
+
+	user, err := api.resolveUser(c.Request.Context(), device.UserId)
+	if err != nil {
+		respondWithError(c, err)
+		c.Abort()
+		return
+	}
+
+	c.Set(deviceContextKey, device)
+	c.Set(userContextKey, user)
+	c.Next()
+}
+
+// resolveDevice returns the device with the given token subject. A device that was never seen is
+// its own user, with the subject as the user id, which is how every user was identified before
+// devices could be linked.
+func (api *Api) resolveDevice(ctx context.Context, subject string, userAgent string) (models.Device, error) {
+	device, err := api.Devices.GetDevice(ctx, subject)
+	if !errors.Is(err, proxy.ErrNotFound) {
+		return device, err
+	}
+
+	device = models.Device{
+		Id:       subject,
+		UserId:   subject,
+		Name:     truncate(userAgent, maxDeviceNameLength),
+		LinkedAt: time.Now().Unix(),
+	}
+
+	return device, api.Devices.UpdateDevice(ctx, device)
+}
+
+func (api *Api) resolveUser(ctx context.Context, userId string) (models.User, error) {
+	results, err := api.Users.GetUsers(ctx, userId)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if len(results) > 0 {
+		return results[0], nil
+	}
+
+	user := models.User{
+		Id:           userId,
+		HouseholdIds: []string{},
+	}
+
+	err = api.Users.UpdateUser(ctx, user)
+	if errors.Is(err, proxy.ErrConditionFailed) {
+		// Another request of the user's got to create them first
+		return providers.GetUser(ctx, api.Users, userId)
+	}
+
+	return user, err
+}
+
+// truncate cuts s down to at most limit bytes without splitting a character
+func truncate(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+
+	for limit > 0 && !utf8.RuneStart(s[limit]) {
+		limit--
+	}
+
+	return s[:limit]
+}
+
+// currentUser is the caller resolved by Authenticate
+func currentUser(c *gin.Context) models.User {
+	return c.MustGet(userContextKey).(models.User)
+}
+
+// currentDevice is the device the caller signed in on, resolved by Authenticate
+func currentDevice(c *gin.Context) models.Device {
+	return c.MustGet(deviceContextKey).(models.Device)
+}
+
+// authorizeHousehold responds with 403 and returns false unless the caller is a member of the household
+func authorizeHousehold(c *gin.Context, householdId string) bool {
+	if slices.Contains(currentUser(c).HouseholdIds, householdId) {
+		return true
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{"error": "not a member of household " + householdId})
+	return false
+}
+
+// authorizeUser responds with 403 and returns false unless userId is the caller
+func authorizeUser(c *gin.Context, userId string) bool {
+	if currentUser(c).Id == userId {
+		return true
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{"error": "cannot act on behalf of another user"})
+	return false
+}
+
+// authorizeHouseholdRole responds with 403 and returns false unless the caller has one of roles in the household,
+// otherwise it returns the household and the caller's role in it.
+// Households created before owners existed are managed by all of their members alike.
+func (api *Api) authorizeHouseholdRole(c *gin.Context, householdId string, roles ...models.HouseholdRole) (models.Household, models.HouseholdRole, bool) {
+	if !authorizeHousehold(c, householdId) {
+		return models.Household{}, "", false
+	}
+
+	household, err := api.Households.GetHousehold(c.Request.Context(), householdId)
+	if err != nil {
+		respondWithError(c, err)
+		return models.Household{}, "", false
+	}
+
+	role := models.OwnerRole
+	if household.OwnerId != "" {
+		role, err = api.memberRole(c.Request.Context(), household, currentUser(c).Id)
+		if err != nil {
+			respondWithError(c, err)
+			return models.Household{}, "", false
+		}
+	}
+
+	if !slices.Contains(roles, role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("%s of household %s cannot do this", role, householdId)})
+		return models.Household{}, "", false
+	}
+
+	return household, role, true
+}
+
+// memberRole returns the role of a user in the household, failing with proxy.ErrNotFound when they
+// aren't a member. Members that joined before the members index existed only appear on their user record.
+func (api *Api) memberRole(ctx context.Context, household models.Household, userId string) (models.HouseholdRole, error) {
+	if household.OwnerId == userId {
+		return models.OwnerRole, nil
+	}
+
+	member, err := api.Households.GetMember(ctx, household.Id, userId)
+	if err == nil {
+		return member.Role, nil
+	}
+	if !errors.Is(err, proxy.ErrNotFound) {
+		return "", err
+	}
+
+	user, err := providers.GetUser(ctx, api.Users, userId)
+	if err != nil {
+		return "", err
+	}
+
+	if !slices.Contains(user.HouseholdIds, household.Id) {
+		return "", fmt.Errorf("user [%s] is not a member of household [%s]: %w", userId, household.Id, proxy.ErrNotFound)
+	}
+
+	return models.MemberRole, nil
+}
@@ -0,0 +1,66 @@
+package routes
+
+import (
+	s3proxy "api/proxy/s3"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UploadBlob accepts uploads to the presigned urls handed out by a DirectoryBlobStore,
+// standing in for S3 when running locally
+func (api *Api) UploadBlob(c *gin.Context) {
+	store, ok := api.Blobs.(*s3proxy.DirectoryBlobStore)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{})
+		return
+	}
+
+	bucket := c.Param("bucket")
+	key := strings.TrimPrefix(c.Param("key"), "/")
+
+	if err := store.VerifyPresignedUpload(bucket, key, c.Request.URL.Query(), c.Request.ContentLength); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := store.PutObject(c.Request.Context(), key, bucket, body); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// DownloadBlob serves the presigned download urls handed out by a DirectoryBlobStore
+func (api *Api) DownloadBlob(c *gin.Context) {
+	store, ok := api.Blobs.(*s3proxy.DirectoryBlobStore)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{})
+		return
+	}
+
+	bucket := c.Param("bucket")
+	key := strings.TrimPrefix(c.Param("key"), "/")
+
+	if err := store.VerifyPresignedDownload(bucket, key, c.Request.URL.Query()); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	body, err := store.GetDocumentFile(c.Request.Context(), bucket, key)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/octet-stream", body)
+}
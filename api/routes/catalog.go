@@ -1,19 +1,17 @@
 package routes
 
 import (
-	"api/models"
-	"api/providers"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-var catalog models.Catalog
+func (api *Api) GetCatalog(c *gin.Context) {
+	catalog, err := api.Catalog.GetCatalog(c.Request.Context())
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
 
-func init() {
-	catalog = providers.GetCatalog()
-}
-
-func GetCatalog(c *gin.Context) {
 	c.JSON(http.StatusOK, catalog)
 }
@@ -0,0 +1,145 @@
+package routes
+
+import (
+	"api/models"
+	"api/providers"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pairingCodeLifetime is how long a pairing code can be redeemed, it only has to last while
+// the user picks up their other device
+const pairingCodeLifetime = 10 * time.Minute
+
+// CreatePairingCode returns a code another device can redeem with PairDevice to become the user
+func (api *Api) CreatePairingCode(c *gin.Context) {
+	id := c.Param("id")
+	if !authorizeUser(c, id) {
+		return
+	}
+
+	now := time.Now()
+	pairing, err := api.Devices.CreatePairingCode(c.Request.Context(), models.PairingCode{
+		UserId:    id,
+		CreatedBy: currentDevice(c).Id,
+		CreatedAt: now.Unix(),
+		ExpiresAt: now.Add(pairingCodeLifetime).Unix(),
+	})
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pairing)
+}
+
+// PairDevice redeems a pairing code and links the calling device to the user who created it.
+// Whatever the device was signed in as before is left as it is, it is just no longer reachable from this device.
+func (api *Api) PairDevice(c *gin.Context) {
+	var request models.PairDeviceRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if utf8.RuneCountInString(name) > maxDeviceNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("name must be at most %d characters", maxDeviceNameLength)})
+		return
+	}
+
+	pairing, err := api.Devices.RedeemPairingCode(c.Request.Context(), providers.NormalizeShortCode(request.Code))
+	if errors.Is(err, providers.ErrPairingCodeExpired) {
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	user, err := providers.GetUser(c.Request.Context(), api.Users, pairing.UserId)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	device := currentDevice(c)
+	if device.UserId != user.Id {
+		device.UserId = user.Id
+		device.LinkedAt = time.Now().Unix()
+	}
+	if name != "" {
+		device.Name = name
+	}
+
+	if err := api.Devices.UpdateDevice(c.Request.Context(), device); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// GetUserDevices lists the devices the user is signed in on, revoked ones are left out
+func (api *Api) GetUserDevices(c *gin.Context) {
+	id := c.Param("id")
+	if !authorizeUser(c, id) {
+		return
+	}
+
+	devices, err := api.Devices.GetUserDevices(c.Request.Context(), id)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	linked := make([]models.Device, 0, len(devices))
+	for _, device := range devices {
+		if device.RevokedAt != 0 {
+			continue
+		}
+
+		device.Current = device.Id == currentDevice(c).Id
+		linked = append(linked, device)
+	}
+
+	c.JSON(http.StatusOK, linked)
+}
+
+// RevokeDevice signs the device out of the user for good, its tokens are refused from then on
+func (api *Api) RevokeDevice(c *gin.Context) {
+	id := c.Param("id")
+	if !authorizeUser(c, id) {
+		return
+	}
+
+	deviceId := c.Param("deviceId")
+
+	device, err := api.Devices.GetDevice(c.Request.Context(), deviceId)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	if device.UserId != id || device.RevokedAt != 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no device " + deviceId + " is linked to user " + id})
+		return
+	}
+
+	device.RevokedAt = time.Now().Unix()
+
+	if err := api.Devices.UpdateDevice(c.Request.Context(), device); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"api/proxy"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// retryAfterSeconds is how long clients are asked to wait after the data layer was throttled
+const retryAfterSeconds = "1"
+
+// respondWithError maps errors from the data access layer to a JSON error response
+func respondWithError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, proxy.ErrInvalidCursor):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, proxy.ErrConditionFailed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, proxy.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, proxy.ErrThrottled):
+		c.Header("Retry-After", retryAfterSeconds)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		log.Printf("%s %s failed: %v\n", c.Request.Method, c.FullPath(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
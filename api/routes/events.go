@@ -0,0 +1,178 @@
+package routes
+
+import (
+	"api/models"
+	"api/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultEventsWait is how long a long poll waits for events unless told otherwise,
+	// API Gateway gives up on a request after 30 seconds so it can wait maxEventsWait at most
+	defaultEventsWait = 20 * time.Second
+	maxEventsWait     = 25 * time.Second
+	// maxEventsPerPoll bounds the events returned by a single long poll
+	maxEventsPerPoll = 100
+	// eventStreamLifetime ends streams after a while, the client reconnects and has its
+	// membership of the household checked again
+	eventStreamLifetime = 5 * time.Minute
+	// eventStreamHeartbeat keeps proxies from closing streams that are idle
+	eventStreamHeartbeat = 15 * time.Second
+	// eventStreamRetry is how many milliseconds EventSource clients wait before reconnecting
+	eventStreamRetry = 1000
+)
+
+// GetHouseholdEvents delivers the household's events after the since query parameter or the
+// Last-Event-ID header. Clients accepting text/event-stream get a stream of server-sent events,
+// others get a long poll that returns once there are events or wait seconds have passed.
+// Responses can't be streamed from Lambda, there event streams end after a long poll and
+// EventSource clients reconnect for the next one.
+func (api *Api) GetHouseholdEvents(c *gin.Context) {
+	householdId := c.Param("householdId")
+	if !authorizeHousehold(c, householdId) {
+		return
+	}
+
+	since := c.Query("since")
+	if lastEventId := c.GetHeader("Last-Event-ID"); lastEventId != "" {
+		since = lastEventId
+	}
+
+	wait := defaultEventsWait
+	if waitParam, ok := c.GetQuery("wait"); ok {
+		seconds, err := strconv.Atoi(waitParam)
+		if err != nil || seconds < 0 || time.Duration(seconds)*time.Second > maxEventsWait {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("wait must be between 0 and %d seconds", int(maxEventsWait.Seconds()))})
+			return
+		}
+		wait = time.Duration(seconds) * time.Second
+	}
+
+	_, onLambda := utils.APIGatewayRequest(c.Request.Context())
+	streaming := strings.Contains(c.GetHeader("Accept"), "text/event-stream")
+
+	if streaming && !onLambda {
+		api.streamHouseholdEvents(c, householdId, since)
+		return
+	}
+
+	events, lastEventId, err := api.pollHouseholdEvents(c.Request.Context(), householdId, since, wait)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	if streaming {
+		writeEventStreamHeaders(c)
+		for _, event := range events {
+			writeEvent(c, event)
+		}
+		fmt.Fprintf(c.Writer, "id: %s\n\n", lastEventId)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.HouseholdEventsResponse{
+		Events:      events,
+		LastEventId: lastEventId,
+	})
+}
+
+// pollHouseholdEvents waits up to wait for the household's events after since, then returns
+// them along with the id to poll from next
+func (api *Api) pollHouseholdEvents(ctx context.Context, householdId string, since string, wait time.Duration) ([]models.HouseholdEvent, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	subscription, position, err := api.Events.Subscribe(ctx, householdId, since)
+	if err != nil {
+		return nil, "", err
+	}
+
+	lastEventId := position
+	if since != "" {
+		lastEventId = since
+	}
+
+	events := make([]models.HouseholdEvent, 0)
+
+	// Wait for the first event, then take whatever else is queued up
+	event, ok := <-subscription
+	for ok {
+		events = append(events, event)
+		lastEventId = event.Id
+
+		if len(events) == maxEventsPerPoll {
+			break
+		}
+
+		select {
+		case event, ok = <-subscription:
+		default:
+			ok = false
+		}
+	}
+
+	return events, lastEventId, nil
+}
+
+func (api *Api) streamHouseholdEvents(c *gin.Context, householdId string, since string) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), eventStreamLifetime)
+	defer cancel()
+
+	subscription, position, err := api.Events.Subscribe(ctx, householdId, since)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	// The server's write timeout is meant for ordinary responses
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(eventStreamLifetime + eventStreamHeartbeat))
+
+	writeEventStreamHeaders(c)
+	if since == "" {
+		// Clients that reconnect resume from here, even if no events came in the meantime
+		fmt.Fprintf(c.Writer, "id: %s\n\n", position)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case event, ok := <-subscription:
+			if !ok {
+				return
+			}
+			writeEvent(c, event)
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
+
+func writeEventStreamHeaders(c *gin.Context) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", eventStreamRetry)
+}
+
+func writeEvent(c *gin.Context, event models.HouseholdEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", event.Id, event.Type, data)
+}
@@ -2,31 +2,208 @@ package routes
 
 import (
 	"api/models"
+	"api/parsing"
 	"api/providers"
+	"api/proxy"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 )
 
-func GetGroceries(c *gin.Context) {
+// maxGroceriesPageSize caps the limit query parameter of GetGroceries
+const maxGroceriesPageSize = 1000
+
+// maxGroceryNotesLength is in characters
+const maxGroceryNotesLength = 1000
+
+// groceryChangesOverlap is how far change tokens reach back before they were handed out, writes
+// that were in flight at the time or stamped by a server with a lagging clock still get picked up.
+// Clients see changes in the overlap twice, which is harmless as they replace items by id.
+const groceryChangesOverlap = 10 * time.Second
+
+// GetGroceries returns the list named by the listId query parameter, the household's default list
+// when it is left out, laid out the way its members arranged it. A single page of it is returned
+// instead when the limit or cursor query parameters are given. The assignedTo query parameter
+// narrows the list down to the items assigned to a member, "me" standing for the caller.
+func (api *Api) GetGroceries(c *gin.Context) {
 	householdId := c.Param("householdId")
+	if !authorizeHousehold(c, householdId) {
+		return
+	}
+
+	assignedTo := c.Query("assignedTo")
+	if assignedTo == "me" {
+		assignedTo = currentUser(c).Id
+	}
+
+	list, ok := api.listParam(c, householdId)
+	if !ok {
+		return
+	}
+
+	limitParam, hasLimit := c.GetQuery("limit")
+	cursor, hasCursor := c.GetQuery("cursor")
+
+	if !hasLimit && !hasCursor {
+		groceryList, err := api.groceryList(c.Request.Context(), list)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		if assignedTo != "" {
+			groceryList = groceryList.AssignedTo(assignedTo)
+		}
+
+		c.IndentedJSON(http.StatusOK, groceryList)
+		return
+	}
+
+	limit := int64(maxGroceriesPageSize)
+	if hasLimit {
+		var err error
+		limit, err = strconv.ParseInt(limitParam, 10, 32)
+		if err != nil || limit < 1 || limit > maxGroceriesPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxGroceriesPageSize)})
+			return
+		}
+	}
+
+	groceryItems, nextCursor, err := api.Groceries.GetGroceryItemsPage(c.Request.Context(), householdId, int32(limit), cursor)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
 
-	groceryItems := providers.GetGroceryItems(householdId)
+	// Pages span every list of the household, so they come back short when filtered down to one
+	groceryItems = models.ItemsInList(groceryItems, list.Id)
+	if assignedTo != "" {
+		groceryItems = models.ItemsAssignedTo(groceryItems, assignedTo)
+	}
 
+	// Pages can't follow the household's layout, which spans the whole list
 	layout := make([]models.LayoutBlock, len(groceryItems))
 	for i, item := range groceryItems {
 		layout[i] = models.LayoutBlock{Type: models.GroceryItemId, Value: item.Id}
 	}
 
 	groceryList := models.GroceryList{
+		ListId: list.Id,
+		Name:   list.Name,
 		Items:  groceryItems,
 		Layout: layout,
+		Cursor: nextCursor,
 	}
 
 	c.IndentedJSON(http.StatusOK, groceryList)
 }
 
-func CreateGroceryItem(c *gin.Context) {
+// groceryList returns the items on the list laid out following its stored layout
+func (api *Api) groceryList(ctx context.Context, list models.HouseholdList) (models.GroceryList, error) {
+	groceryItems, err := api.Groceries.GetGroceryItems(ctx, list.HouseholdId)
+	if err != nil {
+		return models.GroceryList{}, err
+	}
+	groceryItems = models.ItemsInList(groceryItems, list.Id)
+
+	layout, err := api.Layouts.GetLayout(ctx, list.HouseholdId, list.Id)
+	if err != nil {
+		return models.GroceryList{}, err
+	}
+
+	return models.GroceryList{
+		ListId: list.Id,
+		Name:   list.Name,
+		Items:  groceryItems,
+		Layout: layout.Arrange(groceryItems),
+	}, nil
+}
+
+// GetGroceryChanges returns the items of every list of the household, and tombstones of deleted
+// items, that changed since the since query parameter along with the token to pass as since next
+// time. Clients tell the lists apart by the listId of the items. Without since, or when it
+// is older than tombstones are kept, it returns the whole list with reset set instead.
+func (api *Api) GetGroceryChanges(c *gin.Context) {
+	householdId := c.Param("householdId")
+	if !authorizeHousehold(c, householdId) {
+		return
+	}
+
+	since, err := decodeChangeToken(c.Query("since"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "since is not a valid change token"})
+		return
+	}
+
+	now := time.Now()
+	changes := models.GroceryChanges{Token: encodeChangeToken(now.Add(-groceryChangesOverlap))}
+
+	if since.IsZero() || since.Before(now.Add(-providers.GroceryTombstoneRetention)) {
+		changes.Items, err = api.Groceries.GetGroceryItems(c.Request.Context(), householdId)
+		changes.Reset = true
+	} else {
+		changes.Items, err = api.Groceries.GetGroceryItemChanges(c.Request.Context(), householdId, since)
+	}
+
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
+
+// ApplyGroceryOps applies a batch of operations made on a client, possibly while it was offline,
+// to the list named by the listId query parameter and returns the result of each one along with
+// the merged list. ops.Processor documents how conflicts with changes made in the meantime are
+// resolved.
+func (api *Api) ApplyGroceryOps(c *gin.Context) {
+	householdId := c.Param("householdId")
+	if !authorizeHousehold(c, householdId) {
+		return
+	}
+
+	list, ok := api.listParam(c, householdId)
+	if !ok {
+		return
+	}
+
+	var request models.GroceryOpsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := api.Ops.Apply(c.Request.Context(), list, currentUser(c).Id, request.Ops)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	groceryList, err := api.groceryList(c.Request.Context(), list)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.GroceryOpsResponse{
+		Results:     results,
+		GroceryList: groceryList,
+	})
+}
+
+// CreateGroceryItem adds an item to the list named by its listId, the household's default list
+// when it is left out. A quantity at the start of the name, as in "2kg chicken thighs", is split
+// off into the quantity and unit unless the item comes with them.
+func (api *Api) CreateGroceryItem(c *gin.Context) {
 	var groceryItem models.GroceryItem
 
 	if err := c.ShouldBindJSON(&groceryItem); err != nil {
@@ -34,19 +211,54 @@ func CreateGroceryItem(c *gin.Context) {
 		return
 	}
 
+	if !authorizeHousehold(c, groceryItem.HouseholdId) {
+		return
+	}
+
+	if groceryItem.ListId == "" {
+		groceryItem.ListId = models.DefaultListId
+	}
+
+	list, err := api.Lists.GetList(c.Request.Context(), groceryItem.HouseholdId, groceryItem.ListId)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+	if list.Archived {
+		respondWithError(c, providers.ErrListArchived)
+		return
+	}
+
+	if !api.validGroceryItem(c, groceryItem) {
+		return
+	}
+
+	if quantity, ok := parsing.ParseQuantity(groceryItem.Name); ok {
+		groceryItem.SetQuantity(quantity.Amount, quantity.Unit, quantity.Name)
+	}
+
 	groceryItem.GenerateID()
+	groceryItem.AddedBy = currentUser(c).Id
 
-	err := providers.CreateGroceryItem(groceryItem)
+	groceryItem, err = api.Groceries.CreateGroceryItem(c.Request.Context(), groceryItem)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondWithError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{})
+	// The item is there either way, it ends up at the bottom of the list if it can't be placed
+	if err := api.LayoutEditor.PlaceItem(c.Request.Context(), groceryItem.HouseholdId, groceryItem.ListId, groceryItem.Id, api.sectionForItem(c.Request.Context(), groceryItem)); err != nil {
+		log.Printf("could not place grocery item [%s]: %v\n", groceryItem.Id, err)
+	}
+
+	c.JSON(http.StatusOK, groceryItem)
 }
 
-func UpdateGroceryItem(c *gin.Context) {
+// UpdateGroceryItem replaces an item, leaving out its listId keeps it on the list it is on. Moving
+// it to another list puts it at the end of that list, which can't be archived. Who added the item
+// and when can't be changed, checking it records when it was checked.
+func (api *Api) UpdateGroceryItem(c *gin.Context) {
 	var groceryItem models.GroceryItem
 
 	if err := c.ShouldBindJSON(&groceryItem); err != nil {
@@ -54,30 +266,129 @@ func UpdateGroceryItem(c *gin.Context) {
 		return
 	}
 
-	err := providers.UpdateGroceryItem(groceryItem)
+	if !authorizeHousehold(c, groceryItem.HouseholdId) {
+		return
+	}
+
+	if !api.validGroceryItem(c, groceryItem) {
+		return
+	}
 
+	// Updates never create items, those go through CreateGroceryItem
+	stored, err := api.Groceries.GetGroceryItem(c.Request.Context(), groceryItem.HouseholdId, groceryItem.Id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondWithError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{})
+	moving := groceryItem.ListId != "" && !stored.InList(groceryItem.ListId)
+	if moving {
+		list, err := api.Lists.GetList(c.Request.Context(), groceryItem.HouseholdId, groceryItem.ListId)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		if list.Archived {
+			respondWithError(c, providers.ErrListArchived)
+			return
+		}
+	}
+
+	groceryItem.Replaces(stored, time.Now())
+
+	updatedGroceryItem, err := api.Groceries.UpdateGroceryItem(c.Request.Context(), groceryItem)
+
+	// Someone else changed the item first, hand back the server copy so the client can merge
+	var conflict *providers.GroceryItemConflictError
+	if errors.As(err, &conflict) {
+		if conflict.Current == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": conflict.Error()})
+			return
+		}
+
+		c.JSON(http.StatusConflict, gin.H{"error": conflict.Error(), "current": conflict.Current})
+		return
+	}
+
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	if moving {
+		api.moveInLayouts(c.Request.Context(), stored, updatedGroceryItem.ListId)
+	}
+
+	c.JSON(http.StatusOK, updatedGroceryItem)
+}
+
+// moveInLayouts takes an item that was moved to the list toListId out of the layout of the list it
+// was on and places it at the end of its new list. The item is moved either way, so failures are
+// only logged.
+func (api *Api) moveInLayouts(ctx context.Context, stored models.GroceryItem, toListId string) {
+	fromListId := stored.ListId
+	if fromListId == "" {
+		fromListId = models.DefaultListId
+	}
+
+	if err := api.LayoutEditor.RemoveItem(ctx, stored.HouseholdId, fromListId, stored.Id); err != nil {
+		log.Printf("could not remove grocery item [%s] from the layout of list [%s]: %v\n", stored.Id, fromListId, err)
+	}
+
+	if err := api.LayoutEditor.PlaceItem(ctx, stored.HouseholdId, toListId, stored.Id, ""); err != nil {
+		log.Printf("could not place grocery item [%s]: %v\n", stored.Id, err)
+	}
+}
+
+// validGroceryItem responds with 400 and returns false when the item's notes are too long or it is
+// assigned to someone who isn't a member of its household
+func (api *Api) validGroceryItem(c *gin.Context, groceryItem models.GroceryItem) bool {
+	if utf8.RuneCountInString(groceryItem.Notes) > maxGroceryNotesLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("notes must be at most %d characters", maxGroceryNotesLength)})
+		return false
+	}
+
+	if groceryItem.AssignedTo == "" {
+		return true
+	}
+
+	household, err := api.Households.GetHousehold(c.Request.Context(), groceryItem.HouseholdId)
+	if err != nil {
+		respondWithError(c, err)
+		return false
+	}
+
+	_, err = api.memberRole(c.Request.Context(), household, groceryItem.AssignedTo)
+	if errors.Is(err, proxy.ErrNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "assignedTo must be a member of the household"})
+		return false
+	}
+	if err != nil {
+		respondWithError(c, err)
+		return false
+	}
+
+	return true
 }
 
-func DeleteGroceryItem(c *gin.Context) {
+func (api *Api) DeleteGroceryItem(c *gin.Context) {
 	householdId := c.Param("householdId")
 	groceryItemId := c.Param("id")
-	err := providers.DeleteGroceryItem(householdId, groceryItemId)
+	if !authorizeHousehold(c, householdId) {
+		return
+	}
+
+	err := api.Groceries.DeleteGroceryItem(c.Request.Context(), householdId, groceryItemId)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondWithError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{})
 }
 
-func BatchDeleteGroceryItems(c *gin.Context) {
+func (api *Api) BatchDeleteGroceryItems(c *gin.Context) {
 	var request models.BatchDeleteGroceryItemsRequest
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -85,7 +396,72 @@ func BatchDeleteGroceryItems(c *gin.Context) {
 		return
 	}
 
-	providers.BatchDeleteGroceryItems(request.ItemsToDelete)
+	for _, groceryItem := range request.ItemsToDelete {
+		if len(groceryItem.HouseholdId) == 0 || len(groceryItem.Id) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "householdId and id must not be null"})
+			return
+		}
 
-	c.JSON(http.StatusOK, gin.H{})
+		if !authorizeHousehold(c, groceryItem.HouseholdId) {
+			return
+		}
+	}
+
+	notDeleted, err := api.Groceries.BatchDeleteGroceryItems(c.Request.Context(), request.ItemsToDelete)
+
+	deleted := make([]models.GroceryItem, 0, len(request.ItemsToDelete))
+	for _, groceryItem := range request.ItemsToDelete {
+		if !containsGroceryItem(notDeleted, groceryItem) {
+			deleted = append(deleted, groceryItem)
+		}
+	}
+
+	// Only fail the request when nothing was deleted, otherwise report which items are left over
+	if err != nil && len(deleted) == 0 {
+		respondWithError(c, err)
+		return
+	}
+
+	if err != nil {
+		log.Printf("failed to delete %d grocery items: %v\n", len(notDeleted), err)
+	}
+
+	c.JSON(http.StatusOK, models.BatchDeleteGroceryItemsResponse{
+		Deleted:    deleted,
+		NotDeleted: notDeleted,
+	})
+}
+
+func containsGroceryItem(groceryItems []models.GroceryItem, groceryItem models.GroceryItem) bool {
+	for _, item := range groceryItems {
+		if item.HouseholdId == groceryItem.HouseholdId && item.Id == groceryItem.Id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// encodeChangeToken turns a point in time into an opaque token for GetGroceryChanges
+func encodeChangeToken(since time.Time) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(since.UnixMilli(), 10)))
+}
+
+// decodeChangeToken reverses encodeChangeToken, an empty token decodes to the zero time
+func decodeChangeToken(token string) (time.Time, error) {
+	if token == "" {
+		return time.Time{}, nil
+	}
+
+	body, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	milliseconds, err := strconv.ParseInt(string(body), 10, 64)
+	if err != nil || milliseconds <= 0 {
+		return time.Time{}, fmt.Errorf("invalid change token %q", token)
+	}
+
+	return time.UnixMilli(milliseconds), nil
 }
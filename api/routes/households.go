@@ -1,49 +1,335 @@
 package routes
 
 import (
+	"api/models"
 	"api/providers"
+	"api/proxy"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 )
 
-func CreateHousehold(c *gin.Context) {
-	household := providers.CreateHousehold()
+// maxHouseholdNameLength is in characters
+const maxHouseholdNameLength = 100
+
+const defaultHouseholdName = "Household"
+
+func (api *Api) CreateHousehold(c *gin.Context) {
+	var request models.CreateHouseholdRequest
+
+	// The name is optional, so the body may be left out entirely
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		name = defaultHouseholdName
+	}
+	if !validHouseholdName(c, name) {
+		return
+	}
+
+	household, err := api.Households.CreateHousehold(c.Request.Context(), currentUser(c).Id, name)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, household)
+}
+
+func (api *Api) GetHousehold(c *gin.Context) {
+	householdId := c.Param("householdId")
+	if !authorizeHousehold(c, householdId) {
+		return
+	}
+
+	household, err := api.Households.GetHousehold(c.Request.Context(), householdId)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
 
 	c.JSON(http.StatusOK, household)
 }
 
-func JoinHousehold(c *gin.Context) {
+func (api *Api) RenameHousehold(c *gin.Context) {
+	householdId := c.Param("householdId")
+
+	var request models.RenameHouseholdRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if !validHouseholdName(c, name) {
+		return
+	}
+
+	if _, _, ok := api.authorizeHouseholdRole(c, householdId, models.OwnerRole, models.AdminRole); !ok {
+		return
+	}
+
+	household, err := api.Households.RenameHousehold(c.Request.Context(), householdId, name)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, household)
+}
+
+func validHouseholdName(c *gin.Context, name string) bool {
+	if name == "" || utf8.RuneCountInString(name) > maxHouseholdNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("name must be between 1 and %d characters", maxHouseholdNameLength)})
+		return false
+	}
+
+	return true
+}
+
+// GetHouseholdMembers lists the members index, with the owner's role filled in from the household
+func (api *Api) GetHouseholdMembers(c *gin.Context) {
+	householdId := c.Param("householdId")
+	if !authorizeHousehold(c, householdId) {
+		return
+	}
+
+	household, err := api.Households.GetHousehold(c.Request.Context(), householdId)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	members, err := api.Households.GetMembers(c.Request.Context(), householdId)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	for i := range members {
+		if members[i].UserId == household.OwnerId {
+			members[i].Role = models.OwnerRole
+		}
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// RemoveHouseholdMember lets the owner remove anyone but themselves, and admins remove plain members
+func (api *Api) RemoveHouseholdMember(c *gin.Context) {
 	householdId := c.Param("householdId")
 	userId := c.Param("userId")
 
-	if len(userId) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "userId must not be null",
-		})
+	household, callerRole, ok := api.authorizeHouseholdRole(c, householdId, models.OwnerRole, models.AdminRole)
+	if !ok {
 		return
 	}
 
-	if len(householdId) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "householdId must not be null",
-		})
+	role, err := api.memberRole(c.Request.Context(), household, userId)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	if role == models.OwnerRole {
+		c.JSON(http.StatusForbidden, gin.H{"error": "the owner cannot be removed, transfer ownership first"})
 		return
 	}
 
-	err := providers.JoinHousehold(userId, householdId)
+	if role == models.AdminRole && callerRole != models.OwnerRole {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the owner can remove admins"})
+		return
+	}
+
+	if err := api.Households.LeaveHousehold(c.Request.Context(), userId, householdId); err != nil {
+		respondWithError(c, err)
+		return
+	}
 
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+func (api *Api) SetHouseholdMemberRole(c *gin.Context) {
+	householdId := c.Param("householdId")
+	userId := c.Param("userId")
+
+	var request models.SetHouseholdMemberRoleRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if request.Role != models.AdminRole && request.Role != models.MemberRole {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be one of admin or member, transfer the household to change its owner"})
+		return
+	}
+
+	household, _, ok := api.authorizeHouseholdRole(c, householdId, models.OwnerRole)
+	if !ok {
+		return
+	}
+
+	role, err := api.memberRole(c.Request.Context(), household, userId)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		respondWithError(c, err)
+		return
+	}
+
+	if role == models.OwnerRole {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the owner's role cannot be changed, transfer ownership instead"})
+		return
+	}
+
+	err = api.Households.SetMemberRole(c.Request.Context(), householdId, userId, request.Role)
+
+	// Members from before the members index only exist on their user record, give them an entry first
+	if errors.Is(err, proxy.ErrNotFound) {
+		err = api.Households.JoinHousehold(c.Request.Context(), userId, householdId)
+		if err == nil {
+			err = api.Households.SetMemberRole(c.Request.Context(), householdId, userId, request.Role)
+		}
+	}
+
+	if err != nil {
+		respondWithError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{})
 }
 
-func LeaveHousehold(c *gin.Context) {
+// TransferHousehold hands ownership to another member, the previous owner becomes an admin
+func (api *Api) TransferHousehold(c *gin.Context) {
+	householdId := c.Param("householdId")
+
+	var request models.TransferHouseholdRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	household, _, ok := api.authorizeHouseholdRole(c, householdId, models.OwnerRole)
+	if !ok {
+		return
+	}
+
+	role, err := api.memberRole(c.Request.Context(), household, request.UserId)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	if role == models.OwnerRole {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user already owns the household"})
+		return
+	}
+
+	household, err = api.Households.TransferHousehold(c.Request.Context(), householdId, household.OwnerId, request.UserId)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, household)
+}
+
+// householdDeletionTimeout bounds the deletion started by DeleteHousehold, anything left over
+// is picked up by the next scheduled run of the deletion job
+const householdDeletionTimeout = 5 * time.Minute
+
+// DeleteHousehold queues the household for deletion and starts deleting it in the background.
+// The household is hidden from then on, even though its items may take a while to go.
+func (api *Api) DeleteHousehold(c *gin.Context) {
+	householdId := c.Param("householdId")
+
+	if _, _, ok := api.authorizeHouseholdRole(c, householdId, models.OwnerRole); !ok {
+		return
+	}
+
+	if err := api.Households.MarkHouseholdForDeletion(c.Request.Context(), householdId); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	api.deleteHouseholdInBackground(c.Request.Context(), householdId)
+
+	c.JSON(http.StatusAccepted, gin.H{})
+}
+
+// deleteHouseholdInBackground runs the deletion of a household marked for deletion past the end of the request
+func (api *Api) deleteHouseholdInBackground(ctx context.Context, householdId string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), householdDeletionTimeout)
+		defer cancel()
+
+		if err := api.Deletions.DeleteHousehold(ctx, householdId); err != nil {
+			log.Printf("household deletion will be retried: %v\n", err)
+		}
+	}()
+}
+
+// JoinHousehold redeems an invite code and adds the caller to its household.
+// Members redeeming an invite to their own household don't use it up.
+func (api *Api) JoinHousehold(c *gin.Context) {
+	var request models.JoinHouseholdRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	code := providers.NormalizeShortCode(request.Code)
+	user := currentUser(c)
+
+	invite, err := api.Invites.GetInvite(c.Request.Context(), code)
+	if err != nil {
+		respondWithInviteError(c, err)
+		return
+	}
+
+	// Households being deleted are not found, before their invite is used up
+	household, err := api.Households.GetHousehold(c.Request.Context(), invite.HouseholdId)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	if !slices.Contains(user.HouseholdIds, invite.HouseholdId) {
+		if _, err := api.Invites.RedeemInvite(c.Request.Context(), code); err != nil {
+			respondWithInviteError(c, err)
+			return
+		}
+
+		if err := api.Households.JoinHousehold(c.Request.Context(), user.Id, invite.HouseholdId); err != nil {
+			if err := api.Invites.ReleaseInvite(c.Request.Context(), code); err != nil {
+				log.Printf("could not release a use of invite [%s]: %v\n", code, err)
+			}
+
+			respondWithError(c, err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, household)
+}
+
+func (api *Api) LeaveHousehold(c *gin.Context) {
 	householdId := c.Param("householdId")
 	userId := c.Param("userId")
 
@@ -61,14 +347,53 @@ func LeaveHousehold(c *gin.Context) {
 		return
 	}
 
-	err := providers.LeaveHousehold(userId, householdId)
+	if !authorizeUser(c, userId) {
+		return
+	}
+
+	if !authorizeHousehold(c, householdId) {
+		return
+	}
+
+	household, err := api.Households.GetHousehold(c.Request.Context(), householdId)
+	if err != nil && !errors.Is(err, proxy.ErrNotFound) {
+		respondWithError(c, err)
+		return
+	}
+
+	// The owner can only walk away from a household nobody else is left in, which goes with them
+	isDeleting := false
+	if household.OwnerId == userId {
+		userIds, err := providers.GetHouseholdUserIds(c.Request.Context(), api.Households, api.Users, householdId)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		for _, memberId := range userIds {
+			if memberId != userId {
+				c.JSON(http.StatusConflict, gin.H{"error": "transfer ownership before leaving the household"})
+				return
+			}
+		}
+
+		if err := api.Households.MarkHouseholdForDeletion(c.Request.Context(), householdId); err != nil {
+			respondWithError(c, err)
+			return
+		}
+		isDeleting = true
+	}
+
+	err = api.Households.LeaveHousehold(c.Request.Context(), userId, householdId)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		respondWithError(c, err)
 		return
 	}
 
+	if isDeleting {
+		api.deleteHouseholdInBackground(c.Request.Context(), householdId)
+	}
+
 	c.JSON(http.StatusOK, gin.H{})
 }
@@ -0,0 +1,130 @@
+package routes
+
+import (
+	"api/models"
+	"api/providers"
+	"api/proxy"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultInviteExpiry = 7 * 24 * time.Hour
+	maxInviteExpiry     = 30 * 24 * time.Hour
+	defaultInviteUses   = 1
+	maxInviteUses       = 100
+)
+
+func (api *Api) CreateHouseholdInvite(c *gin.Context) {
+	householdId := c.Param("householdId")
+	if !authorizeHousehold(c, householdId) {
+		return
+	}
+
+	var request models.CreateHouseholdInviteRequest
+
+	// Every field has a default, so the body may be left out entirely
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	expiry := defaultInviteExpiry
+	if request.ExpiresInMinutes != 0 {
+		expiry = time.Duration(request.ExpiresInMinutes) * time.Minute
+	}
+	if expiry <= 0 || expiry > maxInviteExpiry {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("expiresInMinutes must be between 1 and %d", int(maxInviteExpiry.Minutes()))})
+		return
+	}
+
+	maxUses := defaultInviteUses
+	if request.MaxUses != 0 {
+		maxUses = request.MaxUses
+	}
+	if maxUses < 1 || maxUses > maxInviteUses {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("maxUses must be between 1 and %d", maxInviteUses)})
+		return
+	}
+
+	now := time.Now()
+	invite, err := api.Invites.CreateInvite(c.Request.Context(), models.HouseholdInvite{
+		HouseholdId: householdId,
+		CreatedBy:   currentUser(c).Id,
+		CreatedAt:   now.Unix(),
+		ExpiresAt:   now.Add(expiry).Unix(),
+		MaxUses:     maxUses,
+	})
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invite)
+}
+
+// GetHouseholdInvites lists the invites that can still be redeemed
+func (api *Api) GetHouseholdInvites(c *gin.Context) {
+	householdId := c.Param("householdId")
+	if _, _, ok := api.authorizeHouseholdRole(c, householdId, models.OwnerRole, models.AdminRole); !ok {
+		return
+	}
+
+	invites, err := api.Invites.GetHouseholdInvites(c.Request.Context(), householdId)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	now := time.Now()
+	outstanding := make([]models.HouseholdInvite, 0, len(invites))
+	for _, invite := range invites {
+		if invite.Outstanding(now) {
+			outstanding = append(outstanding, invite)
+		}
+	}
+
+	c.JSON(http.StatusOK, outstanding)
+}
+
+func (api *Api) RevokeHouseholdInvite(c *gin.Context) {
+	householdId := c.Param("householdId")
+	if _, _, ok := api.authorizeHouseholdRole(c, householdId, models.OwnerRole, models.AdminRole); !ok {
+		return
+	}
+
+	code := providers.NormalizeShortCode(c.Param("code"))
+
+	invite, err := api.Invites.GetInvite(c.Request.Context(), code)
+	if err == nil && invite.HouseholdId != householdId {
+		err = fmt.Errorf("could not find invite [%s]: %w", code, proxy.ErrNotFound)
+	}
+	if err != nil {
+		respondWithInviteError(c, err)
+		return
+	}
+
+	if err := api.Invites.DeleteInvite(c.Request.Context(), code); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// respondWithInviteError is respondWithError with invites that can no longer be redeemed reported as gone
+func respondWithInviteError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, providers.ErrInviteExpired), errors.Is(err, providers.ErrInviteUsedUp):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, proxy.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "invite not found"})
+	default:
+		respondWithError(c, err)
+	}
+}
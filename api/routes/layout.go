@@ -0,0 +1,115 @@
+package routes
+
+import (
+	"api/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MoveLayoutItem moves an item after another block of the list named by the listId query parameter,
+// or to the top, and returns the list
+func (api *Api) MoveLayoutItem(c *gin.Context) {
+	householdId := c.Param("householdId")
+	if !authorizeHousehold(c, householdId) {
+		return
+	}
+
+	list, ok := api.listParam(c, householdId)
+	if !ok {
+		return
+	}
+
+	var request models.MoveLayoutItemRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := api.LayoutEditor.MoveItem(c.Request.Context(), householdId, list.Id, c.Param("id"), request.AfterId); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	api.respondWithGroceryList(c, list)
+}
+
+func (api *Api) InsertLayoutHeading(c *gin.Context) {
+	householdId := c.Param("householdId")
+	if !authorizeHousehold(c, householdId) {
+		return
+	}
+
+	list, ok := api.listParam(c, householdId)
+	if !ok {
+		return
+	}
+
+	var request models.LayoutHeadingRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := api.LayoutEditor.InsertHeading(c.Request.Context(), householdId, list.Id, request.Text, request.AfterId); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	api.respondWithGroceryList(c, list)
+}
+
+func (api *Api) RenameLayoutHeading(c *gin.Context) {
+	householdId := c.Param("householdId")
+	if !authorizeHousehold(c, householdId) {
+		return
+	}
+
+	list, ok := api.listParam(c, householdId)
+	if !ok {
+		return
+	}
+
+	var request models.LayoutHeadingRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := api.LayoutEditor.RenameHeading(c.Request.Context(), householdId, list.Id, c.Param("headingId"), request.Text); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	api.respondWithGroceryList(c, list)
+}
+
+// DeleteLayoutHeading removes a heading, the items under it stay where they are
+func (api *Api) DeleteLayoutHeading(c *gin.Context) {
+	householdId := c.Param("householdId")
+	if !authorizeHousehold(c, householdId) {
+		return
+	}
+
+	list, ok := api.listParam(c, householdId)
+	if !ok {
+		return
+	}
+
+	if err := api.LayoutEditor.DeleteHeading(c.Request.Context(), householdId, list.Id, c.Param("headingId")); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	api.respondWithGroceryList(c, list)
+}
+
+func (api *Api) respondWithGroceryList(c *gin.Context, list models.HouseholdList) {
+	groceryList, err := api.groceryList(c.Request.Context(), list)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, groceryList)
+}
@@ -0,0 +1,337 @@
+package routes
+
+import (
+	"api/models"
+	"api/providers"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxListNameLength is in characters
+const maxListNameLength = 100
+
+// GetHouseholdLists returns the household's lists, archived ones only when the archived query
+// parameter is true
+func (api *Api) GetHouseholdLists(c *gin.Context) {
+	householdId := c.Param("householdId")
+	if !authorizeHousehold(c, householdId) {
+		return
+	}
+
+	lists, err := api.Lists.GetLists(c.Request.Context(), householdId)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	if c.Query("archived") != "true" {
+		active := make([]models.HouseholdList, 0, len(lists))
+		for _, list := range lists {
+			if !list.Archived {
+				active = append(active, list)
+			}
+		}
+		lists = active
+	}
+
+	c.JSON(http.StatusOK, lists)
+}
+
+func (api *Api) CreateHouseholdList(c *gin.Context) {
+	householdId := c.Param("householdId")
+	if !authorizeHousehold(c, householdId) {
+		return
+	}
+
+	var request models.CreateListRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if !validListName(c, name) {
+		return
+	}
+
+	list := models.HouseholdList{
+		HouseholdId: householdId,
+		Id:          uuid.NewString(),
+		Name:        name,
+		CreatedAt:   time.Now().Unix(),
+	}
+
+	if err := api.Lists.CreateList(c.Request.Context(), list); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// UpdateHouseholdList renames, archives or restores a list. The default list can be renamed but
+// not archived.
+func (api *Api) UpdateHouseholdList(c *gin.Context) {
+	householdId := c.Param("householdId")
+	if !authorizeHousehold(c, householdId) {
+		return
+	}
+
+	var request models.UpdateListRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, err := api.Lists.GetList(c.Request.Context(), householdId, c.Param("listId"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	if request.Name != nil {
+		list.Name = strings.TrimSpace(*request.Name)
+		if !validListName(c, list.Name) {
+			return
+		}
+	}
+
+	if request.Archived != nil {
+		if *request.Archived && list.Id == models.DefaultListId {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "the default list can't be archived"})
+			return
+		}
+		list.Archived = *request.Archived
+	}
+
+	if err := api.Lists.UpdateList(c.Request.Context(), list); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// DeleteHouseholdList deletes a list along with its items, staples and layout. Only owners and
+// admins can delete lists, and the default list can't be deleted.
+func (api *Api) DeleteHouseholdList(c *gin.Context) {
+	householdId := c.Param("householdId")
+	listId := c.Param("listId")
+
+	if _, _, ok := api.authorizeHouseholdRole(c, householdId, models.OwnerRole, models.AdminRole); !ok {
+		return
+	}
+
+	if listId == models.DefaultListId {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the default list can't be deleted"})
+		return
+	}
+
+	if _, err := api.Lists.GetList(c.Request.Context(), householdId, listId); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	// The list goes last, so that a deletion that fails part way can be repeated
+	if err := api.deleteListStaples(c.Request.Context(), householdId, listId); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	if err := api.deleteListItems(c.Request.Context(), householdId, listId); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	if err := api.Layouts.DeleteLayout(c.Request.Context(), householdId, listId); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	if err := api.Lists.DeleteList(c.Request.Context(), householdId, listId); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+func (api *Api) deleteListItems(ctx context.Context, householdId string, listId string) error {
+	groceryItems, err := api.Groceries.GetGroceryItems(ctx, householdId)
+	if err != nil {
+		return err
+	}
+
+	groceryItems = models.ItemsInList(groceryItems, listId)
+	if len(groceryItems) == 0 {
+		return nil
+	}
+
+	notDeleted, err := api.Groceries.BatchDeleteGroceryItems(ctx, groceryItems)
+	if err != nil {
+		return err
+	}
+
+	if len(notDeleted) > 0 {
+		return fmt.Errorf("%d of %d grocery items could not be deleted", len(notDeleted), len(groceryItems))
+	}
+
+	return nil
+}
+
+// MoveGroceryItems moves items of the list to the end of another list of the household and
+// returns that list
+func (api *Api) MoveGroceryItems(c *gin.Context) {
+	fromListId := c.Param("listId")
+
+	api.transferGroceryItems(c, func(ctx context.Context, groceryItem models.GroceryItem, toList models.HouseholdList) (models.GroceryItem, error) {
+		groceryItem.ListId = toList.Id
+		moved, err := api.Groceries.UpdateGroceryItem(ctx, groceryItem)
+		if err != nil {
+			return moved, err
+		}
+
+		if err := api.LayoutEditor.RemoveItem(ctx, moved.HouseholdId, fromListId, moved.Id); err != nil {
+			log.Printf("could not remove grocery item [%s] from the layout of list [%s]: %v\n", moved.Id, fromListId, err)
+		}
+
+		return moved, nil
+	})
+}
+
+// CopyGroceryItems copies items of the list to the end of another list of the household and
+// returns that list. The copies are new, unchecked items added by the caller.
+func (api *Api) CopyGroceryItems(c *gin.Context) {
+	addedBy := currentUser(c).Id
+
+	api.transferGroceryItems(c, func(ctx context.Context, groceryItem models.GroceryItem, toList models.HouseholdList) (models.GroceryItem, error) {
+		copied := models.GroceryItem{
+			HouseholdId:   groceryItem.HouseholdId,
+			ListId:        toList.Id,
+			Name:          groceryItem.Name,
+			Quantity:      groceryItem.Quantity,
+			Unit:          groceryItem.Unit,
+			Text:          groceryItem.Text,
+			StoreOverride: groceryItem.StoreOverride,
+			Notes:         groceryItem.Notes,
+			AddedBy:       addedBy,
+			AssignedTo:    groceryItem.AssignedTo,
+		}
+		copied.GenerateID()
+
+		return api.Groceries.CreateGroceryItem(ctx, copied)
+	})
+}
+
+// transferGroceryItems checks that every requested item is on the list, that none is requested
+// twice and that the list they go to can be added to before transferring any of them
+func (api *Api) transferGroceryItems(c *gin.Context, transfer func(context.Context, models.GroceryItem, models.HouseholdList) (models.GroceryItem, error)) {
+	householdId := c.Param("householdId")
+	listId := c.Param("listId")
+	if !authorizeHousehold(c, householdId) {
+		return
+	}
+
+	var request models.TransferGroceryItemsRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if request.ToListId == listId {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "toListId must be another list"})
+		return
+	}
+
+	if _, err := api.Lists.GetList(c.Request.Context(), householdId, listId); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	toList, err := api.Lists.GetList(c.Request.Context(), householdId, request.ToListId)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+	if toList.Archived {
+		respondWithError(c, providers.ErrListArchived)
+		return
+	}
+
+	groceryItems, err := api.Groceries.GetGroceryItems(c.Request.Context(), householdId)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	itemsById := make(map[string]models.GroceryItem)
+	for _, groceryItem := range models.ItemsInList(groceryItems, listId) {
+		itemsById[groceryItem.Id] = groceryItem
+	}
+
+	requested := make(map[string]bool, len(request.ItemIds))
+	for _, itemId := range request.ItemIds {
+		if requested[itemId] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("grocery item [%s] is in itemIds more than once", itemId)})
+			return
+		}
+		requested[itemId] = true
+
+		if _, ok := itemsById[itemId]; !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("grocery item [%s] is not on list [%s]", itemId, listId)})
+			return
+		}
+	}
+
+	for _, itemId := range request.ItemIds {
+		transferred, err := transfer(c.Request.Context(), itemsById[itemId], toList)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		// The item is there either way, it ends up at the bottom of the list if it can't be placed
+		if err := api.LayoutEditor.PlaceItem(c.Request.Context(), householdId, toList.Id, transferred.Id, ""); err != nil {
+			log.Printf("could not place grocery item [%s]: %v\n", transferred.Id, err)
+		}
+	}
+
+	api.respondWithGroceryList(c, toList)
+}
+
+// listParam returns the list named by the listId query parameter, the default list when it is
+// left out. It responds with an error and returns false when the household has no such list.
+func (api *Api) listParam(c *gin.Context, householdId string) (models.HouseholdList, bool) {
+	listId := c.Query("listId")
+	if listId == "" {
+		listId = models.DefaultListId
+	}
+
+	list, err := api.Lists.GetList(c.Request.Context(), householdId, listId)
+	if err != nil {
+		respondWithError(c, err)
+		return models.HouseholdList{}, false
+	}
+
+	return list, true
+}
+
+func validListName(c *gin.Context, name string) bool {
+	if name == "" || utf8.RuneCountInString(name) > maxListNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("name must be between 1 and %d characters", maxListNameLength)})
+		return false
+	}
+
+	return true
+}
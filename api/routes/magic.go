@@ -5,6 +5,7 @@ import (
 	"api/models"
 	"api/parsing"
 	"api/providers"
+	"context"
 	"fmt"
 	"log"
 	"net/http"
@@ -19,7 +20,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func GroceryMagic(c *gin.Context) {
+func (api *Api) GroceryMagic(c *gin.Context) {
 	var request models.GroceryMagicRequest
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -27,7 +28,37 @@ func GroceryMagic(c *gin.Context) {
 		return
 	}
 
-	catalog := providers.GetCatalog()
+	if !authorizeHousehold(c, request.HouseholdId) {
+		return
+	}
+
+	for _, item := range request.GroceryList.Items {
+		if !authorizeHousehold(c, item.HouseholdId) {
+			return
+		}
+	}
+
+	listId := request.GroceryList.ListId
+	if listId == "" {
+		listId = models.DefaultListId
+	}
+
+	// Recipes add items to the list
+	list, err := api.Lists.GetList(c.Request.Context(), request.HouseholdId, listId)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+	if list.Archived {
+		respondWithError(c, providers.ErrListArchived)
+		return
+	}
+
+	catalog, err := api.Catalog.GetCatalog(c.Request.Context())
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
 
 	var groceryItems []models.GroceryItem
 	layoutBlockMap := make(map[models.StorePreference][]models.LayoutBlock)
@@ -39,10 +70,10 @@ func GroceryMagic(c *gin.Context) {
 
 		if isRecipeUrl {
 			wg.Add(1)
-			providers.DeleteGroceryItem(item.HouseholdId, item.Id)
+			api.Groceries.DeleteGroceryItem(c.Request.Context(), item.HouseholdId, item.Id)
 			go func() {
 				defer wg.Done()
-				recipeGroceryItems, extractedLayoutBlockMap := extractAndCreateGroceryItemsFromRecipeUrl(recipeUrl, request.HouseholdId, groceryItems, request.PreferredStores)
+				recipeGroceryItems, extractedLayoutBlockMap := api.extractAndCreateGroceryItemsFromRecipeUrl(c.Request.Context(), recipeUrl, list, currentUser(c).Id, groceryItems, catalog, request.PreferredStores)
 
 				groceryItems = append(groceryItems, recipeGroceryItems...)
 
@@ -64,18 +95,36 @@ func GroceryMagic(c *gin.Context) {
 			Id:          item.Id,
 			Name:        item.Name,
 			HouseholdId: item.HouseholdId,
+			ListId:      item.ListId,
 			Checked:     item.Checked,
 		})
 	}
 
 	wg.Wait()
+
+	// Sorted so that the sections don't move around every time the list is laid out
+	stores := make([]models.StorePreference, 0, len(layoutBlockMap))
+	for key := range layoutBlockMap {
+		stores = append(stores, key)
+	}
+	slices.Sort(stores)
+
 	var layout []models.LayoutBlock
-	for key, blocks := range layoutBlockMap {
+	for _, key := range stores {
 		layout = append(layout, models.LayoutBlock{Value: string(key), Type: models.Text})
-		layout = append(layout, blocks...)
+		layout = append(layout, layoutBlockMap[key]...)
+	}
+
+	// Keep the layout for every member of the household, the list is still laid out if that fails
+	if storedLayout, err := api.LayoutEditor.Replace(c.Request.Context(), request.HouseholdId, list.Id, layout); err != nil {
+		log.Printf("could not store the layout of list [%s]: %v\n", list.Id, err)
+	} else {
+		layout = storedLayout
 	}
 
 	groceryList := models.GroceryList{
+		ListId: list.Id,
+		Name:   list.Name,
 		Items:  groceryItems,
 		Layout: layout,
 	}
@@ -87,6 +136,21 @@ func GroceryMagic(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// sectionForItem names the heading a new item belongs under, the store it is cheapest at
+func (api *Api) sectionForItem(ctx context.Context, item models.GroceryItem) string {
+	if parseItemName(item.Name) == "" && item.StoreOverride == "" {
+		return ""
+	}
+
+	catalog, err := api.Catalog.GetCatalog(ctx)
+	if err != nil {
+		log.Printf("could not get the catalog to place grocery item [%s]: %v\n", item.Id, err)
+		return ""
+	}
+
+	return string(getStorePreferenceForItem(item, catalog, nil))
+}
+
 func getStorePreferenceForItem(item models.GroceryItem, catalog models.Catalog, preferredStores []models.StorePreference) models.StorePreference {
 	if len(item.StoreOverride) > 0 {
 		return item.StoreOverride
@@ -144,14 +208,18 @@ func parseUrl(itemName string) (string, bool) {
 	return u.String(), true
 }
 
-func extractAndCreateGroceryItemsFromRecipeUrl(recipeUrl string, householdId string, existingGroceryItems []models.GroceryItem, preferredStores []models.StorePreference) ([]models.GroceryItem, map[models.StorePreference][]models.LayoutBlock) {
-	recipe, _ := parsing.NewFromURL(recipeUrl)
+func (api *Api) extractAndCreateGroceryItemsFromRecipeUrl(ctx context.Context, recipeUrl string, list models.HouseholdList, addedBy string, existingGroceryItems []models.GroceryItem, catalog models.Catalog, preferredStores []models.StorePreference) ([]models.GroceryItem, map[models.StorePreference][]models.LayoutBlock) {
+	recipe, err := parsing.NewFromURL(ctx, recipeUrl)
+	if err != nil {
+		log.Printf("failed to fetch recipe %s: %v\n", recipeUrl, err)
+		return nil, nil
+	}
 	ingredients := recipe.IngredientList().Ingredients
 
-	var groceryItems []models.GroceryItem = make([]models.GroceryItem, len(ingredients))
+	groceryItems := make([]models.GroceryItem, 0, len(ingredients))
 	layoutBlockMap := make(map[models.StorePreference][]models.LayoutBlock)
 
-	for i, ingredient := range ingredients {
+	for _, ingredient := range ingredients {
 		if isIngredientAlreadyInGroceryList(ingredient, existingGroceryItems) {
 			continue
 		}
@@ -159,16 +227,22 @@ func extractAndCreateGroceryItemsFromRecipeUrl(recipeUrl string, householdId str
 		storePreference := getCheapestStoreForItemOrStorePreference(ingredient.Name, catalog, preferredStores)
 
 		groceryItem := models.GroceryItem{
-			HouseholdId:   householdId,
+			HouseholdId:   list.HouseholdId,
+			ListId:        list.Id,
 			Name:          ingredient.Name,
 			Checked:       false,
 			StoreOverride: "",
+			AddedBy:       addedBy,
 		}
 
 		groceryItem.GenerateID()
 
-		providers.CreateGroceryItem(groceryItem)
-		groceryItems[i] = groceryItem
+		groceryItem, err := api.Groceries.CreateGroceryItem(ctx, groceryItem)
+		if err != nil {
+			log.Printf("could not add ingredient %s from recipe %s: %v\n", ingredient.Name, recipeUrl, err)
+			continue
+		}
+		groceryItems = append(groceryItems, groceryItem)
 
 		layoutBlockMap[storePreference] = append(layoutBlockMap[storePreference], models.LayoutBlock{
 			Value: groceryItem.Id,
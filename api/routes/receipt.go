@@ -2,26 +2,22 @@ package routes
 
 import (
 	"api/models"
-	"api/providers"
-	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-func UploadReceipt(c *gin.Context) {
+func (api *Api) UploadReceipt(c *gin.Context) {
 	var req models.UploadReceiptRequest
 	if err := c.ShouldBind(&req); err != nil {
-		fmt.Println(err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	url, err := providers.GetPresignedReceiptUploadUrl(req.FileName, req.ContentLength, req.ReceiptContext)
+	url, err := api.Receipts.GetPresignedReceiptUploadUrl(c.Request.Context(), currentUser(c).Id, req.FileName, req.ContentLength, req.ReceiptContext)
 
 	if err != nil {
-		fmt.Println(err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		respondWithError(c, err)
 		return
 	}
 
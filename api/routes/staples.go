@@ -0,0 +1,189 @@
+package routes
+
+import (
+	"api/models"
+	"api/providers"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxStapleNameLength is in characters
+const maxStapleNameLength = 100
+
+// maxStapleDays bounds the days between additions of staples that recur every N days
+const maxStapleDays = 365
+
+func (api *Api) GetStaples(c *gin.Context) {
+	householdId := c.Param("householdId")
+	if !authorizeHousehold(c, householdId) {
+		return
+	}
+
+	staples, err := api.Staples.GetStaples(c.Request.Context(), householdId)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, staples)
+}
+
+func (api *Api) CreateStaple(c *gin.Context) {
+	householdId := c.Param("householdId")
+	if !authorizeHousehold(c, householdId) {
+		return
+	}
+
+	var request models.CreateStapleRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	staple := models.Staple{
+		HouseholdId: householdId,
+		Id:          uuid.NewString(),
+		ListId:      request.ListId,
+		Name:        strings.TrimSpace(request.Name),
+		Recurrence:  request.Recurrence,
+		Days:        request.Days,
+		NextDueAt:   request.NextDueAt,
+		CreatedAt:   now.Unix(),
+	}
+	if staple.ListId == "" {
+		staple.ListId = models.DefaultListId
+	}
+	if staple.NextDueAt == 0 {
+		staple.NextDueAt = now.Unix()
+	}
+
+	if !api.validStaple(c, &staple) {
+		return
+	}
+
+	if err := api.Staples.CreateStaple(c.Request.Context(), staple); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, staple)
+}
+
+func (api *Api) UpdateStaple(c *gin.Context) {
+	householdId := c.Param("householdId")
+	if !authorizeHousehold(c, householdId) {
+		return
+	}
+
+	var request models.UpdateStapleRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	staple, err := api.Staples.GetStaple(c.Request.Context(), householdId, c.Param("stapleId"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	if request.Name != nil {
+		staple.Name = strings.TrimSpace(*request.Name)
+	}
+	if request.ListId != nil {
+		staple.ListId = *request.ListId
+	}
+	if request.Recurrence != nil {
+		staple.Recurrence = *request.Recurrence
+	}
+	if request.Days != nil {
+		staple.Days = *request.Days
+	}
+	if request.NextDueAt != nil {
+		staple.NextDueAt = *request.NextDueAt
+	}
+
+	if !api.validStaple(c, &staple) {
+		return
+	}
+
+	staple, err = api.Staples.UpdateStaple(c.Request.Context(), staple)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, staple)
+}
+
+func (api *Api) DeleteStaple(c *gin.Context) {
+	householdId := c.Param("householdId")
+	if !authorizeHousehold(c, householdId) {
+		return
+	}
+
+	if err := api.Staples.DeleteStaple(c.Request.Context(), householdId, c.Param("stapleId")); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// validStaple responds with an error and returns false unless the staple has a name, a number of
+// days when it recurs every N days, and a list that can be added to. Days are dropped for the other
+// recurrences.
+func (api *Api) validStaple(c *gin.Context, staple *models.Staple) bool {
+	if staple.Name == "" || utf8.RuneCountInString(staple.Name) > maxStapleNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("name must be between 1 and %d characters", maxStapleNameLength)})
+		return false
+	}
+
+	if staple.Recurrence != models.EveryNDays {
+		staple.Days = 0
+	} else if staple.Days < 1 || staple.Days > maxStapleDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("days must be between 1 and %d", maxStapleDays)})
+		return false
+	}
+
+	list, err := api.Lists.GetList(c.Request.Context(), staple.HouseholdId, staple.ListId)
+	if err != nil {
+		respondWithError(c, err)
+		return false
+	}
+	if list.Archived {
+		respondWithError(c, providers.ErrListArchived)
+		return false
+	}
+
+	return true
+}
+
+func (api *Api) deleteListStaples(ctx context.Context, householdId string, listId string) error {
+	staples, err := api.Staples.GetStaples(ctx, householdId)
+	if err != nil {
+		return err
+	}
+
+	for _, staple := range staples {
+		if staple.ListId != listId {
+			continue
+		}
+
+		if err := api.Staples.DeleteStaple(ctx, householdId, staple.Id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,22 +1,32 @@
 package routes
 
 import (
+	"api/accounts"
+	"api/models"
 	"api/providers"
+	"api/proxy"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-func CreateUser(c *gin.Context) {
-	user := providers.CreateUser()
-
-	c.JSON(http.StatusOK, user)
+// CreateUser returns the caller, who was created by Authenticate if this is their first request
+func (api *Api) CreateUser(c *gin.Context) {
+	c.JSON(http.StatusOK, currentUser(c))
 }
 
-func GetUser(c *gin.Context) {
+func (api *Api) GetUser(c *gin.Context) {
 	id := c.Param("id")
+	if !authorizeUser(c, id) {
+		return
+	}
 
-	results := providers.GetUsers(id)
+	results, err := api.Users.GetUsers(c.Request.Context(), id)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
 
 	if len(results) == 0 {
 		c.JSON(http.StatusNotFound, gin.H{})
@@ -25,3 +35,101 @@ func GetUser(c *gin.Context) {
 
 	c.JSON(http.StatusOK, results[0])
 }
+
+// GetUserHouseholds returns every household the user is a member of
+func (api *Api) GetUserHouseholds(c *gin.Context) {
+	id := c.Param("id")
+	if !authorizeUser(c, id) {
+		return
+	}
+
+	user := currentUser(c)
+	households := make([]models.Household, 0, len(user.HouseholdIds))
+
+	for _, householdId := range user.HouseholdIds {
+		household, err := api.Households.GetHousehold(c.Request.Context(), householdId)
+
+		// Households that are being deleted are detached from their members shortly, leave them out already
+		if errors.Is(err, proxy.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		households = append(households, household)
+	}
+
+	c.JSON(http.StatusOK, models.UserHouseholdsResponse{
+		DefaultHouseholdId: user.DefaultHousehold(),
+		Households:         households,
+	})
+}
+
+func (api *Api) SetDefaultHousehold(c *gin.Context) {
+	id := c.Param("id")
+	if !authorizeUser(c, id) {
+		return
+	}
+
+	var request models.SetDefaultHouseholdRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !authorizeHousehold(c, request.HouseholdId) {
+		return
+	}
+
+	user, err := providers.SetDefaultHousehold(c.Request.Context(), api.Users, id, request.HouseholdId)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ExportUser responds with an url to download everything kept about the user from as a zip,
+// see accounts.Accounts.Export
+func (api *Api) ExportUser(c *gin.Context) {
+	id := c.Param("id")
+	if !authorizeUser(c, id) {
+		return
+	}
+
+	url, err := api.Accounts.Export(c.Request.Context(), id)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AccountExport{Url: url})
+}
+
+// DeleteUser erases the user and their receipts. Households they owned alone are deleted in the background.
+func (api *Api) DeleteUser(c *gin.Context) {
+	id := c.Param("id")
+	if !authorizeUser(c, id) {
+		return
+	}
+
+	deletingHouseholdIds, err := api.Accounts.Erase(c.Request.Context(), id)
+	if errors.Is(err, accounts.ErrOwnsSharedHousehold) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	for _, householdId := range deletingHouseholdIds {
+		api.deleteHouseholdInBackground(c.Request.Context(), householdId)
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type apiGatewayRequestKey struct{}
+
+// NewHTTPRequest converts an API Gateway v2 (payload format 2.0) request into the request
+// the router would have received had it been served over http
+func NewHTTPRequest(ctx context.Context, req events.APIGatewayV2HTTPRequest) (*http.Request, error) {
+	body := []byte(req.Body)
+	if req.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode request body, %v", err)
+		}
+		body = decoded
+	}
+
+	path := req.RawPath
+	if path == "" {
+		path = req.RequestContext.HTTP.Path
+	}
+
+	requestUrl := &url.URL{Path: path, RawQuery: req.RawQueryString}
+	if unescaped, err := url.PathUnescape(path); err == nil && unescaped != path {
+		requestUrl.Path = unescaped
+		requestUrl.RawPath = path
+	}
+
+	ctx = context.WithValue(ctx, apiGatewayRequestKey{}, req)
+
+	httpRequest, err := http.NewRequestWithContext(ctx, strings.ToUpper(req.RequestContext.HTTP.Method), requestUrl.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	// API Gateway has already joined repeated headers with commas, which is equivalent for list headers
+	for key, value := range req.Headers {
+		httpRequest.Header.Add(key, value)
+	}
+
+	// Cookies are sent separately from the headers in payload format 2.0
+	if len(req.Cookies) > 0 {
+		httpRequest.Header.Set("Cookie", strings.Join(req.Cookies, "; "))
+	}
+
+	httpRequest.Host = httpRequest.Header.Get("Host")
+	if httpRequest.Host == "" {
+		httpRequest.Host = req.RequestContext.DomainName
+	}
+
+	httpRequest.RemoteAddr = req.RequestContext.HTTP.SourceIP
+	httpRequest.RequestURI = requestUrl.RequestURI()
+	httpRequest.ContentLength = int64(len(body))
+
+	return httpRequest, nil
+}
+
+// APIGatewayRequest returns the API Gateway request that ctx was created for, with its
+// path parameters, stage variables and authorizer context. ok is false outside of Lambda.
+func APIGatewayRequest(ctx context.Context) (req events.APIGatewayV2HTTPRequest, ok bool) {
+	req, ok = ctx.Value(apiGatewayRequestKey{}).(events.APIGatewayV2HTTPRequest)
+	return req, ok
+}
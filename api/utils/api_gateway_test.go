@@ -0,0 +1,196 @@
+package utils
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/gin-gonic/gin"
+)
+
+func newAPIGatewayRequest(method string, rawPath string) events.APIGatewayV2HTTPRequest {
+	req := events.APIGatewayV2HTTPRequest{RawPath: rawPath}
+	req.RequestContext.HTTP.Method = method
+	req.RequestContext.HTTP.Path = rawPath
+	req.RequestContext.DomainName = "abc.execute-api.eu-west-1.amazonaws.com"
+	req.RequestContext.HTTP.SourceIP = "203.0.113.7"
+
+	return req
+}
+
+func TestNewHTTPRequest(t *testing.T) {
+	tests := []struct {
+		name  string
+		req   func() events.APIGatewayV2HTTPRequest
+		check func(t *testing.T, r *http.Request)
+	}{
+		{
+			name: "method and query",
+			req: func() events.APIGatewayV2HTTPRequest {
+				req := newAPIGatewayRequest("post", "/groceries")
+				req.RawQueryString = "q=1&q=2&limit=10"
+				return req
+			},
+			check: func(t *testing.T, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("Method = %q, want POST", r.Method)
+				}
+				if q := r.URL.Query()["q"]; !slices.Equal(q, []string{"1", "2"}) {
+					t.Errorf("q = %v, want both values", q)
+				}
+				if r.RequestURI != "/groceries?q=1&q=2&limit=10" {
+					t.Errorf("RequestURI = %q", r.RequestURI)
+				}
+			},
+		},
+		{
+			name: "escaped path",
+			req: func() events.APIGatewayV2HTTPRequest {
+				return newAPIGatewayRequest("GET", "/lists/a%20b%2Fc")
+			},
+			check: func(t *testing.T, r *http.Request) {
+				if r.URL.Path != "/lists/a b/c" || r.URL.EscapedPath() != "/lists/a%20b%2Fc" {
+					t.Errorf("Path = %q, EscapedPath = %q", r.URL.Path, r.URL.EscapedPath())
+				}
+			},
+		},
+		{
+			name: "path without a raw path",
+			req: func() events.APIGatewayV2HTTPRequest {
+				req := newAPIGatewayRequest("GET", "")
+				req.RequestContext.HTTP.Path = "/households"
+				return req
+			},
+			check: func(t *testing.T, r *http.Request) {
+				if r.URL.Path != "/households" {
+					t.Errorf("Path = %q, want /households", r.URL.Path)
+				}
+			},
+		},
+		{
+			name: "base64 body",
+			req: func() events.APIGatewayV2HTTPRequest {
+				req := newAPIGatewayRequest("PUT", "/receipts")
+				req.Body = base64.StdEncoding.EncodeToString([]byte{0x89, 0x50, 0x00, 0xff})
+				req.IsBase64Encoded = true
+				return req
+			},
+			check: func(t *testing.T, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if !slices.Equal(body, []byte{0x89, 0x50, 0x00, 0xff}) || r.ContentLength != 4 {
+					t.Errorf("body = %v, ContentLength = %d", body, r.ContentLength)
+				}
+			},
+		},
+		{
+			name: "headers, cookies and host",
+			req: func() events.APIGatewayV2HTTPRequest {
+				req := newAPIGatewayRequest("GET", "/users")
+				req.Headers = map[string]string{"authorization": "Bearer token", "host": "api.example.com"}
+				req.Cookies = []string{"session=xyz", "theme=dark"}
+				return req
+			},
+			check: func(t *testing.T, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer token" {
+					t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+				}
+				if cookie, err := r.Cookie("theme"); err != nil || cookie.Value != "dark" {
+					t.Errorf("theme cookie = %v, %v", cookie, err)
+				}
+				if r.Host != "api.example.com" || r.RemoteAddr != "203.0.113.7" {
+					t.Errorf("Host = %q, RemoteAddr = %q", r.Host, r.RemoteAddr)
+				}
+			},
+		},
+		{
+			name: "host without a header",
+			req: func() events.APIGatewayV2HTTPRequest {
+				return newAPIGatewayRequest("GET", "/users")
+			},
+			check: func(t *testing.T, r *http.Request) {
+				if r.Host != "abc.execute-api.eu-west-1.amazonaws.com" {
+					t.Errorf("Host = %q, want the domain name", r.Host)
+				}
+			},
+		},
+		{
+			name: "original request",
+			req: func() events.APIGatewayV2HTTPRequest {
+				req := newAPIGatewayRequest("GET", "/users")
+				req.RouteKey = "GET /users"
+				return req
+			},
+			check: func(t *testing.T, r *http.Request) {
+				req, ok := APIGatewayRequest(r.Context())
+				if !ok || req.RouteKey != "GET /users" {
+					t.Errorf("APIGatewayRequest() = %q, %v", req.RouteKey, ok)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := NewHTTPRequest(context.Background(), test.req())
+			if err != nil {
+				t.Fatalf("NewHTTPRequest() error = %v", err)
+			}
+
+			test.check(t, r)
+		})
+	}
+}
+
+func TestNewHTTPRequestRejectsBadBody(t *testing.T) {
+	req := newAPIGatewayRequest("PUT", "/receipts")
+	req.Body = "not base64!"
+	req.IsBase64Encoded = true
+
+	if _, err := NewHTTPRequest(context.Background(), req); err == nil {
+		t.Error("NewHTTPRequest() error = nil, want the body turned down")
+	}
+}
+
+func TestAPIGatewayRequestOutsideLambda(t *testing.T) {
+	if _, ok := APIGatewayRequest(context.Background()); ok {
+		t.Error("APIGatewayRequest() ok = true outside of Lambda")
+	}
+}
+
+func TestAdapterRoundTrip(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/lists/:listId/items", func(c *gin.Context) {
+		body, _ := c.GetRawData()
+		c.SetCookie("seen", "1", 60, "/", "", false, true)
+		c.JSON(http.StatusCreated, gin.H{"listId": c.Param("listId"), "body": string(body), "limit": c.Query("limit")})
+	})
+
+	req := newAPIGatewayRequest("POST", "/lists/weekly%20shop/items")
+	req.RawQueryString = "limit=5"
+	req.Body = "milk"
+
+	r, err := NewHTTPRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("NewHTTPRequest() error = %v", err)
+	}
+
+	w := NewResponseWriter()
+	router.ServeHTTP(w, r)
+	response := w.APIGatewayResponse()
+
+	if response.StatusCode != http.StatusCreated || response.IsBase64Encoded {
+		t.Fatalf("response = %+v", response)
+	}
+	if response.Body != `{"body":"milk","limit":"5","listId":"weekly shop"}` {
+		t.Errorf("Body = %s", response.Body)
+	}
+	if len(response.Cookies) != 1 || !strings.HasPrefix(response.Cookies[0], "seen=1") {
+		t.Errorf("Cookies = %v", response.Cookies)
+	}
+}
@@ -1,22 +1,102 @@
 package utils
 
-import "net/http"
+import (
+	"bytes"
+	"encoding/base64"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
 
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ResponseWriter buffers a response so it can be returned to API Gateway in one piece
 type ResponseWriter struct {
-	Headers    map[string]string
-	StatusCode int
-	Body       string
+	header      http.Header
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func NewResponseWriter() *ResponseWriter {
+	return &ResponseWriter{header: http.Header{}}
 }
 
 func (rw *ResponseWriter) Header() http.Header {
-	return http.Header{}
+	if rw.header == nil {
+		rw.header = http.Header{}
+	}
+
+	return rw.header
 }
 
 func (rw *ResponseWriter) WriteHeader(statusCode int) {
-	rw.StatusCode = statusCode
+	if rw.wroteHeader {
+		return
+	}
+
+	rw.statusCode = statusCode
+	rw.wroteHeader = true
 }
 
 func (rw *ResponseWriter) Write(body []byte) (int, error) {
-	rw.Body = string(body)
-	return len(body), nil
+	rw.WriteHeader(http.StatusOK)
+	return rw.body.Write(body)
+}
+
+// Flush is a no-op, the whole response is sent once the handler returns
+func (rw *ResponseWriter) Flush() {}
+
+// APIGatewayResponse converts the buffered response into an API Gateway v2 response.
+// Bodies that aren't text are base64 encoded and Set-Cookie headers become cookies.
+func (rw *ResponseWriter) APIGatewayResponse() events.APIGatewayV2HTTPResponse {
+	statusCode := rw.statusCode
+	if !rw.wroteHeader {
+		statusCode = http.StatusOK
+	}
+
+	response := events.APIGatewayV2HTTPResponse{
+		StatusCode: statusCode,
+		Headers:    map[string]string{},
+	}
+
+	for key, values := range rw.Header() {
+		if http.CanonicalHeaderKey(key) == "Set-Cookie" {
+			response.Cookies = append(response.Cookies, values...)
+			continue
+		}
+
+		// Payload format 2.0 only supports single value headers, repeated values are comma separated
+		response.Headers[key] = strings.Join(values, ", ")
+	}
+
+	body := rw.body.Bytes()
+	if isTextContent(rw.Header().Get("Content-Type"), body) {
+		response.Body = string(body)
+	} else {
+		response.Body = base64.StdEncoding.EncodeToString(body)
+		response.IsBase64Encoded = true
+	}
+
+	return response
+}
+
+func isTextContent(contentType string, body []byte) bool {
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	isText := strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "json") ||
+		strings.HasSuffix(mediaType, "xml") ||
+		mediaType == "application/javascript" ||
+		mediaType == "application/x-www-form-urlencoded"
+
+	return isText && utf8.Valid(body)
 }
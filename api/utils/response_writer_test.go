@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"encoding/base64"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestResponseWriterAPIGatewayResponse(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(w http.ResponseWriter)
+		check func(t *testing.T, w *ResponseWriter)
+	}{
+		{
+			name: "status written once",
+			write: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusCreated)
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte("{}"))
+			},
+			check: func(t *testing.T, w *ResponseWriter) {
+				if response := w.APIGatewayResponse(); response.StatusCode != http.StatusCreated {
+					t.Errorf("StatusCode = %d, want %d", response.StatusCode, http.StatusCreated)
+				}
+			},
+		},
+		{
+			name:  "nothing written",
+			write: func(w http.ResponseWriter) {},
+			check: func(t *testing.T, w *ResponseWriter) {
+				if response := w.APIGatewayResponse(); response.StatusCode != http.StatusOK || response.Body != "" {
+					t.Errorf("response = %+v, want an empty 200", response)
+				}
+			},
+		},
+		{
+			name: "json body",
+			write: func(w http.ResponseWriter) {
+				w.Header().Set("Content-Type", "application/json; charset=utf-8")
+				w.Write([]byte(`{"name":"milk"}`))
+			},
+			check: func(t *testing.T, w *ResponseWriter) {
+				response := w.APIGatewayResponse()
+				if response.IsBase64Encoded || response.Body != `{"name":"milk"}` {
+					t.Errorf("response = %+v, want the body as text", response)
+				}
+			},
+		},
+		{
+			name: "binary body",
+			write: func(w http.ResponseWriter) {
+				w.Header().Set("Content-Type", "image/png")
+				w.Write([]byte{0x89, 0x50, 0x00, 0xff})
+			},
+			check: func(t *testing.T, w *ResponseWriter) {
+				response := w.APIGatewayResponse()
+				if !response.IsBase64Encoded || response.Body != base64.StdEncoding.EncodeToString([]byte{0x89, 0x50, 0x00, 0xff}) {
+					t.Errorf("response = %+v, want the body base64 encoded", response)
+				}
+			},
+		},
+		{
+			name: "text that isn't utf-8",
+			write: func(w http.ResponseWriter) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.Write([]byte{0xff, 0xfe})
+			},
+			check: func(t *testing.T, w *ResponseWriter) {
+				if response := w.APIGatewayResponse(); !response.IsBase64Encoded {
+					t.Errorf("response = %+v, want the body base64 encoded", response)
+				}
+			},
+		},
+		{
+			name: "repeated headers and cookies",
+			write: func(w http.ResponseWriter) {
+				w.Header().Add("Vary", "Origin")
+				w.Header().Add("Vary", "Accept-Encoding")
+				http.SetCookie(w, &http.Cookie{Name: "a", Value: "1"})
+				http.SetCookie(w, &http.Cookie{Name: "b", Value: "2"})
+			},
+			check: func(t *testing.T, w *ResponseWriter) {
+				response := w.APIGatewayResponse()
+				if response.Headers["Vary"] != "Origin, Accept-Encoding" {
+					t.Errorf("Vary = %q", response.Headers["Vary"])
+				}
+				if _, ok := response.Headers["Set-Cookie"]; ok {
+					t.Errorf("Set-Cookie is in the headers")
+				}
+				if !slices.Equal(response.Cookies, []string{"a=1", "b=2"}) {
+					t.Errorf("Cookies = %v", response.Cookies)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := NewResponseWriter()
+			test.write(w)
+			test.check(t, w)
+		})
+	}
+}
@@ -11,10 +11,20 @@ import { DnsStack } from "../lib/dns-stack";
 const domainName = "DOMAIN-NAME.COM HERE";
 const hostedZoneId = "HOSTED-ZONE-HERE";
 
+// Identity provider that signs the bearer tokens the api accepts
+const jwksUrl = "JWKS-URL HERE";
+const jwtIssuer = "JWT-ISSUER HERE";
+const jwtAudience = "JWT-AUDIENCE HERE";
+
 const app = new cdk.App();
 const env = { account: "ACCOUNT_ID", region: "REGION" };
 
-const { lambdaFunction } = new HttpApiStack(app, "RestApiStack", { env });
+const { lambdaFunction } = new HttpApiStack(app, "RestApiStack", {
+  env,
+  jwksUrl,
+  jwtIssuer,
+  jwtAudience,
+});
 const { hostedZone, certificate } = new CertificateStack(
   app,
   "CertificateStack",
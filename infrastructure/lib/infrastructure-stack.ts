@@ -17,13 +17,22 @@ interface InfrastructureStackProps {
 
 export class InfrastructureStack extends cdk.Stack {
   public readonly householdsTable: Table;
+  public readonly householdInvitesTable: Table;
+  public readonly householdMembersTable: Table;
   public readonly tasksTable: Table;
   public readonly groceriesTable: Table;
   public readonly usersTable: Table;
+  public readonly devicesTable: Table;
+  public readonly pairingCodesTable: Table;
+  public readonly groceryLayoutsTable: Table;
+  public readonly groceryOpsTable: Table;
+  public readonly groceryListsTable: Table;
+  public readonly groceryStaplesTable: Table;
   public readonly expensesTable: Table;
   public readonly catalogBucket: Bucket;
   public readonly unprocessedReceiptsBucket: Bucket;
   public readonly processedReceiptsBucket: Bucket;
+  public readonly accountExportsBucket: Bucket;
 
   constructor(
     scope: Construct,
@@ -42,6 +51,18 @@ export class InfrastructureStack extends cdk.Stack {
         type: AttributeType.STRING,
         name: "id",
       },
+      timeToLiveAttribute: "expiresAt",
+    });
+    this.groceriesTable.addGlobalSecondaryIndex({
+      indexName: "householdId-updatedAt-index",
+      partitionKey: {
+        type: AttributeType.STRING,
+        name: "householdId",
+      },
+      sortKey: {
+        type: AttributeType.NUMBER,
+        name: "updatedAt",
+      },
     });
     this.groceriesTable.grantFullAccess(props!.lambdaFunction);
 
@@ -52,8 +73,45 @@ export class InfrastructureStack extends cdk.Stack {
         name: "id",
       },
     });
+    this.householdsTable.addGlobalSecondaryIndex({
+      indexName: "deletionStatus-index",
+      partitionKey: {
+        type: AttributeType.STRING,
+        name: "deletionStatus",
+      },
+    });
     this.householdsTable.grantFullAccess(props!.lambdaFunction);
 
+    this.householdInvitesTable = new Table(this, "HouseholdInvites", {
+      tableName: "HouseholdInvites",
+      partitionKey: {
+        type: AttributeType.STRING,
+        name: "code",
+      },
+      timeToLiveAttribute: "expiresAt",
+    });
+    this.householdInvitesTable.addGlobalSecondaryIndex({
+      indexName: "householdId-index",
+      partitionKey: {
+        type: AttributeType.STRING,
+        name: "householdId",
+      },
+    });
+    this.householdInvitesTable.grantFullAccess(props!.lambdaFunction);
+
+    this.householdMembersTable = new Table(this, "HouseholdMembers", {
+      tableName: "HouseholdMembers",
+      partitionKey: {
+        type: AttributeType.STRING,
+        name: "householdId",
+      },
+      sortKey: {
+        type: AttributeType.STRING,
+        name: "userId",
+      },
+    });
+    this.householdMembersTable.grantFullAccess(props!.lambdaFunction);
+
     this.usersTable = new Table(this, "Users", {
       tableName: "Users",
       partitionKey: {
@@ -63,6 +121,96 @@ export class InfrastructureStack extends cdk.Stack {
     });
     this.usersTable.grantFullAccess(props!.lambdaFunction);
 
+    this.devicesTable = new Table(this, "Devices", {
+      tableName: "Devices",
+      partitionKey: {
+        type: AttributeType.STRING,
+        name: "id",
+      },
+    });
+    this.devicesTable.addGlobalSecondaryIndex({
+      indexName: "userId-index",
+      partitionKey: {
+        type: AttributeType.STRING,
+        name: "userId",
+      },
+    });
+    this.devicesTable.grantFullAccess(props!.lambdaFunction);
+
+    this.pairingCodesTable = new Table(this, "PairingCodes", {
+      tableName: "PairingCodes",
+      partitionKey: {
+        type: AttributeType.STRING,
+        name: "code",
+      },
+      timeToLiveAttribute: "expiresAt",
+    });
+    this.pairingCodesTable.grantFullAccess(props!.lambdaFunction);
+
+    this.groceryLayoutsTable = new Table(this, "GroceryLayouts", {
+      tableName: "GroceryLayouts",
+      partitionKey: {
+        type: AttributeType.STRING,
+        name: "householdId",
+      },
+      sortKey: {
+        type: AttributeType.STRING,
+        name: "listId",
+      },
+    });
+    this.groceryLayoutsTable.grantFullAccess(props!.lambdaFunction);
+
+    this.groceryOpsTable = new Table(this, "GroceryOps", {
+      tableName: "GroceryOps",
+      partitionKey: {
+        type: AttributeType.STRING,
+        name: "householdId",
+      },
+      sortKey: {
+        type: AttributeType.STRING,
+        name: "opId",
+      },
+      timeToLiveAttribute: "expiresAt",
+    });
+    this.groceryOpsTable.grantFullAccess(props!.lambdaFunction);
+
+    this.groceryListsTable = new Table(this, "GroceryLists", {
+      tableName: "GroceryLists",
+      partitionKey: {
+        type: AttributeType.STRING,
+        name: "householdId",
+      },
+      sortKey: {
+        type: AttributeType.STRING,
+        name: "id",
+      },
+    });
+    this.groceryListsTable.grantFullAccess(props!.lambdaFunction);
+
+    this.groceryStaplesTable = new Table(this, "GroceryStaples", {
+      tableName: "GroceryStaples",
+      partitionKey: {
+        type: AttributeType.STRING,
+        name: "householdId",
+      },
+      sortKey: {
+        type: AttributeType.STRING,
+        name: "id",
+      },
+    });
+    this.groceryStaplesTable.addGlobalSecondaryIndex({
+      indexName: "scheduleId-nextDueAt-index",
+      partitionKey: {
+        type: AttributeType.STRING,
+        name: "scheduleId",
+      },
+      sortKey: {
+        type: AttributeType.NUMBER,
+        name: "nextDueAt",
+      },
+    });
+    this.groceryStaplesTable.grantFullAccess(props!.lambdaFunction);
+
     this.catalogBucket = new Bucket(this, "CatalogBucket", {
       bucketName: "store-comparison-bucket-001",
     });
@@ -97,6 +245,14 @@ export class InfrastructureStack extends cdk.Stack {
     this.processedReceiptsBucket = new Bucket(this, "ProcessedReceipts", {
       bucketName: "processed-receipts-001",
     });
+    this.processedReceiptsBucket.grantReadWrite(props!.lambdaFunction);
+
+    // Exports are downloaded through presigned urls right after they are made
+    this.accountExportsBucket = new Bucket(this, "AccountExports", {
+      bucketName: "account-exports-001",
+      lifecycleRules: [{ expiration: cdk.Duration.days(1) }],
+    });
+    this.accountExportsBucket.grantReadWrite(props!.lambdaFunction);
   }
 
   private createPresignedUrlRole(bucket: Bucket) {
@@ -7,6 +7,16 @@ import {
   HttpMethod,
 } from "aws-cdk-lib/aws-apigatewayv2";
 import { HttpLambdaIntegration } from "aws-cdk-lib/aws-apigatewayv2-integrations";
+import { Rule, RuleTargetInput, Schedule } from "aws-cdk-lib/aws-events";
+import { LambdaFunction } from "aws-cdk-lib/aws-events-targets";
+
+interface HttpApiStackProps extends cdk.StackProps {
+  // Where the token issuer publishes its signing keys, the api fetches them on cold start
+  jwksUrl: string;
+  // Checked against the iss and aud claims of bearer tokens
+  jwtIssuer: string;
+  jwtAudience: string;
+}
 
 export class HttpApiStack extends cdk.Stack {
   private static readonly ROUTES = [
@@ -17,8 +27,23 @@ export class HttpApiStack extends cdk.Stack {
     "/groceries/{id+}",
     "/users",
     "/users/{id+}",
+    "/devices/pair",
     "/households",
-    "/households/join/{householdId}/{userId+}",
+    "/households/join",
+    "/households/{householdId}",
+    "/households/{householdId}/transfer",
+    "/households/{householdId}/members",
+    "/households/{householdId}/events",
+    "/households/{householdId}/members/{userId}",
+    "/households/{householdId}/members/{userId}/role",
+    "/households/{householdId}/invites",
+    "/households/{householdId}/invites/{code}",
+    "/households/{householdId}/lists",
+    "/households/{householdId}/lists/{listId}",
+    "/households/{householdId}/lists/{listId}/items/move",
+    "/households/{householdId}/lists/{listId}/items/copy",
+    "/households/{householdId}/staples",
+    "/households/{householdId}/staples/{stapleId}",
     "/households/leave/{householdId}/{userId+}",
     "/catalog",
     "/receipt/upload",
@@ -26,7 +51,7 @@ export class HttpApiStack extends cdk.Stack {
 
   public readonly lambdaFunction: Function;
 
-  constructor(scope: Construct, id: string, props?: cdk.StackProps) {
+  constructor(scope: Construct, id: string, props: HttpApiStackProps) {
     super(scope, id, props);
 
     this.lambdaFunction = new Function(this, "GoFunction", {
@@ -35,6 +60,12 @@ export class HttpApiStack extends cdk.Stack {
       code: Code.fromAsset("../api/dist"),
       timeout: cdk.Duration.seconds(60),
       memorySize: 512,
+      environment: {
+        API_STAGE: "prod",
+        API_JWKS_URL: props.jwksUrl,
+        API_JWT_ISSUER: props.jwtIssuer,
+        API_JWT_AUDIENCE: props.jwtAudience,
+      },
     });
 
     const lambdaIntegration = new HttpLambdaIntegration(
@@ -59,6 +90,26 @@ export class HttpApiStack extends cdk.Stack {
       });
     });
 
+    // Finish household deletions that were interrupted
+    new Rule(this, "DeleteHouseholdsSchedule", {
+      schedule: Schedule.rate(cdk.Duration.minutes(5)),
+      targets: [
+        new LambdaFunction(this.lambdaFunction, {
+          event: RuleTargetInput.fromObject({ job: "delete-households" }),
+        }),
+      ],
+    });
+
+    // Add the staples that are due, staples are scheduled by the day so hourly is close enough
+    new Rule(this, "AddStaplesSchedule", {
+      schedule: Schedule.rate(cdk.Duration.hours(1)),
+      targets: [
+        new LambdaFunction(this.lambdaFunction, {
+          event: RuleTargetInput.fromObject({ job: "add-staples" }),
+        }),
+      ],
+    });
+
     // Output the API endpoint URL
     new cdk.CfnOutput(this, "ApiEndpoint", {
       value: httpApi.apiEndpoint,
@@ -0,0 +1,143 @@
+import { useState } from "react";
+import { Device, PairingCode } from "../../services/user-service";
+import { Devices, ArrowForward, Close } from "@mui/icons-material";
+import {
+  IconButton,
+  Drawer,
+  Box,
+  Typography,
+  Stack,
+  Input,
+  List,
+  ListItem,
+  ListItemContent,
+} from "@mui/joy";
+
+interface LinkedDevicesProps {
+  createPairingCode(): Promise<PairingCode>;
+  pairDevice(code: string): Promise<void>;
+  getDevices(): Promise<Device[]>;
+  revokeDevice(deviceId: string): Promise<void>;
+}
+
+export function LinkedDevices({
+  createPairingCode,
+  pairDevice,
+  getDevices,
+  revokeDevice,
+}: LinkedDevicesProps) {
+  const [pairingCode, setPairingCode] = useState<PairingCode>();
+  const [code, setCode] = useState("");
+  const [codeHasError, setCodeHasError] = useState(false);
+  const [devices, setDevices] = useState<Device[]>([]);
+  const [isDrawerOpen, setIsDrawerOpen] = useState(false);
+
+  function openDrawer() {
+    setIsDrawerOpen(true);
+    createPairingCode().then(setPairingCode);
+    getDevices().then(setDevices);
+  }
+
+  function closeDrawer() {
+    setIsDrawerOpen(false);
+    setCode("");
+    setCodeHasError(false);
+    setPairingCode(undefined);
+  }
+
+  function linkThisDevice() {
+    const codePattern = /^[0-9a-zA-Z]{4}[- ]?[0-9a-zA-Z]{4}$/;
+
+    if (!codePattern.test(code.trim())) {
+      setCodeHasError(true);
+      return;
+    }
+
+    pairDevice(code.trim());
+    closeDrawer();
+  }
+
+  function handleRevokeDevice(deviceId: string) {
+    revokeDevice(deviceId).then(() =>
+      setDevices(devices.filter((device) => device.id !== deviceId))
+    );
+  }
+
+  return (
+    <>
+      <IconButton onClick={openDrawer}>
+        <Devices />
+      </IconButton>
+      <Drawer anchor="bottom" open={isDrawerOpen} onClose={closeDrawer}>
+        <Box height="100%" padding="24px">
+          <Stack width="100%" px="24px" gap={4}>
+            <Stack>
+              <Typography level="body-sm">
+                Enter this code on your other device to use your lists there
+              </Typography>
+              <Typography level="h3">
+                {pairingCode?.code ?? "creating code..."}
+              </Typography>
+              {pairingCode && (
+                <Typography level="body-xs">
+                  Expires at{" "}
+                  {new Date(pairingCode.expiresAt * 1000).toLocaleTimeString()}
+                </Typography>
+              )}
+            </Stack>
+
+            <Stack>
+              <Typography level="body-sm">
+                Or link this device to another one
+              </Typography>
+              <Box display="flex" alignItems="center">
+                <Input
+                  placeholder="pairing code"
+                  value={code}
+                  onChange={(e) => setCode(e.target.value)}
+                  fullWidth
+                  error={codeHasError}
+                />
+                <IconButton onClick={linkThisDevice}>
+                  <ArrowForward />
+                </IconButton>
+              </Box>
+            </Stack>
+
+            <Stack>
+              <Typography level="body-sm">Linked devices</Typography>
+              <List>
+                {devices.map((device) => (
+                  <ListItem
+                    key={device.id}
+                    endAction={
+                      !device.current && (
+                        <IconButton
+                          color="danger"
+                          onClick={() => handleRevokeDevice(device.id)}
+                        >
+                          <Close />
+                        </IconButton>
+                      )
+                    }
+                  >
+                    <ListItemContent>
+                      <Typography level="body-sm" noWrap>
+                        {device.name || device.id}
+                        {device.current && " (this device)"}
+                      </Typography>
+                      <Typography level="body-xs">
+                        Linked{" "}
+                        {new Date(device.linkedAt * 1000).toLocaleDateString()}
+                      </Typography>
+                    </ListItemContent>
+                  </ListItem>
+                ))}
+              </List>
+            </Stack>
+          </Stack>
+        </Box>
+      </Drawer>
+    </>
+  );
+}
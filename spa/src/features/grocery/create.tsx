@@ -21,7 +21,7 @@ export function createGroceryScreen(
       checkGroceryItem={store.checkGroceryItem}
       clearCheckedItems={store.clearCheckedItems}
       createGroceryItem={store.createGroceryItem}
-      fetchGroceries={store.fetchGroceriesIfTabFocussed}
+      watchGroceryList={store.watchGroceryList}
       initializeGroceryList={store.initializeGroceryList}
     />
   ));
@@ -13,6 +13,31 @@ const containerStyles = {
   bgcolor: "background.paper",
 };
 
+const UNIT_ABBREVIATIONS: Record<string, string> = {
+  kilogram: "kg",
+  gram: "g",
+  liter: "L",
+  milliliter: "ml",
+  pound: "lb",
+  ounce: "oz",
+  tbl: "tbsp",
+};
+
+function describeGroceryItem({ name, quantity, unit }: GroceryItem): string {
+  if (!quantity) {
+    return name;
+  }
+
+  if (!unit) {
+    return `${quantity} ${name}`;
+  }
+
+  const abbreviation = UNIT_ABBREVIATIONS[unit];
+  return abbreviation
+    ? `${quantity}${abbreviation} ${name}`
+    : `${quantity} ${unit} ${name}`;
+}
+
 export function GroceryList({
   groceries,
   layout,
@@ -39,11 +64,13 @@ export function GroceryList({
 
   return (
     <List sx={containerStyles}>
-      {layout.map(({ type, value }) => {
+      {layout.map(({ id, type, value }) => {
+        const groceryItem = groceries.find(({ id }) => id === value);
+
         return (
           <ListItem
             sx={{ width: "100%", height: "48px" }}
-            key={value}
+            key={id ?? value}
             onClick={() => {
               if (type !== "GroceryItemId") {
                 return;
@@ -57,13 +84,9 @@ export function GroceryList({
                 sx={{ display: "flex", justifyContent: "space-between" }}
               >
                 <Typography>
-                  {groceries.find(({ id }) => id === value)?.name}
+                  {groceryItem && describeGroceryItem(groceryItem)}
                 </Typography>
-                <Checkbox
-                  checked={
-                    groceries.find(({ id }) => id === value)?.checked ?? false
-                  }
-                />
+                <Checkbox checked={groceryItem?.checked ?? false} />
               </ListItemButton>
             ) : (
               <Typography level="h4">{value}</Typography>
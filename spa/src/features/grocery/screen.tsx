@@ -5,12 +5,9 @@ import {
   GroceryList as GroceryListModel,
   StoreName,
 } from "../../services/grocery-service";
-import { poll } from "../../utils/poll";
 import { GroceryList } from "./grocery-list";
 import { MoreInfoSheet } from "../more-info/more-info-sheet";
 
-const POLL_INTERVAL = 8000;
-
 export function GroceryScreen({
   groceryItemText,
   groceryList,
@@ -23,7 +20,7 @@ export function GroceryScreen({
   clearCheckedItems,
   createGroceryItem,
   initializeGroceryList,
-  fetchGroceries,
+  watchGroceryList,
   toggleStore,
 }: {
   groceryItemText: string;
@@ -39,17 +36,13 @@ export function GroceryScreen({
     householdId: string
   ): (e: FormEvent<HTMLFormElement>) => void;
   initializeGroceryList(householdId: string): void;
-  fetchGroceries(householdId: string): void;
+  watchGroceryList(householdId: string): () => void;
   toggleStore(storeName: StoreName): void;
 }) {
   useEffect(() => {
     initializeGroceryList(householdId);
-    const interval = poll(() => fetchGroceries(householdId), POLL_INTERVAL);
-
-    return () => {
-      clearInterval(interval);
-    };
-  }, [householdId, initializeGroceryList, fetchGroceries]);
+    return watchGroceryList(householdId);
+  }, [householdId, initializeGroceryList, watchGroceryList]);
 
   return (
     <Stack
@@ -8,9 +8,14 @@ import {
 } from "../../services/grocery-service";
 import { ChangeEvent, FormEvent } from "react";
 import { UserStore } from "../../store/user-store";
+import { HouseholdService } from "../../services/household-service";
+import { wait } from "../../utils/wait";
 
 const MAGIC_ENABLED_STORAGE_KEY = "MAGIC_ENABLED";
 export const RAW_DATA_SELECTED_STORES_KEY = "RAW_DATA_SELECTED_STORES";
+const EVENTS_RETRY_INTERVAL = 5000;
+// Polls that come back empty or reset are followed by a refetch no more often than this
+const REFETCH_INTERVAL = 8000;
 
 export class GroceryListStore {
   isFetching = false;
@@ -23,6 +28,7 @@ export class GroceryListStore {
   constructor(
     private readonly groceryService: GroceryService,
     private readonly userStore: UserStore,
+    private readonly householdService: HouseholdService,
     private readonly storage: Storage = window.localStorage
   ) {
     makeAutoObservable(this);
@@ -64,6 +70,56 @@ export class GroceryListStore {
     }
   };
 
+  // watchGroceryList refetches the list whenever another member changes the household, until the
+  // returned function is called. Events are only seen by the instance that published them, so
+  // polls that come back empty still refetch the list while the tab is focussed.
+  watchGroceryList = (householdId: string) => {
+    let isWatching = true;
+
+    const watch = async () => {
+      let since: string | undefined;
+
+      while (isWatching) {
+        const startedAt = Date.now();
+        try {
+          const { events, lastEventId } =
+            await this.householdService.getEvents(householdId, since);
+          since = lastEventId;
+          if (!isWatching) {
+            break;
+          }
+
+          const isReset = events.some((e) => e.type === "reset");
+          if (events.length > 0 && !isReset) {
+            await this.fetchGroceryList(householdId);
+            continue;
+          }
+
+          // Each instance resets polls for event ids it didn't publish, so resets are spaced out too
+          await wait(REFETCH_INTERVAL - (Date.now() - startedAt));
+          if (!isWatching) {
+            break;
+          }
+
+          if (isReset) {
+            await this.fetchGroceryList(householdId);
+          } else {
+            await this.fetchGroceriesIfTabFocussed(householdId);
+          }
+        } catch (error) {
+          console.error("Watching household events failed:", error);
+          await wait(EVENTS_RETRY_INTERVAL);
+        }
+      }
+    };
+
+    watch();
+
+    return () => {
+      isWatching = false;
+    };
+  };
+
   fetchGroceriesIfTabFocussed = async (householdId: string) => {
     if (document.hasFocus()) {
       await this.fetchGroceryList(householdId);
@@ -110,7 +166,9 @@ export class GroceryListStore {
       items: this.groceryList.items.map((item: GroceryItem) => {
         if (item.id === id) {
           item.checked = !item.checked;
-          this.groceryService.updateGroceryItem(item);
+          this.groceryService
+            .updateGroceryItem(item)
+            .then((storedItem) => Object.assign(item, storedItem));
         }
 
         return item;
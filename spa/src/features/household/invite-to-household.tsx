@@ -1,4 +1,5 @@
 import { useState } from "react";
+import { HouseholdInvite } from "../../services/household-service";
 import { InsertLink, AddHome, ArrowForward } from "@mui/icons-material";
 import {
   IconButton,
@@ -16,8 +17,9 @@ interface InviteToHouseholdProps {
   householdId?: string;
   isLoading: boolean;
   leaveHousehold(): Promise<void>;
-  joinHousehold(householdId: string): Promise<void>;
+  joinHousehold(code: string): Promise<void>;
   createAndJoinHousehold(): Promise<void>;
+  createInvite(): Promise<HouseholdInvite>;
 }
 
 export function CreateAndInviteToHousehold({
@@ -26,9 +28,11 @@ export function CreateAndInviteToHousehold({
   leaveHousehold,
   createAndJoinHousehold,
   joinHousehold,
+  createInvite,
 }: InviteToHouseholdProps) {
-  const [groceryListId, setGroceryListId] = useState("");
-  const [groceryListIdHasError, setGroceryListIdHasError] = useState(false);
+  const [inviteCode, setInviteCode] = useState("");
+  const [inviteCodeHasError, setInviteCodeHasError] = useState(false);
+  const [invite, setInvite] = useState<HouseholdInvite>();
   const [isTooltipOpen, setIsTooltipOpen] = useState(false);
   const [isDrawerOpen, setIsDrawerOpen] = useState(false);
 
@@ -36,7 +40,12 @@ export function CreateAndInviteToHousehold({
   if (window.location.port) {
     joinUrl += `:${window.location.port}`;
   }
-  joinUrl += `/households/join/${householdId}`;
+  joinUrl += `/households/join/${invite?.code ?? ""}`;
+
+  function openDrawer() {
+    setIsDrawerOpen(true);
+    createInvite().then(setInvite);
+  }
 
   function copyJoinUrl() {
     setIsTooltipOpen(true);
@@ -49,24 +58,24 @@ export function CreateAndInviteToHousehold({
 
   function closeDrawer() {
     setIsDrawerOpen(false);
-    setGroceryListId("");
-    setGroceryListIdHasError(false);
+    setInviteCode("");
+    setInviteCodeHasError(false);
+    setInvite(undefined);
   }
 
   function joinGroceryList() {
-    const pattern = /\/households\/join\/([0-9a-fA-F-]{36})/;
-    const parsedId = groceryListId.match(pattern)?.[1] ?? groceryListId;
+    const pattern = /\/households\/join\/([^/?#]+)/;
+    const parsedCode = inviteCode.match(pattern)?.[1] ?? inviteCode;
 
-    const uuidPattern =
-      /^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[4][0-9a-fA-F]{3}-[89abAB][0-9a-fA-F]{3}-[0-9a-fA-F]{12}$/;
-    const isValid = uuidPattern.test(parsedId);
+    const codePattern = /^[0-9a-zA-Z]{4}[- ]?[0-9a-zA-Z]{4}$/;
+    const isValid = codePattern.test(parsedCode.trim());
 
     if (!isValid) {
-      setGroceryListIdHasError(true);
+      setInviteCodeHasError(true);
       return;
     }
 
-    joinHousehold(parsedId);
+    joinHousehold(parsedCode.trim());
     closeDrawer();
   }
 
@@ -82,7 +91,7 @@ export function CreateAndInviteToHousehold({
   ) {
     return (
       <>
-        <IconButton onClick={() => setIsDrawerOpen(true)}>
+        <IconButton onClick={openDrawer}>
           <InsertLink />
         </IconButton>
         <Drawer anchor="bottom" open={isDrawerOpen} onClose={closeDrawer}>
@@ -106,21 +115,31 @@ export function CreateAndInviteToHousehold({
               <Stack width="100%" gap={4}>
                 <Stack>
                   <Typography level="body-sm">
-                    Share this link to share your grocery list
+                    Share this link or code to share your grocery list
                   </Typography>
                   <Box display="flex" alignItems="center">
-                    <Input value={joinUrl} fullWidth />
+                    <Input
+                      value={invite ? joinUrl : ""}
+                      placeholder="creating invite..."
+                      fullWidth
+                    />
                     <Tooltip
                       open={isTooltipOpen}
                       onClose={() => {}}
                       title="Copied!"
                       disableHoverListener
                     >
-                      <IconButton onClick={copyJoinUrl}>
+                      <IconButton onClick={copyJoinUrl} disabled={!invite}>
                         <InsertLink />
                       </IconButton>
                     </Tooltip>
                   </Box>
+                  {invite && (
+                    <Typography level="body-xs">
+                      Code {invite.code}, expires{" "}
+                      {new Date(invite.expiresAt * 1000).toLocaleDateString()}
+                    </Typography>
+                  )}
                 </Stack>
 
                 <Stack>
@@ -129,11 +148,11 @@ export function CreateAndInviteToHousehold({
                   </Typography>
                   <Box display="flex" alignItems="center">
                     <Input
-                      placeholder="grocery list id"
-                      value={groceryListId}
-                      onChange={(e) => setGroceryListId(e.target.value)}
+                      placeholder="invite code"
+                      value={inviteCode}
+                      onChange={(e) => setInviteCode(e.target.value)}
                       fullWidth
-                      error={groceryListIdHasError}
+                      error={inviteCodeHasError}
                     />
                     <IconButton onClick={joinGroceryList}>
                       <ArrowForward />
@@ -8,8 +8,10 @@ import {
 import { createGroceryScreen } from "./features/grocery/create";
 import { UserStore } from "./store/user-store";
 import { CreateAndInviteToHousehold as CreateAndInviteToHouseholdImpl } from "./features/household/invite-to-household";
+import { LinkedDevices as LinkedDevicesImpl } from "./features/devices/linked-devices";
 import { UserService } from "./services/user-service";
 import { ApiService } from "./services/api-service";
+import { createAuthenticator } from "./services/auth-service";
 import { GroceryService } from "./services/grocery-service";
 import { HouseholdService } from "./services/household-service";
 import { observer } from "mobx-react-lite";
@@ -21,14 +23,18 @@ import { CatalogService } from "./services/catalog-service";
 import { ReceiptService } from "./services/receipt-service";
 import { createRawDataForBusiness } from "./features/raw-data/business/create";
 
-const apiService = new ApiService(window.fetch);
+const apiService = new ApiService(window.fetch, createAuthenticator());
 const groceryService = new GroceryService(apiService);
 const catalogService = new CatalogService(apiService);
 const receiptService = new ReceiptService(apiService);
 const userService = new UserService(apiService);
 const householdService = new HouseholdService(apiService);
 const userStore = new UserStore(userService, householdService);
-const groceryStore = new GroceryListStore(groceryService, userStore);
+const groceryStore = new GroceryListStore(
+  groceryService,
+  userStore,
+  householdService
+);
 
 const CreateAndInviteToHousehold = observer(() => {
   if (!userStore.userId) {
@@ -41,11 +47,27 @@ const CreateAndInviteToHousehold = observer(() => {
       isLoading={userStore.isLoading}
       leaveHousehold={userStore.leaveHousehold}
       joinHousehold={userStore.joinHousehold}
+      createInvite={userStore.createInvite}
       createAndJoinHousehold={userStore.createAndJoinHousehold}
     />
   );
 });
 
+const LinkedDevices = observer(() => {
+  if (!userStore.userId) {
+    return null;
+  }
+
+  return (
+    <LinkedDevicesImpl
+      createPairingCode={userStore.createPairingCode}
+      pairDevice={userStore.pairDevice}
+      getDevices={userStore.getDevices}
+      revokeDevice={userStore.revokeDevice}
+    />
+  );
+});
+
 const EndIcons = observer(() => (
   <Box display="flex" alignItems="center">
     <AutoCompleteGroceries
@@ -55,6 +77,7 @@ const EndIcons = observer(() => (
       magic={groceryStore.magic}
     />
     <CreateAndInviteToHousehold />
+    <LinkedDevices />
   </Box>
 ));
 
@@ -74,13 +97,27 @@ const indexRoute = createRoute({
 
 const joinHouseholdRoute = createRoute({
   getParentRoute: () => rootRoute,
-  path: "/households/join/$householdId",
+  path: "/households/join/$code",
   component: function JoinHousehold() {
     /** @ts-ignore */
-    const { householdId } = joinHouseholdRoute.useParams();
+    const { code } = joinHouseholdRoute.useParams();
+    const navigate = useNavigate();
+
+    userStore.joinHousehold(code).then(() => navigate({ to: "/" }));
+
+    return null;
+  },
+});
+
+const pairDeviceRoute = createRoute({
+  getParentRoute: () => rootRoute,
+  path: "/devices/pair/$code",
+  component: function PairDevice() {
+    /** @ts-ignore */
+    const { code } = pairDeviceRoute.useParams();
     const navigate = useNavigate();
 
-    userStore.joinHousehold(householdId).then(() => navigate({ to: "/" }));
+    userStore.pairDevice(code).then(() => navigate({ to: "/" }));
 
     return null;
   },
@@ -113,6 +150,7 @@ const rawDataForBusinessRoute = createRoute({
 const routeTree = rootRoute.addChildren([
   indexRoute,
   joinHouseholdRoute,
+  pairDeviceRoute,
   rawDataRoute,
   rawDataForBusinessRoute,
 ]);
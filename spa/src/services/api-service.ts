@@ -1,7 +1,18 @@
+import { Authenticator } from "./auth-service";
+
 export class ApiService {
   private static readonly BASE_URL = process.env.REACT_APP_API_BASE_URL;
 
-  constructor(private readonly fetch: typeof window.fetch) {}
+  private accessToken?: string;
+
+  constructor(
+    private readonly fetch: typeof window.fetch,
+    private readonly authenticator: Authenticator
+  ) {}
+
+  public setAccessToken(accessToken: string | undefined) {
+    this.accessToken = accessToken;
+  }
 
   public get<T>(url: string): Promise<T> {
     return this.makeRequest<T>(url, "GET");
@@ -19,16 +30,43 @@ export class ApiService {
     return this.makeRequest(url, "PUT", body);
   }
 
+  public patch<T = void>(url: string, body?: any): Promise<T> {
+    return this.makeRequest(url, "PATCH", body);
+  }
+
   private async makeRequest<T = void>(
     url: string,
     method: string,
     body?: any
   ): Promise<T> {
+    const res = await this.send(url, method, body);
+
+    return res.json();
+  }
+
+  // send signs in before the first request, and again once when the api turns the token down
+  private async send(
+    url: string,
+    method: string,
+    body?: any,
+    isRetry = false
+  ): Promise<Response> {
+    if (!this.accessToken) {
+      this.setAccessToken(await this.authenticator.getAccessToken());
+    }
+    const accessToken = this.accessToken;
+
+    const headers: Record<string, string> = {
+      "Content-Type": "application/json",
+    };
+
+    if (accessToken) {
+      headers.Authorization = `Bearer ${accessToken}`;
+    }
+
     const options: RequestInit = {
       method,
-      headers: {
-        "Content-Type": "application/json",
-      },
+      headers,
       credentials: "same-origin",
     };
 
@@ -37,7 +75,15 @@ export class ApiService {
     }
 
     const res = await fetch(`${ApiService.BASE_URL}${url}`, options);
+    if (res.status !== 401 || isRetry) {
+      return res;
+    }
 
-    return res.json();
+    // Unless another request replaced the token in the meantime
+    if (this.accessToken === accessToken) {
+      this.setAccessToken(await this.authenticator.reauthenticate());
+    }
+
+    return this.send(url, method, body, true);
   }
 }
@@ -0,0 +1,287 @@
+// Authenticator gets the bearer tokens the api is called with
+export interface Authenticator {
+  // getAccessToken returns the current token, signing in first when there is none
+  getAccessToken(): Promise<string>;
+  // reauthenticate drops a token the api turned down and returns a new one
+  reauthenticate(): Promise<string>;
+}
+
+// createAuthenticator signs in with the dev token when REACT_APP_DEV_TOKEN is set, to run
+// against an api started locally, and with the identity provider otherwise
+export const createAuthenticator = (): Authenticator => {
+  const devToken = process.env.REACT_APP_DEV_TOKEN;
+  if (devToken) {
+    return new DevAuthenticator(devToken);
+  }
+
+  const authority = process.env.REACT_APP_OIDC_AUTHORITY;
+  const clientId = process.env.REACT_APP_OIDC_CLIENT_ID;
+  if (!authority || !clientId) {
+    throw new Error(
+      "Set REACT_APP_OIDC_AUTHORITY and REACT_APP_OIDC_CLIENT_ID, or REACT_APP_DEV_TOKEN"
+    );
+  }
+
+  return new OidcAuthenticator(
+    authority,
+    clientId,
+    process.env.REACT_APP_OIDC_AUDIENCE
+  );
+};
+
+// DevAuthenticator uses a token printed by `go run . dev-token`
+export class DevAuthenticator implements Authenticator {
+  constructor(private readonly token: string) {}
+
+  public getAccessToken(): Promise<string> {
+    return Promise.resolve(this.token);
+  }
+
+  public reauthenticate(): Promise<string> {
+    return Promise.reject(
+      new Error(
+        "REACT_APP_DEV_TOKEN was rejected, print a new one with `go run . dev-token`"
+      )
+    );
+  }
+}
+
+interface TokenSet {
+  accessToken: string;
+  refreshToken?: string;
+  // expiresAt is in unix milliseconds
+  expiresAt: number;
+}
+
+interface TokenResponse {
+  access_token: string;
+  refresh_token?: string;
+  expires_in?: number;
+}
+
+// PendingLogin is kept while the identity provider is signing the user in
+interface PendingLogin {
+  state: string;
+  verifier: string;
+  returnTo: string;
+}
+
+interface OpenIdConfiguration {
+  authorization_endpoint: string;
+  token_endpoint: string;
+}
+
+// OidcAuthenticator signs in with the authorization code flow and PKCE, redirecting to the
+// identity provider and back to the app. Tokens are refreshed when they expire or the api turns
+// them down, signing in again when that fails.
+export class OidcAuthenticator implements Authenticator {
+  private static readonly TOKENS_LOCALSTORAGE_KEY = "GROCERY_TOKENS";
+  private static readonly LOGIN_SESSIONSTORAGE_KEY = "GROCERY_LOGIN";
+  // Tokens are refreshed this long before they expire
+  private static readonly EXPIRY_MARGIN_MS = 60 * 1000;
+
+  private pending?: Promise<string>;
+  private configuration?: Promise<OpenIdConfiguration>;
+
+  constructor(
+    private readonly authority: string,
+    private readonly clientId: string,
+    private readonly audience?: string,
+    private readonly redirectUri = window.location.origin
+  ) {}
+
+  public getAccessToken(): Promise<string> {
+    // Requests made while signing in wait for the same token
+    if (!this.pending) {
+      this.pending = this.authenticate().finally(() => {
+        this.pending = undefined;
+      });
+    }
+
+    return this.pending;
+  }
+
+  public reauthenticate(): Promise<string> {
+    if (!this.pending) {
+      const tokens = this.storedTokens();
+      if (tokens) {
+        // Refreshed on the next getAccessToken, or signed in again without a refresh token
+        this.storeTokens({ ...tokens, expiresAt: 0 });
+      }
+    }
+
+    return this.getAccessToken();
+  }
+
+  private async authenticate(): Promise<string> {
+    const params = new URLSearchParams(window.location.search);
+    const code = params.get("code");
+    if (code) {
+      return this.completeLogin(code, params.get("state"));
+    }
+
+    const tokens = this.storedTokens();
+    if (
+      tokens &&
+      tokens.expiresAt > Date.now() + OidcAuthenticator.EXPIRY_MARGIN_MS
+    ) {
+      return tokens.accessToken;
+    }
+
+    if (tokens?.refreshToken) {
+      try {
+        return await this.requestTokens({
+          grant_type: "refresh_token",
+          refresh_token: tokens.refreshToken,
+        });
+      } catch (e) {
+        console.warn("Could not refresh the access token, signing in", e);
+      }
+    }
+
+    return this.login();
+  }
+
+  // login redirects to the identity provider, the returned promise never settles as the app is left
+  private async login(): Promise<string> {
+    const { authorization_endpoint } = await this.openIdConfiguration();
+
+    const state = randomString();
+    const verifier = randomString();
+    const pending: PendingLogin = {
+      state,
+      verifier,
+      returnTo: window.location.href,
+    };
+    sessionStorage.setItem(
+      OidcAuthenticator.LOGIN_SESSIONSTORAGE_KEY,
+      JSON.stringify(pending)
+    );
+
+    const url = new URL(authorization_endpoint);
+    url.search = new URLSearchParams({
+      response_type: "code",
+      client_id: this.clientId,
+      redirect_uri: this.redirectUri,
+      scope: "openid offline_access",
+      state,
+      code_challenge: await codeChallenge(verifier),
+      code_challenge_method: "S256",
+      ...(this.audience ? { audience: this.audience } : {}),
+    }).toString();
+
+    window.location.assign(url.toString());
+    return new Promise<string>(() => {});
+  }
+
+  private async completeLogin(
+    code: string,
+    state: string | null
+  ): Promise<string> {
+    const login = sessionStorage.getItem(
+      OidcAuthenticator.LOGIN_SESSIONSTORAGE_KEY
+    );
+    sessionStorage.removeItem(OidcAuthenticator.LOGIN_SESSIONSTORAGE_KEY);
+
+    const pending: Partial<PendingLogin> = login ? JSON.parse(login) : {};
+
+    // The code is only used once, leave it out of the url either way
+    window.history.replaceState(null, "", this.redirectUri);
+
+    if (!pending.verifier || !state || state !== pending.state) {
+      return this.login();
+    }
+
+    const accessToken = await this.requestTokens({
+      grant_type: "authorization_code",
+      code,
+      code_verifier: pending.verifier,
+      redirect_uri: this.redirectUri,
+    });
+
+    // Back to the page that needed signing in, like an invite link, which the app loads afresh
+    if (pending.returnTo && pending.returnTo !== window.location.href) {
+      window.location.replace(pending.returnTo);
+      return new Promise<string>(() => {});
+    }
+
+    return accessToken;
+  }
+
+  private async requestTokens(grant: Record<string, string>): Promise<string> {
+    const { token_endpoint } = await this.openIdConfiguration();
+
+    const res = await fetch(token_endpoint, {
+      method: "POST",
+      headers: { "Content-Type": "application/x-www-form-urlencoded" },
+      body: new URLSearchParams({ client_id: this.clientId, ...grant }),
+    });
+    if (!res.ok) {
+      throw new Error(`Token request failed with status ${res.status}`);
+    }
+
+    const body: TokenResponse = await res.json();
+    this.storeTokens({
+      accessToken: body.access_token,
+      // Refresh tokens are kept when the provider doesn't rotate them
+      refreshToken: body.refresh_token || grant.refresh_token,
+      expiresAt: Date.now() + (body.expires_in ?? 3600) * 1000,
+    });
+
+    return body.access_token;
+  }
+
+  private openIdConfiguration(): Promise<OpenIdConfiguration> {
+    if (!this.configuration) {
+      const url = `${this.authority.replace(
+        /\/$/,
+        ""
+      )}/.well-known/openid-configuration`;
+
+      this.configuration = fetch(url).then((res) => {
+        if (!res.ok) {
+          this.configuration = undefined;
+          throw new Error(`Could not load ${url}, status ${res.status}`);
+        }
+
+        return res.json();
+      });
+    }
+
+    return this.configuration;
+  }
+
+  private storedTokens(): TokenSet | undefined {
+    const tokens = localStorage.getItem(
+      OidcAuthenticator.TOKENS_LOCALSTORAGE_KEY
+    );
+
+    return tokens ? JSON.parse(tokens) : undefined;
+  }
+
+  private storeTokens(tokens: TokenSet) {
+    localStorage.setItem(
+      OidcAuthenticator.TOKENS_LOCALSTORAGE_KEY,
+      JSON.stringify(tokens)
+    );
+  }
+}
+
+const base64Url = (bytes: Uint8Array) =>
+  btoa(String.fromCharCode(...Array.from(bytes)))
+    .replace(/\+/g, "-")
+    .replace(/\//g, "_")
+    .replace(/=+$/, "");
+
+const randomString = () =>
+  base64Url(window.crypto.getRandomValues(new Uint8Array(32)));
+
+const codeChallenge = async (verifier: string) =>
+  base64Url(
+    new Uint8Array(
+      await window.crypto.subtle.digest(
+        "SHA-256",
+        new TextEncoder().encode(verifier)
+      )
+    )
+  );
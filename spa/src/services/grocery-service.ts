@@ -3,21 +3,82 @@ import { ApiService } from "./api-service";
 export interface GroceryItem {
   householdId: string;
   id: string;
+  /** Left out for items on the household's default list */
+  listId?: string;
   name: string;
+  /** 0 when no amount was given */
+  quantity?: number;
+  /** Empty for a number of things, otherwise e.g. "kilogram" or "cup" */
+  unit?: string;
+  /** What the item was typed as, when its quantity was split off the name */
+  text?: string;
+  notes?: string;
+  /** Ids of the user that added the item and of the member that is to buy it */
+  addedBy?: string;
+  assignedTo?: string;
   checked: boolean;
+  version: number;
+  /** Unix milliseconds set by the server, checkedAt is 0 while the item is unchecked */
+  createdAt?: number;
+  updatedAt?: number;
+  checkedAt?: number;
+  deleted?: boolean;
+}
+
+export interface GroceryChanges {
+  items: GroceryItem[];
+  token: string;
+  reset?: boolean;
+}
+
+export interface GroceryItemConflict {
+  error: string;
+  current: GroceryItem;
 }
 
 type LayoutBlockType = "GroceryItemId" | "Text";
 export interface LayoutBlock {
+  id?: string;
   value: string;
   type: LayoutBlockType;
 }
 
 export interface GroceryList {
+  listId?: string;
+  name?: string;
   items: GroceryItem[];
   layout: LayoutBlock[];
 }
 
+export type GroceryOpType =
+  | "add"
+  | "rename"
+  | "check"
+  | "uncheck"
+  | "delete"
+  | "reorder";
+
+export interface GroceryOp {
+  id: string;
+  type: GroceryOpType;
+  itemId: string;
+  name?: string;
+  afterId?: string;
+  baseVersion?: number;
+  clientTimestamp: number;
+}
+
+export interface GroceryOpResult {
+  opId: string;
+  status: "applied" | "superseded" | "rejected";
+  reason?: string;
+}
+
+export interface GroceryOpsResponse {
+  results: GroceryOpResult[];
+  groceryList: GroceryList;
+}
+
 export interface GroceryMagicRequest {
   groceryList: GroceryList;
   householdId: string;
@@ -54,23 +115,120 @@ export interface GroceryItemData {
 export class GroceryService {
   constructor(private readonly apiService: ApiService) {}
 
-  public getGroceryList(householdId: string): Promise<GroceryList> {
-    return this.apiService.get<GroceryList>(`/groceries/${householdId}`);
+  /**
+   * Without a listId the household's default list is used, as for every list method here.
+   * assignedTo narrows the list down to a member's items, "me" for the signed in user.
+   */
+  public getGroceryList(
+    householdId: string,
+    listId?: string,
+    assignedTo?: string
+  ): Promise<GroceryList> {
+    const params = new URLSearchParams();
+    if (listId) {
+      params.set("listId", listId);
+    }
+    if (assignedTo) {
+      params.set("assignedTo", assignedTo);
+    }
+
+    const query = params.toString() ? `?${params}` : "";
+    return this.apiService.get<GroceryList>(`/groceries/${householdId}${query}`);
   }
 
-  public createGroceryItem(name: string, householdId: string): Promise<void> {
+  public getGroceryChanges(
+    householdId: string,
+    since?: string
+  ): Promise<GroceryChanges> {
+    const query = since ? `?since=${encodeURIComponent(since)}` : "";
+    return this.apiService.get(`/groceries/${householdId}/changes${query}`);
+  }
+
+  public applyOps(
+    householdId: string,
+    ops: GroceryOp[],
+    listId?: string
+  ): Promise<GroceryOpsResponse> {
+    return this.apiService.post(
+      `/groceries/${householdId}/ops${listQuery(listId)}`,
+      { ops }
+    );
+  }
+
+  public moveLayoutItem(
+    householdId: string,
+    itemId: string,
+    afterId?: string,
+    listId?: string
+  ): Promise<GroceryList> {
+    return this.apiService.put(
+      `/groceries/${householdId}/layout/items/${itemId}${listQuery(listId)}`,
+      { afterId }
+    );
+  }
+
+  public insertLayoutHeading(
+    householdId: string,
+    text: string,
+    afterId?: string,
+    listId?: string
+  ): Promise<GroceryList> {
+    return this.apiService.post(
+      `/groceries/${householdId}/layout/headings${listQuery(listId)}`,
+      { text, afterId }
+    );
+  }
+
+  public renameLayoutHeading(
+    householdId: string,
+    headingId: string,
+    text: string,
+    listId?: string
+  ): Promise<GroceryList> {
+    return this.apiService.patch(
+      `/groceries/${householdId}/layout/headings/${headingId}${listQuery(listId)}`,
+      { text }
+    );
+  }
+
+  public deleteLayoutHeading(
+    householdId: string,
+    headingId: string,
+    listId?: string
+  ): Promise<GroceryList> {
+    return this.apiService.delete(
+      `/groceries/${householdId}/layout/headings/${headingId}${listQuery(listId)}`
+    );
+  }
+
+  public createGroceryItem(
+    name: string,
+    householdId: string,
+    listId?: string
+  ): Promise<GroceryItem> {
     const groceryItem: GroceryItem = {
       id: "",
       name,
       householdId,
+      listId,
       checked: false,
+      version: 0,
     };
 
-    return this.apiService.put("/groceries", groceryItem);
+    return this.apiService.put<GroceryItem>("/groceries", groceryItem);
   }
 
-  public updateGroceryItem(groceryItem: GroceryItem): Promise<void> {
-    return this.apiService.post("/groceries", groceryItem);
+  /**
+   * Returns the stored item, which is the server copy if someone else updated it first
+   */
+  public async updateGroceryItem(
+    groceryItem: GroceryItem
+  ): Promise<GroceryItem> {
+    const response = await this.apiService.post<
+      GroceryItem | GroceryItemConflict
+    >("/groceries", groceryItem);
+
+    return "current" in response ? response.current : response;
   }
 
   public magic(
@@ -106,3 +264,7 @@ export class GroceryService {
     return this.apiService.post("/groceries/batchDelete", request);
   }
 }
+
+function listQuery(listId?: string): string {
+  return listId ? `?listId=${encodeURIComponent(listId)}` : "";
+}
@@ -1,7 +1,91 @@
 import { ApiService } from "./api-service";
+import { GroceryItem, GroceryList } from "./grocery-service";
 
 export interface Household {
   id: string;
+  name: string;
+  createdAt: number;
+  ownerId: string;
+}
+
+export const DEFAULT_LIST_ID = "default";
+
+export interface HouseholdList {
+  householdId: string;
+  id: string;
+  name: string;
+  createdAt: number;
+  archived: boolean;
+}
+
+export type StapleRecurrence = "weekly" | "fortnightly" | "days";
+
+/** An item the household buys regularly, added to its list whenever it is due */
+export interface Staple {
+  householdId: string;
+  id: string;
+  listId: string;
+  name: string;
+  recurrence: StapleRecurrence;
+  /** Days between additions when the recurrence is "days" */
+  days?: number;
+  /** Unix seconds, lastAddedAt is 0 until the staple was first added */
+  nextDueAt: number;
+  lastAddedAt: number;
+  createdAt: number;
+  version: number;
+}
+
+export interface StapleChanges {
+  name?: string;
+  listId?: string;
+  recurrence?: StapleRecurrence;
+  days?: number;
+  nextDueAt?: number;
+}
+
+export type HouseholdRole = "owner" | "admin" | "member";
+
+export interface HouseholdMember {
+  householdId: string;
+  userId: string;
+  role: HouseholdRole;
+  joinedAt: number;
+}
+
+export interface HouseholdInvite {
+  code: string;
+  householdId: string;
+  createdBy: string;
+  createdAt: number;
+  expiresAt: number;
+  maxUses: number;
+  uses: number;
+}
+
+export type HouseholdEventType =
+  | "item.created"
+  | "item.updated"
+  | "item.checked"
+  | "item.unchecked"
+  | "item.deleted"
+  | "list.created"
+  | "list.updated"
+  | "list.deleted"
+  | "reset";
+
+export interface HouseholdEvent {
+  id: string;
+  householdId: string;
+  type: HouseholdEventType;
+  item?: GroceryItem;
+  list?: HouseholdList;
+  at: number;
+}
+
+export interface HouseholdEventsResponse {
+  events: HouseholdEvent[];
+  lastEventId: string;
 }
 
 export class HouseholdService {
@@ -11,11 +95,134 @@ export class HouseholdService {
     return this.apiService.put("/households");
   }
 
-  public joinHousehold(userId: string, householdId: string): Promise<void> {
-    return this.apiService.post(`/households/join/${householdId}/${userId}`);
+  public joinHousehold(code: string): Promise<Household> {
+    return this.apiService.post("/households/join", { code });
   }
 
   public leaveHousehold(userId: string, householdId: string): Promise<void> {
     return this.apiService.post(`/households/leave/${householdId}/${userId}`);
   }
+
+  public renameHousehold(householdId: string, name: string): Promise<Household> {
+    return this.apiService.patch(`/households/${householdId}`, { name });
+  }
+
+  public deleteHousehold(householdId: string): Promise<void> {
+    return this.apiService.delete(`/households/${householdId}`);
+  }
+
+  public getLists(
+    householdId: string,
+    includeArchived = false
+  ): Promise<HouseholdList[]> {
+    const query = includeArchived ? "?archived=true" : "";
+    return this.apiService.get(`/households/${householdId}/lists${query}`);
+  }
+
+  public createList(householdId: string, name: string): Promise<HouseholdList> {
+    return this.apiService.post(`/households/${householdId}/lists`, { name });
+  }
+
+  public updateList(
+    householdId: string,
+    listId: string,
+    changes: { name?: string; archived?: boolean }
+  ): Promise<HouseholdList> {
+    return this.apiService.patch(
+      `/households/${householdId}/lists/${listId}`,
+      changes
+    );
+  }
+
+  public deleteList(householdId: string, listId: string): Promise<void> {
+    return this.apiService.delete(`/households/${householdId}/lists/${listId}`);
+  }
+
+  public getStaples(householdId: string): Promise<Staple[]> {
+    return this.apiService.get(`/households/${householdId}/staples`);
+  }
+
+  /**
+   * Without a listId the staple goes on the default list, without a nextDueAt it is added on the
+   * next run of the staples job
+   */
+  public createStaple(
+    householdId: string,
+    staple: StapleChanges & { name: string; recurrence: StapleRecurrence }
+  ): Promise<Staple> {
+    return this.apiService.post(`/households/${householdId}/staples`, staple);
+  }
+
+  public updateStaple(
+    householdId: string,
+    stapleId: string,
+    changes: StapleChanges
+  ): Promise<Staple> {
+    return this.apiService.patch(
+      `/households/${householdId}/staples/${stapleId}`,
+      changes
+    );
+  }
+
+  public deleteStaple(householdId: string, stapleId: string): Promise<void> {
+    return this.apiService.delete(
+      `/households/${householdId}/staples/${stapleId}`
+    );
+  }
+
+  /**
+   * Moves the items to the end of another list and returns that list
+   */
+  public moveGroceryItems(
+    householdId: string,
+    listId: string,
+    itemIds: string[],
+    toListId: string
+  ): Promise<GroceryList> {
+    return this.apiService.post(
+      `/households/${householdId}/lists/${listId}/items/move`,
+      { itemIds, toListId }
+    );
+  }
+
+  /**
+   * Copies the items, unchecked, to the end of another list and returns that list
+   */
+  public copyGroceryItems(
+    householdId: string,
+    listId: string,
+    itemIds: string[],
+    toListId: string
+  ): Promise<GroceryList> {
+    return this.apiService.post(
+      `/households/${householdId}/lists/${listId}/items/copy`,
+      { itemIds, toListId }
+    );
+  }
+
+  public getEvents(
+    householdId: string,
+    since?: string
+  ): Promise<HouseholdEventsResponse> {
+    const query = since ? `?since=${encodeURIComponent(since)}` : "";
+    return this.apiService.get(`/households/${householdId}/events${query}`);
+  }
+
+  public getMembers(householdId: string): Promise<HouseholdMember[]> {
+    return this.apiService.get(`/households/${householdId}/members`);
+  }
+
+  public createInvite(householdId: string): Promise<HouseholdInvite> {
+    return this.apiService.post(`/households/${householdId}/invites`);
+  }
+
+  public getInvites(householdId: string): Promise<HouseholdInvite[]> {
+    return this.apiService.get(`/households/${householdId}/invites`);
+  }
+
+  public revokeInvite(householdId: string, code: string): Promise<void> {
+    return this.apiService.delete(
+      `/households/${householdId}/invites/${code}`
+    );
+  }
 }
@@ -1,8 +1,35 @@
 import { ApiService } from "./api-service";
+import { Household } from "./household-service";
 
 export interface User {
   id: string;
   householdIds: string[];
+  defaultHouseholdId: string;
+}
+
+export interface Device {
+  id: string;
+  userId: string;
+  name: string;
+  linkedAt: number;
+  current: boolean;
+}
+
+export interface PairingCode {
+  code: string;
+  userId: string;
+  createdBy: string;
+  createdAt: number;
+  expiresAt: number;
+}
+
+export interface AccountExport {
+  url: string;
+}
+
+export interface UserHouseholds {
+  defaultHouseholdId: string;
+  households: Household[];
 }
 
 export class UserService {
@@ -15,4 +42,40 @@ export class UserService {
   public createUser(): Promise<User> {
     return this.apiService.put("/users");
   }
+
+  public deleteUser(id: string): Promise<void> {
+    return this.apiService.delete(`/users/${id}`);
+  }
+
+  public exportUser(id: string): Promise<AccountExport> {
+    return this.apiService.get(`/users/${id}/export`);
+  }
+
+  public getDevices(id: string): Promise<Device[]> {
+    return this.apiService.get(`/users/${id}/devices`);
+  }
+
+  public revokeDevice(id: string, deviceId: string): Promise<void> {
+    return this.apiService.delete(
+      `/users/${id}/devices/${encodeURIComponent(deviceId)}`
+    );
+  }
+
+  public createPairingCode(id: string): Promise<PairingCode> {
+    return this.apiService.post(`/users/${id}/pairing-codes`);
+  }
+
+  public pairDevice(code: string, name?: string): Promise<User> {
+    return this.apiService.post("/devices/pair", { code, name });
+  }
+
+  public getHouseholds(id: string): Promise<UserHouseholds> {
+    return this.apiService.get(`/users/${id}/households`);
+  }
+
+  public setDefaultHousehold(id: string, householdId: string): Promise<User> {
+    return this.apiService.put(`/users/${id}/households/default`, {
+      householdId,
+    });
+  }
 }
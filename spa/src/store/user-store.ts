@@ -3,8 +3,6 @@ import { HouseholdService } from "../services/household-service";
 import { UserService } from "../services/user-service";
 
 export class UserStore {
-  private static readonly USER_ID_LOCALSTORAGE_KEY = "GROCERY_USER_ID";
-
   userId?: string;
   householdId?: string;
   isLoading = false;
@@ -38,16 +36,49 @@ export class UserStore {
     }
 
     const household = await this.householdService.createHousehold();
-    await this.householdService.joinHousehold(userId, household.id);
     this.householdId = household.id;
     this.isLoading = false;
   };
 
-  public joinHousehold = async (householdId: string) => {
+  public joinHousehold = async (code: string) => {
+    await this.getUserId();
+
+    const household = await this.householdService.joinHousehold(code);
+    this.householdId = household.id;
+  };
+
+  public createInvite = async () => {
+    if (!this.householdId) {
+      throw new Error("Not in a household");
+    }
+
+    return this.householdService.createInvite(this.householdId);
+  };
+
+  public createPairingCode = async () => {
+    const userId = await this.getUserId();
+
+    return this.userService.createPairingCode(userId);
+  };
+
+  public pairDevice = async (code: string) => {
+    const user = await this.userService.pairDevice(code);
+
+    this.userId = user.id;
+    this.householdId =
+      user.defaultHouseholdId || user.householdIds[0] || undefined;
+  };
+
+  public getDevices = async () => {
+    const userId = await this.getUserId();
+
+    return this.userService.getDevices(userId);
+  };
+
+  public revokeDevice = async (deviceId: string) => {
     const userId = await this.getUserId();
 
-    await this.householdService.joinHousehold(userId, householdId);
-    this.householdId = householdId;
+    await this.userService.revokeDevice(userId, deviceId);
   };
 
   public leaveHousehold = async () => {
@@ -61,7 +92,8 @@ export class UserStore {
     this.getOrCreateUser();
   };
 
-  private createUser = async () => {
+  // getOrCreateUser loads the user the access token belongs to, the api creates them on first sight
+  private getOrCreateUser = async () => {
     this.isLoading = true;
     const user = await this.userService.createUser();
 
@@ -69,24 +101,11 @@ export class UserStore {
       throw new Error("UserId not created successfully");
     }
 
-    localStorage.setItem(UserStore.USER_ID_LOCALSTORAGE_KEY, user.id);
     this.userId = user.id;
-    this.isLoading = false;
-  };
-
-  private getOrCreateUser = async () => {
-    const userId = localStorage.getItem(UserStore.USER_ID_LOCALSTORAGE_KEY);
-
-    if (userId) {
-      this.userId = userId;
-      const user = await this.userService.getUser(userId);
-
-      if (user.householdIds.length) {
-        this.householdId = user.householdIds[0];
-      }
-    } else {
-      this.createUser();
+    if (user.householdIds.length) {
+      this.householdId = user.defaultHouseholdId || user.householdIds[0];
     }
+    this.isLoading = false;
   };
 
   private getUserId = async () => {